@@ -0,0 +1,262 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+)
+
+// BlockUnblock
+// @Security 		BearerAuth
+// @Summary 		Block-Unblock
+// @Description 	this api for blocking a user or lifting the block, blocking removes the follows between both users
+// @Tags 			block
+// @Accept			json
+// @Produce 		json
+// @Param 			request body entity.BlockAction true "Block Model"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/blocks [POST]
+func (h *HandlerV1) BlockUnblock(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.BlockAction
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.UserID = cast.ToString(claims["sub"])
+
+	if _, err := uuid.Parse(request.BlockedID); err != nil || request.BlockedID == request.UserID {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	status, err := h.Block.Block(ctx, request)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: status,
+	})
+}
+
+// Blocks
+// @Security 		BearerAuth
+// @Summary 		Blocked Users
+// @Description 	this api for getting the users blocked by the current user
+// @Tags 			block
+// @Accept			json
+// @Produce 		json
+// @Success 		200 {object} entity.ListUser
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/blocks [GET]
+func (h *HandlerV1) Blocks(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	users, err := h.Block.Blocked(ctx, cast.ToString(claims["sub"]))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// MuteUnmute
+// @Security 		BearerAuth
+// @Summary 		Mute-Unmute
+// @Description 	this api for muting a user or lifting the mute, muted users are left out of timelines, search and notifications
+// @Tags 			block
+// @Accept			json
+// @Produce 		json
+// @Param 			request body entity.MuteAction true "Mute Model"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/mutes [POST]
+func (h *HandlerV1) MuteUnmute(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.MuteAction
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.UserID = cast.ToString(claims["sub"])
+
+	if _, err := uuid.Parse(request.MutedID); err != nil || request.MutedID == request.UserID {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	status, err := h.Block.Mute(ctx, request)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: status,
+	})
+}
+
+// Mutes
+// @Security 		BearerAuth
+// @Summary 		Muted Users
+// @Description 	this api for getting the users muted by the current user
+// @Tags 			block
+// @Accept			json
+// @Produce 		json
+// @Success 		200 {object} entity.ListUser
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/mutes [GET]
+func (h *HandlerV1) Mutes(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	users, err := h.Block.Muted(ctx, cast.ToString(claims["sub"]))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
@@ -0,0 +1,446 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+)
+
+// BookmarkTweet
+// @Security 		BearerAuth
+// @Summary 		Bookmark Tweet
+// @Description 	this api for saving a tweet privately, saving it again moves it to the given folder or out of any folder
+// @Tags 			bookmark
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Tweet ID"
+// @Param 			request body entity.BookmarkAction false "Bookmark Folder"
+// @Success 		201 {object} entity.ResponseWithStatus
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/tweets/{id}/bookmark [POST]
+func (h *HandlerV1) BookmarkTweet(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.BookmarkAction
+
+	// the body is optional, without it the tweet is saved outside of any folder
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.TweetID = c.Param("id")
+
+	_, err = uuid.Parse(request.TweetID)
+	if err == nil && request.FolderID != nil {
+		_, err = uuid.Parse(*request.FolderID)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.UserID = cast.ToString(claims["sub"])
+
+	created, err := h.Bookmark.Bookmark(ctx, request)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	c.JSON(status, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
+
+// RemoveBookmark
+// @Security 		BearerAuth
+// @Summary 		Remove Bookmark
+// @Description 	this api for removing a tweet from the bookmarks of the current user
+// @Tags 			bookmark
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Tweet ID"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/tweets/{id}/bookmark [DELETE]
+func (h *HandlerV1) RemoveBookmark(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	tweetID := c.Param("id")
+	if _, err := uuid.Parse(tweetID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	if err := h.Bookmark.RemoveBookmark(ctx, cast.ToString(claims["sub"]), tweetID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
+
+// Bookmarks
+// @Security 		BearerAuth
+// @Summary 		Bookmarks
+// @Description 	this api for getting the saved tweets of the current user, the most recently saved first
+// @Tags 			bookmark
+// @Accept			json
+// @Produce 		json
+// @Param 			folder_id query string false "Folder ID"
+// @Param 			cursor query string false "Cursor"
+// @Param 			limit query int false "Limit"
+// @Success 		200 {object} entity.TimelineResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/bookmarks [GET]
+func (h *HandlerV1) Bookmarks(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	params, errs := utils.ParseQueryParam(c.Request.URL.Query())
+	if len(errs) > 0 || params.Limit == 0 || params.Limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(errs)
+		return
+	}
+
+	cursor, err := utils.DecodeCursor(params.Filters["cursor"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	var folderID *string
+	if value, ok := params.Filters["folder_id"]; ok {
+		if _, err := uuid.Parse(value); err != nil {
+			c.JSON(http.StatusBadRequest, entity.Error{
+				Message: entity.IncorrectData,
+			})
+			return
+		}
+		folderID = &value
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	bookmarks, err := h.Bookmark.Bookmarks(ctx, entity.BookmarkFilter{
+		UserID:   cast.ToString(claims["sub"]),
+		FolderID: folderID,
+		Cursor:   cursor,
+		Limit:    int(params.Limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, bookmarks)
+}
+
+// CreateBookmarkFolder
+// @Security 		BearerAuth
+// @Summary 		Create Bookmark Folder
+// @Description 	this api for creating a named folder to file bookmarks in
+// @Tags 			bookmark
+// @Accept			json
+// @Produce 		json
+// @Param 			request body entity.CreateBookmarkFolderRequest true "Folder"
+// @Success 		201 {object} entity.BookmarkFolder
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/bookmarks/folders [POST]
+func (h *HandlerV1) CreateBookmarkFolder(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.CreateBookmarkFolderRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" || utf8.RuneCountInString(request.Name) > maxFolderNameLength {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ID = uuid.NewString()
+	request.UserID = cast.ToString(claims["sub"])
+
+	folder, err := h.Bookmark.CreateFolder(ctx, request)
+	if err != nil {
+		if errors.Is(err, errorspkg.ErrorConflict) {
+			c.JSON(http.StatusBadRequest, entity.Error{
+				Message: entity.FolderExists,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, folder)
+}
+
+// BookmarkFolders
+// @Security 		BearerAuth
+// @Summary 		Bookmark Folders
+// @Description 	this api for getting the bookmark folders of the current user
+// @Tags 			bookmark
+// @Accept			json
+// @Produce 		json
+// @Success 		200 {object} entity.BookmarkFoldersResponse
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/bookmarks/folders [GET]
+func (h *HandlerV1) BookmarkFolders(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	folders, err := h.Bookmark.Folders(ctx, cast.ToString(claims["sub"]))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, folders)
+}
+
+// DeleteBookmarkFolder
+// @Security 		BearerAuth
+// @Summary 		Delete Bookmark Folder
+// @Description 	this api for deleting a bookmark folder, the tweets filed in it stay bookmarked
+// @Tags 			bookmark
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Folder ID"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/bookmarks/folders/{id} [DELETE]
+func (h *HandlerV1) DeleteBookmarkFolder(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	folderID := c.Param("id")
+	if _, err := uuid.Parse(folderID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	if err := h.Bookmark.DeleteFolder(ctx, cast.ToString(claims["sub"]), folderID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
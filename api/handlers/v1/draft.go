@@ -0,0 +1,409 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+)
+
+// validDraft tells whether a draft could be published as a tweet and is scheduled for a
+// time ahead within maxScheduleAhead, the publish time is moved to UTC in place
+func validDraft(draft *entity.DraftRequest) bool {
+	if draft.Content == nil && len(draft.MediaIDs) == 0 {
+		return false
+	}
+
+	if !validMediaIDs(draft.MediaIDs) {
+		return false
+	}
+
+	if draft.ParentTweetID != nil && draft.QuoteTweetID != nil {
+		return false
+	}
+
+	for _, id := range []*string{draft.ParentTweetID, draft.QuoteTweetID} {
+		if id == nil {
+			continue
+		}
+
+		if _, err := uuid.Parse(*id); err != nil {
+			return false
+		}
+	}
+
+	if draft.PublishAt != nil {
+		now := time.Now()
+		if !draft.PublishAt.After(now) || draft.PublishAt.After(now.Add(maxScheduleAhead)) {
+			return false
+		}
+
+		publishAt := draft.PublishAt.UTC()
+		draft.PublishAt = &publishAt
+	}
+
+	return true
+}
+
+// CreateDraft
+// @Security 		BearerAuth
+// @Summary 		Create Draft
+// @Description 	this api for saving a tweet as a draft, a draft with publish_at is scheduled and published at that time
+// @Tags 			draft
+// @Accept			json
+// @Produce 		json
+// @Param 			request body entity.DraftRequest true "Draft"
+// @Success 		201 {object} entity.Draft
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/drafts [POST]
+func (h *HandlerV1) CreateDraft(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.DraftRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	if !validDraft(&request) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ID = uuid.NewString()
+	request.UserID = cast.ToString(claims["sub"])
+
+	draft, err := h.Draft.CreateDraft(ctx, request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, draft)
+}
+
+// Drafts
+// @Security 		BearerAuth
+// @Summary 		Drafts
+// @Description 	this api for getting the unscheduled drafts of the current user, the most recently edited first
+// @Tags 			draft
+// @Accept			json
+// @Produce 		json
+// @Success 		200 {object} entity.DraftsResponse
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/drafts [GET]
+func (h *HandlerV1) Drafts(c *gin.Context) {
+	h.listDrafts(c, false)
+}
+
+// ScheduledTweets
+// @Security 		BearerAuth
+// @Summary 		Scheduled Tweets
+// @Description 	this api for getting the scheduled tweets of the current user, the next one to go out first
+// @Tags 			draft
+// @Accept			json
+// @Produce 		json
+// @Success 		200 {object} entity.DraftsResponse
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/drafts/scheduled [GET]
+func (h *HandlerV1) ScheduledTweets(c *gin.Context) {
+	h.listDrafts(c, true)
+}
+
+func (h *HandlerV1) listDrafts(c *gin.Context, scheduled bool) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	drafts, err := h.Draft.Drafts(ctx, cast.ToString(claims["sub"]), scheduled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, drafts)
+}
+
+// UpdateDraft
+// @Security 		BearerAuth
+// @Summary 		Update Draft
+// @Description 	this api for editing a draft or scheduled tweet, setting publish_at reschedules it and leaving it out takes it off the schedule
+// @Tags 			draft
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Draft ID"
+// @Param 			request body entity.DraftRequest true "Draft"
+// @Success 		200 {object} entity.Draft
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/drafts/{id} [PUT]
+func (h *HandlerV1) UpdateDraft(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.DraftRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ID = c.Param("id")
+
+	if _, err := uuid.Parse(request.ID); err != nil || !validDraft(&request) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.UserID = cast.ToString(claims["sub"])
+
+	draft, err := h.Draft.UpdateDraft(ctx, request)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, draft)
+}
+
+// DeleteDraft
+// @Security 		BearerAuth
+// @Summary 		Delete Draft
+// @Description 	this api for discarding a draft or cancelling a scheduled tweet
+// @Tags 			draft
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Draft ID"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/drafts/{id} [DELETE]
+func (h *HandlerV1) DeleteDraft(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	draftID := c.Param("id")
+	if _, err := uuid.Parse(draftID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	if err := h.Draft.DeleteDraft(ctx, cast.ToString(claims["sub"]), draftID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
+
+// PublishDraft
+// @Security 		BearerAuth
+// @Summary 		Publish Draft
+// @Description 	this api for publishing a draft or scheduled tweet right away
+// @Tags 			draft
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Draft ID"
+// @Success 		200 {object} entity.CreateTweetResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/drafts/{id}/publish [POST]
+func (h *HandlerV1) PublishDraft(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	draftID := c.Param("id")
+	if _, err := uuid.Parse(draftID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	// the reply and mention notifications are saved with the tweet
+	response, err := h.Draft.PublishDraft(ctx, cast.ToString(claims["sub"]), draftID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		} else if errors.Is(err, errorspkg.ErrorBlocked) {
+			c.JSON(http.StatusForbidden, entity.Error{
+				Message: entity.Blocked,
+			})
+			log.Println(err.Error())
+			return
+		} else if errors.Is(err, errorspkg.ErrorNoPermission) {
+			c.JSON(http.StatusForbidden, entity.Error{
+				Message: entity.NoAccess,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
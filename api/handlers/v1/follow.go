@@ -4,15 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
-	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/kafka"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/spf13/cast"
 )
 
@@ -22,8 +22,9 @@ import (
 // @Tags 			follow
 // @Accept			json
 // @Produce 		json
+// @Description 	this api for following or unfollowing a user, following a protected account sends a follow request and calling it again withdraws the request
 // @Param 			request body entity.FollowAction true "Follow Model"
-// @Success 		200 {object} entity.ResponseWithStatus
+// @Success 		200 {object} entity.FollowResponse
 // @Failure 		400 {object} entity.Error
 // @Failure 		401 {object} entity.Error
 // @Failure 		403 {object} entity.Error
@@ -63,7 +64,7 @@ func (h *HandlerV1) FollowUnfollow(c *gin.Context) {
 
 	request.UserID = cast.ToString(claims["sub"])
 
-	status, err := h.Follow.Follow(ctx, request)
+	response, err := h.Follow.Follow(ctx, request)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			c.JSON(http.StatusNotFound, entity.Error{
@@ -71,6 +72,12 @@ func (h *HandlerV1) FollowUnfollow(c *gin.Context) {
 			})
 			log.Println(err.Error())
 			return
+		} else if errors.Is(err, errorspkg.ErrorBlocked) {
+			c.JSON(http.StatusForbidden, entity.Error{
+				Message: entity.Blocked,
+			})
+			log.Println(err.Error())
+			return
 		} else {
 			c.JSON(http.StatusInternalServerError, entity.Error{
 				Message: entity.ServerError,
@@ -80,9 +87,142 @@ func (h *HandlerV1) FollowUnfollow(c *gin.Context) {
 		}
 	}
 
-	msgKafka := fmt.Sprintf("User %s followed User %s", request.UserID, request.FollowingID)
-	err = kafka.SendMessage(h.Config.Kafka.Topic, msgKafka)
+	c.JSON(http.StatusOK, response)
+}
+
+// FollowRequests
+// @Security 		BearerAuth
+// @Summary 		Follow Requests
+// @Description 	this api for getting the pending follow requests sent to the current user, newest first
+// @Tags 			follow
+// @Accept			json
+// @Produce 		json
+// @Success 		200 {object} entity.ListUser
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/follow-requests [GET]
+func (h *HandlerV1) FollowRequests(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	requests, err := h.Follow.FollowRequests(ctx, cast.ToString(claims["sub"]))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// ApproveFollowRequest
+// @Security 		BearerAuth
+// @Summary 		Approve Follow Request
+// @Description 	this api for accepting a pending follow request, the requester starts following the current user
+// @Tags 			follow
+// @Accept			json
+// @Produce 		json
+// @Param 			user_id path string true "Requester ID"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/follow-requests/{user_id}/approve [POST]
+func (h *HandlerV1) ApproveFollowRequest(c *gin.Context) {
+	h.answerFollowRequest(c, true)
+}
+
+// RejectFollowRequest
+// @Security 		BearerAuth
+// @Summary 		Reject Follow Request
+// @Description 	this api for declining a pending follow request
+// @Tags 			follow
+// @Accept			json
+// @Produce 		json
+// @Param 			user_id path string true "Requester ID"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/follow-requests/{user_id}/reject [POST]
+func (h *HandlerV1) RejectFollowRequest(c *gin.Context) {
+	h.answerFollowRequest(c, false)
+}
+
+// answerFollowRequest approves or rejects the follow request of the user in the path
+func (h *HandlerV1) answerFollowRequest(c *gin.Context, approve bool) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	requesterID := c.Param("user_id")
+	if _, err := uuid.Parse(requesterID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request := entity.FollowRequestAction{
+		UserID:      cast.ToString(claims["sub"]),
+		RequesterID: requesterID,
+	}
+
+	if approve {
+		err = h.Follow.ApproveFollowRequest(ctx, request)
+	} else {
+		err = h.Follow.RejectFollowRequest(ctx, request)
+	}
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
 		c.JSON(http.StatusInternalServerError, entity.Error{
 			Message: entity.ServerError,
 		})
@@ -91,7 +231,7 @@ func (h *HandlerV1) FollowUnfollow(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, entity.ResponseWithStatus{
-		Status: status,
+		Status: true,
 	})
 }
 
@@ -0,0 +1,562 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+)
+
+// validGroupTitle tells whether a title is neither blank nor too long
+func validGroupTitle(title string) bool {
+	return strings.TrimSpace(title) != "" && utf8.RuneCountInString(title) <= maxGroupTitleLength
+}
+
+// validMemberRoles are the roles a group member can be given
+var validMemberRoles = map[string]struct{}{
+	entity.MemberRoleOwner:  {},
+	entity.MemberRoleAdmin:  {},
+	entity.MemberRoleMember: {},
+}
+
+// validIDs tells whether ids is a non empty list of uuids
+func validIDs(ids []string) bool {
+	if len(ids) == 0 {
+		return false
+	}
+
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// groupFailed answers a failed group operation
+func groupFailed(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, entity.Error{
+			Message: entity.NotFoundData,
+		})
+	case errors.Is(err, errorspkg.ErrorNoPermission):
+		c.JSON(http.StatusForbidden, entity.Error{
+			Message: entity.NoAccess,
+		})
+	case errors.Is(err, errorspkg.ErrorGroupFull):
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.GroupFull,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+	}
+	log.Println(err.Error())
+}
+
+// CreateGroup
+// @Security 		BearerAuth
+// @Summary 		Create Group
+// @Description 	this api for creating a group conversation owned by the current user
+// @Tags 			group
+// @Accept			json
+// @Produce 		json
+// @Param 			request body entity.CreateGroupRequest true "Group"
+// @Success 		201 {object} entity.Conversation
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/groups [POST]
+func (h *HandlerV1) CreateGroup(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.CreateGroupRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	if !validGroupTitle(request.Title) || !validIDs(request.MemberIDs) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ID = uuid.NewString()
+	request.OwnerID = cast.ToString(claims["sub"])
+
+	conversation, err := h.Message.CreateGroup(ctx, request)
+	if err != nil {
+		groupFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, conversation)
+}
+
+// UpdateGroup
+// @Security 		BearerAuth
+// @Summary 		Update Group
+// @Description 	this api for renaming a group, owners and admins only
+// @Tags 			group
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Conversation ID"
+// @Param 			request body entity.UpdateGroupRequest true "Group"
+// @Success 		200 {object} entity.Conversation
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/groups/{id} [PUT]
+func (h *HandlerV1) UpdateGroup(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.UpdateGroupRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ID = c.Param("id")
+
+	if _, err := uuid.Parse(request.ID); err != nil || request.Title == nil || !validGroupTitle(*request.Title) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.UserID = cast.ToString(claims["sub"])
+
+	conversation, err := h.Message.UpdateGroup(ctx, request)
+	if err != nil {
+		groupFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// UploadGroupAvatar
+// @Security 		BearerAuth
+// @Summary 		Upload Group Avatar
+// @Description 	this api for uploading the avatar of a group, owners and admins only
+// @Tags 			group
+// @Accept 			json
+// @Produce 		json
+// @Param 			id path string true "Conversation ID"
+// @Param 			avatar formData file true "Group Avatar"
+// @Success 		200 {object} entity.Conversation
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		413 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/groups/{id}/avatar [POST]
+func (h *HandlerV1) UploadGroupAvatar(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	userID := cast.ToString(claims["sub"])
+
+	// checking the role first keeps files of rejected uploads out of the bucket
+	conversation, err := h.Message.GetConversation(ctx, id, userID)
+	if err == nil && conversation.Kind != entity.ConversationKindGroup {
+		err = sql.ErrNoRows
+	}
+	if err == nil {
+		err = errorspkg.ErrorNoPermission
+		for _, member := range conversation.Members {
+			if member.UserID == userID && member.Role != entity.MemberRoleMember {
+				err = nil
+			}
+		}
+	}
+	if err != nil {
+		groupFailed(c, err)
+		return
+	}
+
+	h.limitUpload(c, 1, entity.MediaKindImage)
+
+	file, err := c.FormFile("avatar")
+	if bodyTooLarge(c, err) {
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	media, err := h.uploadMedia(ctx, entity.MediaUpload{
+		UserID:         userID,
+		Purpose:        entity.MediaPurposeConversation,
+		ConversationID: &id,
+		Kinds:          []string{entity.MediaKindImage},
+	}, file)
+	if err != nil {
+		mediaFailed(c, err)
+		return
+	}
+
+	conversation, err = h.Message.UpdateGroup(ctx, entity.UpdateGroupRequest{
+		ID:        id,
+		UserID:    userID,
+		AvatarURL: &media.URLs.Medium,
+	})
+	if err != nil {
+		groupFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// AddGroupMembers
+// @Security 		BearerAuth
+// @Summary 		Add Group Members
+// @Description 	this api for inviting users to a group, owners and admins only
+// @Tags 			group
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Conversation ID"
+// @Param 			request body entity.GroupMembersRequest true "User IDs"
+// @Success 		200 {object} entity.Conversation
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/groups/{id}/members [POST]
+func (h *HandlerV1) AddGroupMembers(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.GroupMembersRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ConversationID = c.Param("id")
+
+	if _, err := uuid.Parse(request.ConversationID); err != nil || !validIDs(request.MemberIDs) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.UserID = cast.ToString(claims["sub"])
+
+	conversation, err := h.Message.AddMembers(ctx, request)
+	if err != nil {
+		groupFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// RemoveGroupMember
+// @Security 		BearerAuth
+// @Summary 		Remove Group Member
+// @Description 	this api for removing a member from a group, only members of a higher role can remove someone
+// @Tags 			group
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Conversation ID"
+// @Param 			user_id path string true "Member ID"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/groups/{id}/members/{user_id} [DELETE]
+func (h *HandlerV1) RemoveGroupMember(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	request := entity.GroupMemberRequest{
+		ConversationID: c.Param("id"),
+		MemberID:       c.Param("user_id"),
+	}
+
+	if !validIDs([]string{request.ConversationID, request.MemberID}) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.UserID = cast.ToString(claims["sub"])
+
+	if err := h.Message.RemoveMember(ctx, request); err != nil {
+		groupFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
+
+// SetGroupMemberRole
+// @Security 		BearerAuth
+// @Summary 		Set Group Member Role
+// @Description 	this api for changing the role of a member, owners only. Making someone the owner hands the group over
+// @Tags 			group
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Conversation ID"
+// @Param 			user_id path string true "Member ID"
+// @Param 			request body entity.GroupMemberRequest true "Role: owner, admin or member"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/groups/{id}/members/{user_id} [PUT]
+func (h *HandlerV1) SetGroupMemberRole(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.GroupMemberRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ConversationID = c.Param("id")
+	request.MemberID = c.Param("user_id")
+	request.UserID = cast.ToString(claims["sub"])
+
+	// checking: the ids are valid, the role exists and owners do not change their own role
+	if _, ok := validMemberRoles[request.Role]; !ok || !validIDs([]string{request.ConversationID, request.MemberID}) || request.MemberID == request.UserID {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	if err := h.Message.SetMemberRole(ctx, request); err != nil {
+		groupFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
+
+// LeaveGroup
+// @Security 		BearerAuth
+// @Summary 		Leave Group
+// @Description 	this api for leaving a group, an owner leaving hands it over to an admin or the oldest member
+// @Tags 			group
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Conversation ID"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/groups/{id}/leave [POST]
+func (h *HandlerV1) LeaveGroup(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	if err := h.Message.LeaveGroup(ctx, id, cast.ToString(claims["sub"])); err != nil {
+		groupFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
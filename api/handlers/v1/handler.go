@@ -4,11 +4,44 @@ import (
 	"time"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/eventbus"
 	tokens "github.com/dostonshernazarov/mini-twitter/internal/pkg/token"
 	"github.com/dostonshernazarov/mini-twitter/internal/usecase"
 	"go.uber.org/zap"
 )
 
+const (
+	// maxPageLimit caps the limit query param of cursor paginated lists
+	maxPageLimit = 100
+	// defaultThreadDepth and maxThreadDepth bound the levels of a reply tree
+	defaultThreadDepth = 3
+	maxThreadDepth     = 5
+	// maxTrendsLimit caps the number of trending hashtags returned at once
+	maxTrendsLimit = 50
+	// maxMessageLength caps the characters of a direct message
+	maxMessageLength = 10000
+	// maxGroupTitleLength caps the characters of a group title
+	maxGroupTitleLength = 100
+	// maxFolderNameLength caps the characters of a bookmark folder name
+	maxFolderNameLength = 50
+	// maxListNameLength and maxListDescriptionLength cap the characters of a list name and description
+	maxListNameLength        = 25
+	maxListDescriptionLength = 100
+	// minPollOptions and maxPollOptions bound the options of a poll, maxPollOptionLength caps the characters of one
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionLength = 25
+	// minPollDuration and maxPollDuration bound how long a poll stays open, in minutes
+	minPollDuration = 5
+	maxPollDuration = 7 * 24 * 60
+	// maxTweetMedia caps the media attached to one tweet
+	maxTweetMedia = 4
+	// multipartOverhead is the room left in an upload body for the multipart headers and boundaries
+	multipartOverhead = 1 << 20
+	// maxScheduleAhead caps how far ahead a tweet can be scheduled
+	maxScheduleAhead = 365 * 24 * time.Hour
+)
+
 type HandlerV1 struct {
 	Config         *config.Config
 	Logger         *zap.Logger
@@ -17,8 +50,18 @@ type HandlerV1 struct {
 	User           usecase.User
 	Tweet          usecase.Twit
 	Follow         usecase.Follow
+	Block          usecase.Block
+	Bookmark       usecase.Bookmark
+	List           usecase.List
+	Poll           usecase.Poll
+	Draft          usecase.Draft
+	Media          usecase.Media
 	Search         usecase.Search
 	Like           usecase.Like
+	Trend          usecase.Trend
+	Notification   usecase.Notification
+	Message        usecase.Message
+	EventBus       eventbus.Bus
 }
 
 type HandlerV1Config struct {
@@ -29,8 +72,18 @@ type HandlerV1Config struct {
 	User           usecase.User
 	Tweet          usecase.Twit
 	Follow         usecase.Follow
+	Block          usecase.Block
+	Bookmark       usecase.Bookmark
+	List           usecase.List
+	Poll           usecase.Poll
+	Draft          usecase.Draft
+	Media          usecase.Media
 	Search         usecase.Search
 	Like           usecase.Like
+	Trend          usecase.Trend
+	Notification   usecase.Notification
+	Message        usecase.Message
+	EventBus       eventbus.Bus
 }
 
 func New(c *HandlerV1Config) *HandlerV1 {
@@ -42,7 +95,17 @@ func New(c *HandlerV1Config) *HandlerV1 {
 		User:           c.User,
 		Tweet:          c.Tweet,
 		Follow:         c.Follow,
+		Block:          c.Block,
+		Bookmark:       c.Bookmark,
+		List:           c.List,
+		Poll:           c.Poll,
+		Draft:          c.Draft,
+		Media:          c.Media,
 		Search:         c.Search,
 		Like:           c.Like,
+		Trend:          c.Trend,
+		Notification:   c.Notification,
+		Message:        c.Message,
+		EventBus:       c.EventBus,
 	}
 }
@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+)
+
+// HashtagTweets
+// @Security 		BearerAuth
+// @Summary 		Hashtag Tweets
+// @Description 	this api for getting tweets tagged with a hashtag, newest first
+// @Tags			hashtag
+// @Accept 			json
+// @Produce 		json
+// @Param 			tag path string true "Hashtag without #"
+// @Param 			cursor query string false "Cursor"
+// @Param 			limit query int false "Limit"
+// @Success 		200 {object} entity.TimelineResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/hashtags/{tag}/tweets [GET]
+func (h *HandlerV1) HashtagTweets(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	params, errs := utils.ParseQueryParam(c.Request.URL.Query())
+	if len(errs) > 0 || params.Limit == 0 || params.Limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(errs)
+		return
+	}
+
+	cursor, err := utils.DecodeCursor(params.Filters["cursor"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	tweets, err := h.Tweet.HashtagTweets(ctx, entity.HashtagFilter{
+		Tag:      strings.ToLower(strings.TrimPrefix(c.Param("tag"), "#")),
+		ViewerID: cast.ToString(claims["sub"]),
+		Cursor:   cursor,
+		Limit:    int(params.Limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, tweets)
+}
+
+// Trends
+// @Security 		BearerAuth
+// @Summary 		Trending Hashtags
+// @Description 	this api for getting the most used hashtags of the last hour or day
+// @Tags			hashtag
+// @Accept 			json
+// @Produce 		json
+// @Param 			window query string false "Window, 1h or 24h"
+// @Param 			limit query int false "Limit"
+// @Success 		200 {object} entity.TrendsResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/trends [GET]
+func (h *HandlerV1) Trends(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	params, errs := utils.ParseQueryParam(c.Request.URL.Query())
+	if len(errs) > 0 || params.Limit == 0 || params.Limit > maxTrendsLimit {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(errs)
+		return
+	}
+
+	window := params.Filters["window"]
+	if window == "" {
+		window = entity.TrendWindowHour
+	}
+
+	trends, err := h.Trend.Trends(ctx, window, int(params.Limit))
+	if err != nil {
+		if errors.Is(err, errorspkg.ErrorInvalidWindow) {
+			c.JSON(http.StatusBadRequest, entity.Error{
+				Message: entity.IncorrectData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, trends)
+}
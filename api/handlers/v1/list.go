@@ -0,0 +1,664 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+)
+
+// validListName tells whether a list name is neither blank nor too long
+func validListName(name string) bool {
+	return strings.TrimSpace(name) != "" && utf8.RuneCountInString(name) <= maxListNameLength
+}
+
+// validListDescription tells whether a list description is not too long, no description is valid
+func validListDescription(description *string) bool {
+	return description == nil || utf8.RuneCountInString(*description) <= maxListDescriptionLength
+}
+
+// listFailed answers a failed list operation
+func listFailed(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, entity.Error{
+			Message: entity.NotFoundData,
+		})
+	case errors.Is(err, errorspkg.ErrorNoPermission):
+		c.JSON(http.StatusForbidden, entity.Error{
+			Message: entity.NoAccess,
+		})
+	case errors.Is(err, errorspkg.ErrorBlocked):
+		c.JSON(http.StatusForbidden, entity.Error{
+			Message: entity.Blocked,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+	}
+	log.Println(err.Error())
+}
+
+// CreateList
+// @Security 		BearerAuth
+// @Summary 		Create List
+// @Description 	this api for creating a list owned by the current user, private lists are only seen by their owner
+// @Tags 			list
+// @Accept			json
+// @Produce 		json
+// @Param 			request body entity.CreateListRequest true "List"
+// @Success 		201 {object} entity.List
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/lists [POST]
+func (h *HandlerV1) CreateList(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.CreateListRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if !validListName(request.Name) || !validListDescription(request.Description) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ID = uuid.NewString()
+	request.OwnerID = cast.ToString(claims["sub"])
+
+	list, err := h.List.CreateList(ctx, request)
+	if err != nil {
+		listFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+// Lists
+// @Security 		BearerAuth
+// @Summary 		Lists
+// @Description 	this api for getting the lists the current user owns or subscribed to
+// @Tags 			list
+// @Accept			json
+// @Produce 		json
+// @Success 		200 {object} entity.ListsResponse
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/lists [GET]
+func (h *HandlerV1) Lists(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	lists, err := h.List.Lists(ctx, cast.ToString(claims["sub"]))
+	if err != nil {
+		listFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lists)
+}
+
+// GetList
+// @Security 		BearerAuth
+// @Summary 		Get List
+// @Description 	this api for getting a list with its member and subscriber counts
+// @Tags 			list
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "List ID"
+// @Success 		200 {object} entity.List
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/lists/{id} [GET]
+func (h *HandlerV1) GetList(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	listID := c.Param("id")
+	if _, err := uuid.Parse(listID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	list, err := h.List.GetList(ctx, listID, cast.ToString(claims["sub"]))
+	if err != nil {
+		listFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// UpdateList
+// @Security 		BearerAuth
+// @Summary 		Update List
+// @Description 	this api for changing the name, description or privacy of a list, only its owner can change it
+// @Tags 			list
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "List ID"
+// @Param 			request body entity.UpdateListRequest true "List"
+// @Success 		200 {object} entity.List
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/lists/{id} [PUT]
+func (h *HandlerV1) UpdateList(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.UpdateListRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ID = c.Param("id")
+
+	if request.Name != nil {
+		name := strings.TrimSpace(*request.Name)
+		request.Name = &name
+	}
+
+	_, err = uuid.Parse(request.ID)
+	if err != nil || (request.Name != nil && !validListName(*request.Name)) || !validListDescription(request.Description) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.OwnerID = cast.ToString(claims["sub"])
+
+	list, err := h.List.UpdateList(ctx, request)
+	if err != nil {
+		listFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteList
+// @Security 		BearerAuth
+// @Summary 		Delete List
+// @Description 	this api for deleting a list with its members and subscriptions, only its owner can delete it
+// @Tags 			list
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "List ID"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/lists/{id} [DELETE]
+func (h *HandlerV1) DeleteList(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	listID := c.Param("id")
+	if _, err := uuid.Parse(listID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	if err := h.List.DeleteList(ctx, listID, cast.ToString(claims["sub"])); err != nil {
+		listFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
+
+// AddListMember
+// @Security 		BearerAuth
+// @Summary 		Add List Member
+// @Description 	this api for adding a user to a list, only its owner can add members and users with a block between them and the owner can not be added
+// @Tags 			list
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "List ID"
+// @Param 			request body entity.ListMemberRequest true "Member"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/lists/{id}/members [POST]
+func (h *HandlerV1) AddListMember(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.ListMemberRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ListID = c.Param("id")
+
+	if !validIDs([]string{request.ListID, request.UserID}) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.OwnerID = cast.ToString(claims["sub"])
+
+	if err := h.List.AddMember(ctx, request); err != nil {
+		listFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
+
+// RemoveListMember
+// @Security 		BearerAuth
+// @Summary 		Remove List Member
+// @Description 	this api for removing a user from a list, only its owner can remove members
+// @Tags 			list
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "List ID"
+// @Param 			user_id path string true "Member ID"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/lists/{id}/members/{user_id} [DELETE]
+func (h *HandlerV1) RemoveListMember(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	request := entity.ListMemberRequest{
+		ListID: c.Param("id"),
+		UserID: c.Param("user_id"),
+	}
+
+	if !validIDs([]string{request.ListID, request.UserID}) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.OwnerID = cast.ToString(claims["sub"])
+
+	if err := h.List.RemoveMember(ctx, request); err != nil {
+		listFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
+
+// ListMembers
+// @Security 		BearerAuth
+// @Summary 		List Members
+// @Description 	this api for getting the users on a list, the most recently added first
+// @Tags 			list
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "List ID"
+// @Success 		200 {object} entity.ListUser
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/lists/{id}/members [GET]
+func (h *HandlerV1) ListMembers(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	listID := c.Param("id")
+	if _, err := uuid.Parse(listID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	members, err := h.List.Members(ctx, listID, cast.ToString(claims["sub"]))
+	if err != nil {
+		listFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// SubscribeList
+// @Security 		BearerAuth
+// @Summary 		Subscribe-Unsubscribe List
+// @Description 	this api for subscribing to a list or cancelling the subscription
+// @Tags 			list
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "List ID"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/lists/{id}/subscribe [POST]
+func (h *HandlerV1) SubscribeList(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	listID := c.Param("id")
+	if _, err := uuid.Parse(listID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	status, err := h.List.Subscribe(ctx, listID, cast.ToString(claims["sub"]))
+	if err != nil {
+		listFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: status,
+	})
+}
+
+// ListTimeline
+// @Security 		BearerAuth
+// @Summary 		List Timeline
+// @Description 	this api for getting the tweets of the members of a list, newest first
+// @Tags 			list
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "List ID"
+// @Param 			cursor query string false "Cursor"
+// @Param 			limit query int false "Limit"
+// @Success 		200 {object} entity.TimelineResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/lists/{id}/timeline [GET]
+func (h *HandlerV1) ListTimeline(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	listID := c.Param("id")
+	if _, err := uuid.Parse(listID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	params, errs := utils.ParseQueryParam(c.Request.URL.Query())
+	if len(errs) > 0 || params.Limit == 0 || params.Limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(errs)
+		return
+	}
+
+	cursor, err := utils.DecodeCursor(params.Filters["cursor"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	timeline, err := h.List.Timeline(ctx, entity.ListTimelineFilter{
+		ListID:   listID,
+		ViewerID: cast.ToString(claims["sub"]),
+		Cursor:   cursor,
+		Limit:    int(params.Limit),
+	})
+	if err != nil {
+		listFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
@@ -0,0 +1,304 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+)
+
+// validMediaIDs tells whether ids could be the distinct media of one tweet, no media is fine
+func validMediaIDs(ids []string) bool {
+	if len(ids) > maxTweetMedia {
+		return false
+	}
+
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil || seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+
+	return true
+}
+
+// limitUpload caps the body of an upload request to files files of the largest kind allowed,
+// so a form over it is not spooled to disk before the sizes are checked
+func (h *HandlerV1) limitUpload(c *gin.Context, files int, kinds ...string) {
+	sizes := map[string]int64{
+		entity.MediaKindImage: h.Config.Media.MaxImageSize,
+		entity.MediaKindGIF:   h.Config.Media.MaxGIFSize,
+		entity.MediaKindVideo: h.Config.Media.MaxVideoSize,
+	}
+
+	var largest int64
+	for _, kind := range kinds {
+		if sizes[kind] > largest {
+			largest = sizes[kind]
+		}
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(files)*largest+multipartOverhead)
+}
+
+// bodyTooLarge answers a form that could not be read because it went over limitUpload
+func bodyTooLarge(c *gin.Context, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+
+	c.JSON(http.StatusRequestEntityTooLarge, entity.Error{
+		Message: entity.MediaTooLarge,
+	})
+	return true
+}
+
+// uploadMedia stores a file of the form as the upload described, it has to be one of its kinds
+func (h *HandlerV1) uploadMedia(ctx context.Context, upload entity.MediaUpload, file *multipart.FileHeader) (entity.Media, error) {
+	src, err := file.Open()
+	if err != nil {
+		return entity.Media{}, err
+	}
+	defer src.Close()
+
+	upload.Name = file.Filename
+	upload.Size = file.Size
+	upload.Body = src
+
+	return h.Media.Upload(ctx, upload)
+}
+
+// mediaFailed answers a failed upload
+func mediaFailed(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errorspkg.ErrorMediaType):
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.UnsupportedMedia,
+		})
+	case errors.Is(err, errorspkg.ErrorMediaSize):
+		c.JSON(http.StatusRequestEntityTooLarge, entity.Error{
+			Message: entity.MediaTooLarge,
+		})
+	case errors.Is(err, errorspkg.ErrorMediaMismatch):
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.MediaMismatch,
+		})
+	case errors.Is(err, errorspkg.ErrorMediaMissing):
+		c.JSON(http.StatusConflict, entity.Error{
+			Message: entity.MediaNotUploaded,
+		})
+	case errors.Is(err, errorspkg.ErrorDirectUpload):
+		c.JSON(http.StatusNotImplemented, entity.Error{
+			Message: entity.DirectUploadOff,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.UploadingError,
+		})
+	}
+	log.Println(err.Error())
+}
+
+// StartMediaUpload
+// @Security 		BearerAuth
+// @Summary 		Start Media Upload
+// @Description 	this api for uploading a file straight to the bucket, the file is PUT to upload_url with the headers given and then the upload is completed with media_id
+// @Tags			media
+// @Accept 			json
+// @Produce 		json
+// @Param 			request body entity.DirectUploadRequest true "File"
+// @Success 		201 {object} entity.DirectUploadResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		413 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Failure 		501 {object} entity.Error
+// @Router 			/v1/media/uploads [POST]
+func (h *HandlerV1) StartMediaUpload(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.DirectUploadRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil || request.Size <= 0 {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.UserID = cast.ToString(claims["sub"])
+
+	response, err := h.Media.StartDirectUpload(ctx, request)
+	if err != nil {
+		mediaFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// CompleteMediaUpload
+// @Security 		BearerAuth
+// @Summary 		Complete Media Upload
+// @Description 	this api for completing a direct upload once the file is in the bucket, the file is checked and its id can go into media_ids of a tweet afterwards
+// @Tags			media
+// @Accept 			json
+// @Produce 		json
+// @Param 			id path string true "Media ID"
+// @Success 		200 {object} entity.Media
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		409 {object} entity.Error
+// @Failure 		413 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Failure 		501 {object} entity.Error
+// @Router 			/v1/media/uploads/{id}/complete [POST]
+func (h *HandlerV1) CompleteMediaUpload(c *gin.Context) {
+	duration, err := time.ParseDuration("15m")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	media, err := h.Media.CompleteDirectUpload(ctx, cast.ToString(claims["sub"]), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, entity.Error{
+			Message: entity.NotFoundData,
+		})
+		return
+	} else if err != nil {
+		mediaFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, media)
+}
+
+// ServeMedia
+// @Security 		BearerAuth
+// @Summary 		Serve Media
+// @Description 	this api for getting a file of a media the viewer may see, the urls of media point here. Range requests are supported
+// @Tags			media
+// @Produce 		application/octet-stream
+// @Param 			id path string true "Media ID"
+// @Param 			file path string true "File name, as in the media urls"
+// @Success 		200 {file} file
+// @Success 		206 {file} file
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		416 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/media/{id}/{file} [GET]
+func (h *HandlerV1) ServeMedia(c *gin.Context) {
+	duration, err := time.ParseDuration("15m")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	file, err := h.Media.Open(ctx, id, c.Param("file"), cast.ToString(claims["sub"]))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, entity.Error{
+			Message: entity.NotFoundData,
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+	defer file.Body.Close()
+
+	// who may see a media can change, so only the viewer's own client keeps it for a while
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("Content-Type", file.ContentType)
+	if file.ETag != "" {
+		c.Header("ETag", file.ETag)
+	}
+
+	http.ServeContent(c.Writer, c.Request, "", file.ModTime, file.Body)
+}
@@ -0,0 +1,580 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+)
+
+// StartConversation
+// @Security 		BearerAuth
+// @Summary 		Start Conversation
+// @Description 	this api for opening a direct conversation with a user, an existing one is returned with 200
+// @Tags 			message
+// @Accept			json
+// @Produce 		json
+// @Param 			request body entity.StartConversationRequest true "Recipient"
+// @Success 		200 {object} entity.Conversation
+// @Success 		201 {object} entity.Conversation
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/conversations [POST]
+func (h *HandlerV1) StartConversation(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.StartConversationRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ID = uuid.NewString()
+	request.UserID = cast.ToString(claims["sub"])
+
+	// checking: the recipient is a valid id of someone else
+	if _, err := uuid.Parse(request.RecipientID); err != nil || request.RecipientID == request.UserID {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	conversation, created, err := h.Message.StartConversation(ctx, request)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		if errors.Is(err, errorspkg.ErrorBlocked) {
+			c.JSON(http.StatusForbidden, entity.Error{
+				Message: entity.Blocked,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	if created {
+		c.JSON(http.StatusCreated, conversation)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// ListConversations
+// @Security 		BearerAuth
+// @Summary 		List Conversations
+// @Description 	this api for getting the conversations of the current user, the most recently active first
+// @Tags 			message
+// @Accept			json
+// @Produce 		json
+// @Param 			cursor query string false "Cursor"
+// @Param 			limit query int false "Limit"
+// @Success 		200 {object} entity.ConversationsResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/conversations [GET]
+func (h *HandlerV1) ListConversations(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	params, errs := utils.ParseQueryParam(c.Request.URL.Query())
+	if len(errs) > 0 || params.Limit == 0 || params.Limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(errs)
+		return
+	}
+
+	cursor, err := utils.DecodeCursor(params.Filters["cursor"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	conversations, err := h.Message.ListConversations(ctx, entity.ConversationFilter{
+		UserID: cast.ToString(claims["sub"]),
+		Cursor: cursor,
+		Limit:  int(params.Limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, conversations)
+}
+
+// GetConversation
+// @Security 		BearerAuth
+// @Summary 		Get Conversation
+// @Description 	this api for getting a conversation of the current user with its read receipts
+// @Tags 			message
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Conversation ID"
+// @Success 		200 {object} entity.Conversation
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/conversations/{id} [GET]
+func (h *HandlerV1) GetConversation(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	conversation, err := h.Message.GetConversation(ctx, id, cast.ToString(claims["sub"]))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// SendMessage
+// @Security 		BearerAuth
+// @Summary 		Send Message
+// @Description 	this api for sending a message to a conversation, the other members get it over the websocket
+// @Tags 			message
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Conversation ID"
+// @Param 			request body entity.SendMessageRequest true "Message"
+// @Success 		201 {object} entity.Message
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/conversations/{id}/messages [POST]
+func (h *HandlerV1) SendMessage(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.SendMessageRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ConversationID = c.Param("id")
+
+	// checking: the conversation id is valid and the message is neither blank nor too long
+	if _, err := uuid.Parse(request.ConversationID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	} else if strings.TrimSpace(request.Content) == "" || utf8.RuneCountInString(request.Content) > maxMessageLength {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ID = uuid.NewString()
+	request.SenderID = cast.ToString(claims["sub"])
+
+	message, err := h.Message.SendMessage(ctx, request)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		if errors.Is(err, errorspkg.ErrorBlocked) {
+			c.JSON(http.StatusForbidden, entity.Error{
+				Message: entity.Blocked,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
+
+// ListMessages
+// @Security 		BearerAuth
+// @Summary 		List Messages
+// @Description 	this api for getting the messages of a conversation, newest first
+// @Tags 			message
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Conversation ID"
+// @Param 			cursor query string false "Cursor"
+// @Param 			limit query int false "Limit"
+// @Success 		200 {object} entity.MessagesResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/conversations/{id}/messages [GET]
+func (h *HandlerV1) ListMessages(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	params, errs := utils.ParseQueryParam(c.Request.URL.Query())
+	if len(errs) > 0 || params.Limit == 0 || params.Limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(errs)
+		return
+	}
+
+	cursor, err := utils.DecodeCursor(params.Filters["cursor"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	messages, err := h.Message.ListMessages(ctx, entity.MessageFilter{
+		ConversationID: id,
+		UserID:         cast.ToString(claims["sub"]),
+		Cursor:         cursor,
+		Limit:          int(params.Limit),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+// ReadConversation
+// @Security 		BearerAuth
+// @Summary 		Read Conversation
+// @Description 	this api for marking every message of a conversation read, the other members get the receipt over the websocket
+// @Tags 			message
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Conversation ID"
+// @Success 		200 {object} entity.ReadReceipt
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/conversations/{id}/read [POST]
+func (h *HandlerV1) ReadConversation(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	receipt, err := h.Message.MarkRead(ctx, id, cast.ToString(claims["sub"]))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, receipt)
+}
+
+// MuteConversation
+// @Security 		BearerAuth
+// @Summary 		Mute Conversation
+// @Description 	this api for muting or unmuting a conversation, muted conversations are not pushed over the websocket. An empty duration mutes it until unmuted
+// @Tags 			message
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Conversation ID"
+// @Param 			request body entity.MuteConversationRequest true "Mute setting, duration like 8h"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/conversations/{id}/mute [PUT]
+func (h *HandlerV1) MuteConversation(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.MuteConversationRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.ConversationID = c.Param("id")
+	if _, err := uuid.Parse(request.ConversationID); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	if request.Muted && request.Duration != "" {
+		muteFor, err := time.ParseDuration(request.Duration)
+		if err != nil || muteFor <= 0 {
+			c.JSON(http.StatusBadRequest, entity.Error{
+				Message: entity.IncorrectData,
+			})
+			return
+		}
+
+		until := time.Now().UTC().Add(muteFor)
+		request.MutedUntil = &until
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.UserID = cast.ToString(claims["sub"])
+
+	if err := h.Message.Mute(ctx, request); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
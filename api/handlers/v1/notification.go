@@ -0,0 +1,148 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+)
+
+// ListNotifications
+// @Security 		BearerAuth
+// @Summary 		List Notifications
+// @Description 	this api for getting notifications of the current user, newest first
+// @Tags 			notification
+// @Accept			json
+// @Produce 		json
+// @Param 			cursor query string false "Cursor"
+// @Param 			limit query int false "Limit"
+// @Success 		200 {object} entity.NotificationsResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/notifications [GET]
+func (h *HandlerV1) ListNotifications(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	params, errs := utils.ParseQueryParam(c.Request.URL.Query())
+	if len(errs) > 0 || params.Limit == 0 || params.Limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(errs)
+		return
+	}
+
+	cursor, err := utils.DecodeCursor(params.Filters["cursor"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	notifications, err := h.Notification.List(ctx, entity.NotificationFilter{
+		UserID: cast.ToString(claims["sub"]),
+		Cursor: cursor,
+		Limit:  int(params.Limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// ReadNotifications
+// @Security 		BearerAuth
+// @Summary 		Read Notifications
+// @Description 	this api for marking notifications read, an empty ids list or no body marks all of them
+// @Tags 			notification
+// @Accept			json
+// @Produce 		json
+// @Param 			request body entity.ReadNotificationsRequest false "Notification IDs"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/notifications/read [POST]
+func (h *HandlerV1) ReadNotifications(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.ReadNotificationsRequest
+
+	// a request without a body marks all of them read
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.UserID = cast.ToString(claims["sub"])
+
+	if err := h.Notification.MarkRead(ctx, request); err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+)
+
+// validPoll tells whether a poll has a valid duration and distinct options that are neither
+// blank nor too long, the options are trimmed in place
+func validPoll(poll *entity.PollRequest) bool {
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return false
+	}
+
+	if poll.DurationMinutes < minPollDuration || poll.DurationMinutes > maxPollDuration {
+		return false
+	}
+
+	seen := make(map[string]struct{}, len(poll.Options))
+	for i, option := range poll.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > maxPollOptionLength {
+			return false
+		}
+
+		if _, ok := seen[option]; ok {
+			return false
+		}
+		seen[option] = struct{}{}
+
+		poll.Options[i] = option
+	}
+
+	return true
+}
+
+// VotePoll
+// @Security 		BearerAuth
+// @Summary 		Vote Poll
+// @Description 	this api for voting in the poll of a tweet, each user votes once and sees the results afterwards
+// @Tags 			tweet
+// @Accept			json
+// @Produce 		json
+// @Param 			id path string true "Tweet ID"
+// @Param 			request body entity.PollVote true "Vote"
+// @Success 		200 {object} entity.Poll
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/tweets/{id}/poll/vote [POST]
+func (h *HandlerV1) VotePoll(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var request entity.PollVote
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.TweetID = c.Param("id")
+
+	if !validIDs([]string{request.TweetID, request.OptionID}) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	request.UserID = cast.ToString(claims["sub"])
+
+	poll, err := h.Poll.Vote(ctx, request)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		} else if errors.Is(err, errorspkg.ErrorPollClosed) {
+			c.JSON(http.StatusBadRequest, entity.Error{
+				Message: entity.PollClosed,
+			})
+			return
+		} else if errors.Is(err, errorspkg.ErrorConflict) {
+			c.JSON(http.StatusBadRequest, entity.Error{
+				Message: entity.AlreadyVoted,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, poll)
+}
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/spf13/cast"
 
 	"github.com/gin-gonic/gin"
 )
@@ -40,7 +42,16 @@ func (h *HandlerV1) SearchTweet(c *gin.Context) {
 
 	data := c.Param("data")
 
-	response, err := h.Search.Search(ctx, data)
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	response, err := h.Search.Search(ctx, data, cast.ToString(claims["sub"]))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, entity.Error{
 			Message: entity.ServerError,
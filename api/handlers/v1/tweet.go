@@ -4,13 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
-	"path"
+	"strconv"
 	"time"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
 	"github.com/spf13/cast"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
@@ -22,15 +22,16 @@ import (
 // UploadTweetFiles
 // @Security 		BearerAuth
 // @Summary 		Upload Tweet Files
-// @Description 	this api for creating a new tweet
+// @Description 	this api for uploading images, gifs and videos, the returned ids go into media_ids of a tweet
 // @Tags			tweet
 // @Accept 			multipart/form-data
 // @Produce 		json
 // @Param 			files formData []file true "Tweet Files"
-// @Success 		201 {object} []string
+// @Success 		201 {object} []entity.Media
 // @Failure 		400 {object} entity.Error
 // @Failure 		401 {object} entity.Error
 // @Failure 		403 {object} entity.Error
+// @Failure 		413 {object} entity.Error
 // @Failure 		500 {object} entity.Error
 // @Router 			/v1/tweets/upload [POST]
 func (h *HandlerV1) UploadTweetFiles(c *gin.Context) {
@@ -43,11 +44,16 @@ func (h *HandlerV1) UploadTweetFiles(c *gin.Context) {
 		return
 	}
 
-	_, cancel := context.WithTimeout(context.Background(), duration)
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
 	defer cancel()
 
+	kinds := []string{entity.MediaKindImage, entity.MediaKindGIF, entity.MediaKindVideo}
+	h.limitUpload(c, maxTweetMedia, kinds...)
+
 	form, err := c.MultipartForm()
-	if err != nil {
+	if bodyTooLarge(c, err) {
+		return
+	} else if err != nil {
 		c.JSON(http.StatusBadRequest, entity.Error{
 			Message: "Error parsing form-data",
 		})
@@ -55,30 +61,47 @@ func (h *HandlerV1) UploadTweetFiles(c *gin.Context) {
 	}
 	files := form.File["files"]
 
-	var fileURLs []string
+	if len(files) == 0 || len(files) > maxTweetMedia {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
 
-	for _, file := range files {
-		uuidPath := uuid.NewString() + path.Ext(file.Filename)
-		filePath := fmt.Sprintf("./media/tweets/%s", uuidPath)
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
 
-		if err := c.SaveUploadedFile(file, filePath); err != nil {
-			c.JSON(http.StatusInternalServerError, entity.Error{
-				Message: entity.UploadingError,
-			})
-			log.Println(err.Error())
+	userID := cast.ToString(claims["sub"])
+
+	var uploaded []entity.Media
+
+	for _, file := range files {
+		media, err := h.uploadMedia(ctx, entity.MediaUpload{
+			UserID:  userID,
+			Purpose: entity.MediaPurposeTweet,
+			Kinds:   kinds,
+		}, file)
+		if err != nil {
+			mediaFailed(c, err)
 			return
 		}
 
-		fileURLs = append(fileURLs, uuidPath)
+		uploaded = append(uploaded, media)
 	}
 
-	c.JSON(http.StatusOK, fileURLs)
+	c.JSON(http.StatusCreated, uploaded)
 }
 
 // CreateTweet
 // @Security 		BearerAuth
 // @Summary 		Create Tweet
-// @Description 	this api for creating a new tweet
+// @Description 	this api for creating a new tweet with media uploaded by the caller, a tweet with a poll needs content and can not carry media
 // @Tags			tweet
 // @Accept 			json
 // @Produce 		json
@@ -123,13 +146,23 @@ func (h *HandlerV1) CreateTweet(c *gin.Context) {
 
 	UserId := cast.ToString(claims["sub"])
 
-	// checking: post or repost
-	if request.ParentTweetID != nil && request.Content != nil {
+	// checking: a tweet has content, it can reply to or quote one tweet, reposts go through retweet
+	if request.Content == nil && len(request.MediaIDs) == 0 {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	} else if request.ParentTweetID != nil && request.QuoteTweetID != nil {
 		c.JSON(http.StatusBadRequest, entity.Error{
 			Message: entity.IncorrectData,
 		})
 		return
-	} else if request.ParentTweetID == nil && request.Content == nil {
+	} else if !validMediaIDs(request.MediaIDs) {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	} else if request.Poll != nil && (request.Content == nil || len(request.MediaIDs) > 0 || !validPoll(request.Poll)) {
 		c.JSON(http.StatusBadRequest, entity.Error{
 			Message: entity.IncorrectData,
 		})
@@ -137,18 +170,40 @@ func (h *HandlerV1) CreateTweet(c *gin.Context) {
 	}
 
 	response, err := h.Tweet.CreateTweet(ctx, entity.CreateTweetRequest{
-		ID:            uuid.NewString(),
-		UserID:        UserId,
-		ParentTweetID: request.ParentTweetID,
-		Content:       request.Content,
-		URLs:          request.URLs,
+		ID:              uuid.NewString(),
+		UserID:          UserId,
+		ParentTweetID:   request.ParentTweetID,
+		OriginalTweetID: request.QuoteTweetID,
+		Content:         request.Content,
+		MediaIDs:        request.MediaIDs,
+		Poll:            request.Poll,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, entity.Error{
-			Message: entity.ServerError,
-		})
-		log.Println(err.Error())
-		return
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		} else if errors.Is(err, errorspkg.ErrorBlocked) {
+			c.JSON(http.StatusForbidden, entity.Error{
+				Message: entity.Blocked,
+			})
+			log.Println(err.Error())
+			return
+		} else if errors.Is(err, errorspkg.ErrorNoPermission) {
+			c.JSON(http.StatusForbidden, entity.Error{
+				Message: entity.NoAccess,
+			})
+			log.Println(err.Error())
+			return
+		} else {
+			c.JSON(http.StatusInternalServerError, entity.Error{
+				Message: entity.ServerError,
+			})
+			log.Println(err.Error())
+			return
+		}
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -158,7 +213,7 @@ func (h *HandlerV1) CreateTweet(c *gin.Context) {
 // UpdateTweet
 // @Security 		BearerAuth
 // @Summary 		Update Tweet
-// @Description 	this api for updating a tweet
+// @Description 	this api for editing a tweet within the edit window after posting, the replaced content is kept in the tweet history
 // @Tags			tweet
 // @Accept 			json
 // @Produce 		json
@@ -168,6 +223,7 @@ func (h *HandlerV1) CreateTweet(c *gin.Context) {
 // @Failure 		401 {object} entity.Error
 // @Failure 		403 {object} entity.Error
 // @Failure 		404 {object} entity.Error
+// @Failure 		409 {object} entity.Error
 // @Failure 		500 {object} entity.Error
 // @Router 			/v1/tweets [PUT]
 func (h *HandlerV1) UpdateTweet(c *gin.Context) {
@@ -204,7 +260,7 @@ func (h *HandlerV1) UpdateTweet(c *gin.Context) {
 
 	userId := cast.ToString(claims["sub"])
 
-	tweet, err := h.Tweet.GetTweet(ctx, request.ID)
+	tweet, err := h.Tweet.GetTweet(ctx, request.ID, userId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			c.JSON(http.StatusNotFound, entity.Error{
@@ -228,16 +284,49 @@ func (h *HandlerV1) UpdateTweet(c *gin.Context) {
 		return
 	}
 
-	// no update reposted tweet
-	if tweet.ParentTweetID != nil {
+	// no update reposted tweet or reply
+	if tweet.Kind == entity.TweetKindRetweet || tweet.ParentTweetID != nil {
 		c.JSON(http.StatusBadRequest, entity.Error{
 			Message: entity.IncorrectData,
 		})
 		return
 	}
 
+	request.UserID = userId
+
 	response, err := h.Tweet.UpdateTweet(ctx, request)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		} else if errors.Is(err, errorspkg.ErrorNoPermission) {
+			c.JSON(http.StatusUnauthorized, entity.Error{
+				Message: entity.NoAccess,
+			})
+			log.Println(err.Error())
+			return
+		} else if errors.Is(err, errorspkg.ErrorEditClosed) {
+			c.JSON(http.StatusBadRequest, entity.Error{
+				Message: entity.EditWindowClosed,
+			})
+			log.Println(err.Error())
+			return
+		} else if errors.Is(err, errorspkg.ErrorEditLimit) {
+			c.JSON(http.StatusBadRequest, entity.Error{
+				Message: entity.EditLimitReached,
+			})
+			log.Println(err.Error())
+			return
+		} else if errors.Is(err, errorspkg.ErrorConflict) {
+			c.JSON(http.StatusConflict, entity.Error{
+				Message: entity.EditConflict,
+			})
+			log.Println(err.Error())
+			return
+		}
 		c.JSON(http.StatusInternalServerError, entity.Error{
 			Message: entity.ServerError,
 		})
@@ -289,7 +378,7 @@ func (h *HandlerV1) DeleteTweet(c *gin.Context) {
 
 	userId := cast.ToString(claims["sub"])
 
-	tweet, err := h.Tweet.GetTweet(ctx, id)
+	tweet, err := h.Tweet.GetTweet(ctx, id, userId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			c.JSON(http.StatusNotFound, entity.Error{
@@ -335,6 +424,134 @@ func (h *HandlerV1) DeleteTweet(c *gin.Context) {
 	})
 }
 
+// Retweet
+// @Security 		BearerAuth
+// @Summary 		Retweet
+// @Description 	this api for reposting a tweet with id
+// @Tags			tweet
+// @Accept 			json
+// @Produce 		json
+// @Param 			id path string true "Tweet ID"
+// @Success 		201 {object} entity.CreateTweetResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/tweets/{id}/retweet [POST]
+func (h *HandlerV1) Retweet(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	response, err := h.Tweet.Retweet(ctx, entity.RetweetAction{
+		ID:      uuid.NewString(),
+		UserID:  cast.ToString(claims["sub"]),
+		TweetID: c.Param("id"),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		} else if errors.Is(err, errorspkg.ErrorConflict) {
+			c.JSON(http.StatusBadRequest, entity.Error{
+				Message: entity.AlreadyRetweeted,
+			})
+			return
+		} else {
+			c.JSON(http.StatusInternalServerError, entity.Error{
+				Message: entity.ServerError,
+			})
+			log.Println(err.Error())
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// UndoRetweet
+// @Security 		BearerAuth
+// @Summary 		Undo Retweet
+// @Description 	this api for removing own retweet of a tweet with id
+// @Tags			tweet
+// @Accept 			json
+// @Produce 		json
+// @Param 			id path string true "Tweet ID"
+// @Success 		200 {object} entity.ResponseWithStatus
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/tweets/{id}/retweet [DELETE]
+func (h *HandlerV1) UndoRetweet(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	err = h.Tweet.UndoRetweet(ctx, entity.RetweetAction{
+		UserID:  cast.ToString(claims["sub"]),
+		TweetID: c.Param("id"),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		} else {
+			c.JSON(http.StatusInternalServerError, entity.Error{
+				Message: entity.ServerError,
+			})
+			log.Println(err.Error())
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, entity.ResponseWithStatus{
+		Status: true,
+	})
+}
+
 // GetTweet
 // @Security 		BearerAuth
 // @Summary 		Get Tweet
@@ -365,7 +582,16 @@ func (h *HandlerV1) GetTweet(c *gin.Context) {
 
 	id := c.Param("id")
 
-	tweet, err := h.Tweet.GetTweet(ctx, id)
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	tweet, err := h.Tweet.GetTweet(ctx, id, cast.ToString(claims["sub"]))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			c.JSON(http.StatusNotFound, entity.Error{
@@ -385,6 +611,165 @@ func (h *HandlerV1) GetTweet(c *gin.Context) {
 	c.JSON(http.StatusOK, tweet)
 }
 
+// TweetHistory
+// @Security 		BearerAuth
+// @Summary 		Tweet History
+// @Description 	this api for getting every version of a tweet, the current one first
+// @Tags			tweet
+// @Accept 			json
+// @Produce 		json
+// @Param 			id path string true "Tweet ID"
+// @Success 		200 {object} entity.TweetHistoryResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/tweets/{id}/history [GET]
+func (h *HandlerV1) TweetHistory(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	history, err := h.Tweet.TweetHistory(ctx, id, cast.ToString(claims["sub"]))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// Thread
+// @Security 		BearerAuth
+// @Summary 		Tweet Thread
+// @Description 	this api for getting a tweet with its ancestors and a page of its replies as a tree
+// @Tags			tweet
+// @Accept 			json
+// @Produce 		json
+// @Param 			id path string true "Tweet ID"
+// @Param 			depth query int false "Depth"
+// @Param 			cursor query string false "Cursor"
+// @Param 			limit query int false "Limit"
+// @Success 		200 {object} entity.ThreadResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		404 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/tweets/{id}/thread [GET]
+func (h *HandlerV1) Thread(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	params, errs := utils.ParseQueryParam(c.Request.URL.Query())
+	if len(errs) > 0 || params.Limit == 0 || params.Limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(errs)
+		return
+	}
+
+	depth := defaultThreadDepth
+	if value, ok := params.Filters["depth"]; ok {
+		depth, err = strconv.Atoi(value)
+		if err != nil || depth < 1 || depth > maxThreadDepth {
+			c.JSON(http.StatusBadRequest, entity.Error{
+				Message: entity.IncorrectData,
+			})
+			log.Println("invalid `depth` param")
+			return
+		}
+	}
+
+	cursor, err := utils.DecodeCursor(params.Filters["cursor"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	thread, err := h.Tweet.Thread(ctx, entity.ThreadFilter{
+		TweetID:  c.Param("id"),
+		ViewerID: cast.ToString(claims["sub"]),
+		Depth:    depth,
+		Cursor:   cursor,
+		Limit:    int(params.Limit),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, entity.Error{
+				Message: entity.NotFoundData,
+			})
+			log.Println(err.Error())
+			return
+		}
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
 // GetTweets
 // @Security 		BearerAuth
 // @Summary 		List Tweet
@@ -423,10 +808,19 @@ func (h *HandlerV1) ListTweets(c *gin.Context) {
 		return
 	}
 
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
 	tweets, err := h.Tweet.ListTweets(ctx, entity.Filter{
 		Page:  int(params.Page),
 		Limit: int(params.Limit),
-	})
+	}, cast.ToString(claims["sub"]))
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -485,7 +879,7 @@ func (h *HandlerV1) UserTweets(c *gin.Context) {
 
 	userId := cast.ToString(claims["sub"])
 
-	tweets, err := h.Tweet.UserTweets(ctx, userId)
+	tweets, err := h.Tweet.UserTweets(ctx, userId, userId)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -505,3 +899,145 @@ func (h *HandlerV1) UserTweets(c *gin.Context) {
 
 	c.JSON(http.StatusOK, tweets)
 }
+
+// HomeTimeline
+// @Security 		BearerAuth
+// @Summary 		Home Timeline
+// @Description 	this api for getting tweets of followed users and own tweets, newest first
+// @Tags			tweet
+// @Accept 			json
+// @Produce 		json
+// @Param 			cursor query string false "Cursor"
+// @Param 			limit query int false "Limit"
+// @Success 		200 {object} entity.TimelineResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/timeline [GET]
+func (h *HandlerV1) HomeTimeline(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	params, errs := utils.ParseQueryParam(c.Request.URL.Query())
+	if len(errs) > 0 || params.Limit == 0 || params.Limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(errs)
+		return
+	}
+
+	cursor, err := utils.DecodeCursor(params.Filters["cursor"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	timeline, err := h.Tweet.HomeTimeline(ctx, entity.TimelineFilter{
+		UserID: cast.ToString(claims["sub"]),
+		Cursor: cursor,
+		Limit:  int(params.Limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// MentionTweets
+// @Security 		BearerAuth
+// @Summary 		My Mentions
+// @Description 	this api for getting tweets that mention the current user, newest first
+// @Tags			tweet
+// @Accept 			json
+// @Produce 		json
+// @Param 			cursor query string false "Cursor"
+// @Param 			limit query int false "Limit"
+// @Success 		200 {object} entity.TimelineResponse
+// @Failure 		400 {object} entity.Error
+// @Failure 		401 {object} entity.Error
+// @Failure 		403 {object} entity.Error
+// @Failure 		500 {object} entity.Error
+// @Router 			/v1/users/me/mentions [GET]
+func (h *HandlerV1) MentionTweets(c *gin.Context) {
+	duration, err := time.ParseDuration(h.Config.Context.TimeOut)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	params, errs := utils.ParseQueryParam(c.Request.URL.Query())
+	if len(errs) > 0 || params.Limit == 0 || params.Limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(errs)
+		return
+	}
+
+	cursor, err := utils.DecodeCursor(params.Filters["cursor"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, entity.Error{
+			Message: entity.IncorrectData,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	claims, err := utils.GetClaimsFromToken(c.Request, h.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	tweets, err := h.Tweet.MentionTweets(ctx, entity.TimelineFilter{
+		UserID: cast.ToString(claims["sub"]),
+		Cursor: cursor,
+		Limit:  int(params.Limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entity.Error{
+			Message: entity.ServerError,
+		})
+		log.Println(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, tweets)
+}
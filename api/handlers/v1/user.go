@@ -4,13 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
-	awss3 "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/awsS3"
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/etc"
 	tokens "github.com/dostonshernazarov/mini-twitter/internal/pkg/token"
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
@@ -226,11 +224,18 @@ func (h *HandlerV1) UpdateUser(c *gin.Context) {
 		}
 	}
 
+	// leaving is_protected out keeps the current setting
+	isProtected := user.IsProtected
+	if request.IsProtected != nil {
+		isProtected = *request.IsProtected
+	}
+
 	err = h.User.Update(ctx, entity.UpdateUserRequest{
-		ID:       id,
-		Name:     request.Name,
-		Username: request.Username,
-		Bio:      request.Bio,
+		ID:          id,
+		Name:        request.Name,
+		Username:    request.Username,
+		Bio:         request.Bio,
+		IsProtected: isProtected,
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, entity.Error{
@@ -408,16 +413,17 @@ func (h *HandlerV1) ListUsers(c *gin.Context) {
 // UploadProfilePhoto
 // @Security 		BearerAuth
 // @Summary 		Upload User Profile photo
-// @Description 	this api for uploading user profile
+// @Description 	this api for uploading a profile photo, it is stored without its metadata in several sizes and as WebP
 // @Tags 			user
 // @Accept 			json
 // @Produce 		json
 // @Param 			avatar formData file true "User Profile Photo"
-// @Success 		201 {object} string
+// @Success 		200 {object} entity.MediaURLs
 // @Failure 		400 {object} entity.Error
 // @Failure 		401 {object} entity.Error
 // @Failure 		403 {object} entity.Error
 // @Failure 		404 {object} entity.Error
+// @Failure 		413 {object} entity.Error
 // @Failure 		500 {object} entity.Error
 // @Router 			/v1/users/upload-photo [POST]
 func (h *HandlerV1) UploadProfilePhoto(c *gin.Context) {
@@ -444,8 +450,12 @@ func (h *HandlerV1) UploadProfilePhoto(c *gin.Context) {
 
 	id := cast.ToString(claims["sub"])
 
+	h.limitUpload(c, 1, entity.MediaKindImage)
+
 	file, err := c.FormFile("avatar")
-	if err != nil {
+	if bodyTooLarge(c, err) {
+		return
+	} else if err != nil {
 		c.JSON(http.StatusBadRequest, entity.Error{
 			Message: entity.IncorrectData,
 		})
@@ -454,35 +464,17 @@ func (h *HandlerV1) UploadProfilePhoto(c *gin.Context) {
 
 	}
 
-	src, err := file.Open()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, entity.Error{
-			Message: entity.ServerError,
-		})
-		log.Println(err.Error())
-		return
-	}
-
-	defer src.Close()
-
-	url, uniqueFile, err := awss3.UploadFileToS3(h.Config, src, file.Filename)
+	media, err := h.uploadMedia(ctx, entity.MediaUpload{
+		UserID:  id,
+		Purpose: entity.MediaPurposeProfile,
+		Kinds:   []string{entity.MediaKindImage},
+	}, file)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, entity.Error{
-			Message: entity.ServerError,
-		})
-		log.Println(err.Error())
-		return
-	}
-
-	if err := c.SaveUploadedFile(file, fmt.Sprintf("./media/users/%s", uniqueFile)); err != nil {
-		c.JSON(http.StatusBadRequest, entity.Error{
-			Message: entity.ServerError,
-		})
-		log.Println(err.Error())
+		mediaFailed(c, err)
 		return
 	}
 
-	if err := h.User.UploadImage(ctx, id, url); err != nil {
+	if err := h.User.UploadImage(ctx, id, media.URLs); err != nil {
 		c.JSON(http.StatusInternalServerError, entity.Error{
 			Message: entity.ServerError,
 		})
@@ -490,9 +482,7 @@ func (h *HandlerV1) UploadProfilePhoto(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": url,
-	})
+	c.JSON(http.StatusOK, media.URLs)
 }
 
 // GetUser
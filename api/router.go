@@ -1,7 +1,6 @@
 package api
 
 import (
-	"net/http"
 	"time"
 
 	"github.com/casbin/casbin/v2"
@@ -11,6 +10,7 @@ import (
 
 	"github.com/dostonshernazarov/mini-twitter/api/websocket"
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/eventbus"
 	"github.com/dostonshernazarov/mini-twitter/internal/usecase"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -32,8 +32,19 @@ type RouteOption struct {
 	User           usecase.User
 	Tweet          usecase.Twit
 	Follow         usecase.Follow
+	Block          usecase.Block
+	Bookmark       usecase.Bookmark
+	List           usecase.List
+	Poll           usecase.Poll
+	Draft          usecase.Draft
+	Media          usecase.Media
 	Search         usecase.Search
 	Like           usecase.Like
+	Trend          usecase.Trend
+	Notification   usecase.Notification
+	Message        usecase.Message
+	Hub            *websocket.Hub
+	EventBus       eventbus.Bus
 }
 
 // NewRoute
@@ -68,8 +79,18 @@ func NewRoute(option RouteOption) *gin.Engine {
 		User:           option.User,
 		Tweet:          option.Tweet,
 		Follow:         option.Follow,
+		Block:          option.Block,
+		Bookmark:       option.Bookmark,
+		List:           option.List,
+		Poll:           option.Poll,
+		Draft:          option.Draft,
+		Media:          option.Media,
 		Search:         option.Search,
 		Like:           option.Like,
+		Trend:          option.Trend,
+		Notification:   option.Notification,
+		Message:        option.Message,
+		EventBus:       option.EventBus,
 	})
 
 	api := router.Group("/v1")
@@ -89,8 +110,12 @@ func NewRoute(option RouteOption) *gin.Engine {
 		api.GET("/users", HandlerV1.GetUser)
 		api.GET("/users/list", HandlerV1.ListUsers)
 		api.GET("/users/profile", HandlerV1.GetUserProfile)
+		api.GET("/users/me/mentions", HandlerV1.MentionTweets)
 		api.POST("/users/upload-photo", HandlerV1.UploadProfilePhoto)
 
+		api.POST("/media/uploads", HandlerV1.StartMediaUpload)
+		api.POST("/media/uploads/:id/complete", HandlerV1.CompleteMediaUpload)
+
 		api.POST("/tweets/upload", HandlerV1.UploadTweetFiles)
 		api.POST("/tweets", HandlerV1.CreateTweet)
 		api.PUT("/tweets", HandlerV1.UpdateTweet)
@@ -98,6 +123,21 @@ func NewRoute(option RouteOption) *gin.Engine {
 		api.GET("/tweets/:id", HandlerV1.GetTweet)
 		api.GET("/tweets", HandlerV1.ListTweets)
 		api.GET("/tweets/users/:id", HandlerV1.UserTweets)
+		api.POST("/tweets/:id/retweet", HandlerV1.Retweet)
+		api.DELETE("/tweets/:id/retweet", HandlerV1.UndoRetweet)
+		api.GET("/tweets/:id/thread", HandlerV1.Thread)
+		api.GET("/tweets/:id/history", HandlerV1.TweetHistory)
+		api.POST("/tweets/:id/poll/vote", HandlerV1.VotePoll)
+		api.POST("/drafts", HandlerV1.CreateDraft)
+		api.GET("/drafts", HandlerV1.Drafts)
+		api.GET("/drafts/scheduled", HandlerV1.ScheduledTweets)
+		api.PUT("/drafts/:id", HandlerV1.UpdateDraft)
+		api.DELETE("/drafts/:id", HandlerV1.DeleteDraft)
+		api.POST("/drafts/:id/publish", HandlerV1.PublishDraft)
+		api.GET("/timeline", HandlerV1.HomeTimeline)
+
+		api.GET("/hashtags/:tag/tweets", HandlerV1.HashtagTweets)
+		api.GET("/trends", HandlerV1.Trends)
 
 		api.GET("/search/:data", HandlerV1.SearchTweet)
 		api.POST("/likes", HandlerV1.LikeTweet)
@@ -105,14 +145,59 @@ func NewRoute(option RouteOption) *gin.Engine {
 		api.POST("/follows", HandlerV1.FollowUnfollow)
 		api.GET("/followings", HandlerV1.Followings)
 		api.GET("/followers", HandlerV1.Followers)
+		api.GET("/follow-requests", HandlerV1.FollowRequests)
+		api.POST("/follow-requests/:user_id/approve", HandlerV1.ApproveFollowRequest)
+		api.POST("/follow-requests/:user_id/reject", HandlerV1.RejectFollowRequest)
+		api.POST("/blocks", HandlerV1.BlockUnblock)
+		api.GET("/blocks", HandlerV1.Blocks)
+		api.POST("/mutes", HandlerV1.MuteUnmute)
+		api.GET("/mutes", HandlerV1.Mutes)
+		api.POST("/tweets/:id/bookmark", HandlerV1.BookmarkTweet)
+		api.DELETE("/tweets/:id/bookmark", HandlerV1.RemoveBookmark)
+		api.GET("/bookmarks", HandlerV1.Bookmarks)
+		api.POST("/bookmarks/folders", HandlerV1.CreateBookmarkFolder)
+		api.GET("/bookmarks/folders", HandlerV1.BookmarkFolders)
+		api.DELETE("/bookmarks/folders/:id", HandlerV1.DeleteBookmarkFolder)
+		api.POST("/lists", HandlerV1.CreateList)
+		api.GET("/lists", HandlerV1.Lists)
+		api.GET("/lists/:id", HandlerV1.GetList)
+		api.PUT("/lists/:id", HandlerV1.UpdateList)
+		api.DELETE("/lists/:id", HandlerV1.DeleteList)
+		api.POST("/lists/:id/members", HandlerV1.AddListMember)
+		api.GET("/lists/:id/members", HandlerV1.ListMembers)
+		api.DELETE("/lists/:id/members/:user_id", HandlerV1.RemoveListMember)
+		api.POST("/lists/:id/subscribe", HandlerV1.SubscribeList)
+		api.GET("/lists/:id/timeline", HandlerV1.ListTimeline)
+
+		api.GET("/notifications", HandlerV1.ListNotifications)
+		api.POST("/notifications/read", HandlerV1.ReadNotifications)
+
+		api.POST("/conversations", HandlerV1.StartConversation)
+		api.GET("/conversations", HandlerV1.ListConversations)
+		api.GET("/conversations/:id", HandlerV1.GetConversation)
+		api.POST("/conversations/:id/messages", HandlerV1.SendMessage)
+		api.GET("/conversations/:id/messages", HandlerV1.ListMessages)
+		api.POST("/conversations/:id/read", HandlerV1.ReadConversation)
+		api.PUT("/conversations/:id/mute", HandlerV1.MuteConversation)
+
+		api.POST("/groups", HandlerV1.CreateGroup)
+		api.PUT("/groups/:id", HandlerV1.UpdateGroup)
+		api.POST("/groups/:id/avatar", HandlerV1.UploadGroupAvatar)
+		api.POST("/groups/:id/members", HandlerV1.AddGroupMembers)
+		api.PUT("/groups/:id/members/:user_id", HandlerV1.SetGroupMemberRole)
+		api.DELETE("/groups/:id/members/:user_id", HandlerV1.RemoveGroupMember)
+		api.POST("/groups/:id/leave", HandlerV1.LeaveGroup)
 
 	}
 
 	url := ginSwagger.URL("swagger/doc.json")
 	api.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, url))
 
-	// Websocker router
-	http.HandleFunc("/ws", websocket.WsHandler)
+	// media are private in their store, the app serves them to the viewers allowed to see them
+	router.GET("/media/:id/:file", HandlerV1.ServeMedia)
+
+	// websocket router
+	router.GET("/ws", websocket.NewHandler(option.Hub, option.Config))
 
 	return router
 }
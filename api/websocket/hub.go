@@ -0,0 +1,164 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait is the time allowed to write one message to a peer
+	writeWait = 10 * time.Second
+	// pongWait is the time allowed to read the next pong from a peer
+	pongWait = 60 * time.Second
+	// pingPeriod must be shorter than pongWait so a live peer is never timed out
+	pingPeriod = pongWait * 9 / 10
+	// maxMessageSize caps what a peer may send, clients only answer pings
+	maxMessageSize = 512
+	// sendQueueSize is the number of messages buffered for a slow peer before it is dropped
+	sendQueueSize = 64
+)
+
+// Hub keeps the open connections of every user, a user may be connected from several
+// devices at once. Each connection has its own send queue drained by its own writer
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[string]map[*client]bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		clients: make(map[string]map[*client]bool),
+	}
+}
+
+type client struct {
+	hub    *Hub
+	userID string
+	conn   *websocket.Conn
+	send   chan []byte
+}
+
+func (h *Hub) register(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.clients[c.userID] == nil {
+		h.clients[c.userID] = make(map[*client]bool)
+	}
+	h.clients[c.userID][c] = true
+}
+
+// unregister removes a client and closes its queue, which stops its writer
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.clients[c.userID][c] {
+		return
+	}
+
+	delete(h.clients[c.userID], c)
+	if len(h.clients[c.userID]) == 0 {
+		delete(h.clients, c.userID)
+	}
+	close(c.send)
+}
+
+// Connections returns the number of open connections of a user
+func (h *Hub) Connections(userID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.clients[userID])
+}
+
+// SendToUser queues message on every connection of a user, connections whose queue is full are dropped
+func (h *Hub) SendToUser(userID string, message []byte) {
+	var slow []*client
+
+	h.mu.RLock()
+	for c := range h.clients[userID] {
+		select {
+		case c.send <- message:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range slow {
+		log.Printf("WebSocket send queue of user %s is full, dropping connection", userID)
+		h.unregister(c)
+	}
+}
+
+// Deliver pushes an event to the connections of its subject, the user it is addressed to
+func (h *Hub) Deliver(ctx context.Context, event entity.Event) error {
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	h.SendToUser(event.SubjectID, message)
+
+	return nil
+}
+
+// readPump discards what the peer sends and keeps the read deadline moving on pongs,
+// it unregisters the client once the connection fails or times out
+func (c *client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println("WebSocket closed:", err)
+			}
+			return
+		}
+	}
+}
+
+// writePump is the only writer of a connection, it sends queued messages and pings
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package websocket_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ws "github.com/dostonshernazarov/mini-twitter/api/websocket"
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
+	tokens "github.com/dostonshernazarov/mini-twitter/internal/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const signingKey = "test_signing_key"
+
+func newServer(t *testing.T) (*httptest.Server, *ws.Hub) {
+	gin.SetMode(gin.TestMode)
+
+	hub := ws.NewHub()
+	router := gin.New()
+	router.GET("/ws", ws.NewHandler(hub, &config.Config{SigningKey: signingKey}))
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return server, hub
+}
+
+func accessToken(t *testing.T, userID string) string {
+	return signedToken(t, userID, entity.RoleUser, signingKey)
+}
+
+func signedToken(t *testing.T, userID, role, key string) string {
+	jwtHandler := tokens.JwtHandler{
+		Sub:       userID,
+		Role:      role,
+		SigninKey: key,
+		Log:       zap.NewNop(),
+	}
+
+	access, _, err := jwtHandler.GenerateJwt()
+	assert.NoError(t, err)
+
+	return access
+}
+
+func dial(t *testing.T, server *httptest.Server, userID string) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?token=" + accessToken(t, userID)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func waitConnections(t *testing.T, hub *ws.Hub, userID string, want int) {
+	assert.Eventually(t, func() bool {
+		return hub.Connections(userID) == want
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHandlerRejectsMissingToken(t *testing.T) {
+	server, _ := newServer(t)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHandlerRejectsInvalidToken(t *testing.T) {
+	server, _ := newServer(t)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	header := http.Header{"Authorization": []string{"Bearer not-a-token"}}
+	_, resp, err := websocket.DefaultDialer.Dial(url, header)
+
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHandlerRejectsTokenOfOtherKey(t *testing.T) {
+	server, _ := newServer(t)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?token=" + signedToken(t, uuid.NewString(), entity.RoleUser, "other_key")
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHandlerRejectsUnauthorizedRole(t *testing.T) {
+	server, _ := newServer(t)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?token=" + signedToken(t, uuid.NewString(), entity.RoleUnauthorized, signingKey)
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHandlerAcceptsTokenInHeader(t *testing.T) {
+	server, hub := newServer(t)
+
+	userID := uuid.NewString()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	header := http.Header{"Authorization": []string{"Bearer " + accessToken(t, userID)}}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	waitConnections(t, hub, userID, 1)
+}
+
+func TestHubRoutesToEveryConnectionOfRecipient(t *testing.T) {
+	server, hub := newServer(t)
+
+	recipient := uuid.NewString()
+	other := uuid.NewString()
+
+	phone := dial(t, server, recipient)
+	laptop := dial(t, server, recipient)
+	stranger := dial(t, server, other)
+
+	waitConnections(t, hub, recipient, 2)
+	waitConnections(t, hub, other, 1)
+
+	hub.SendToUser(recipient, []byte(`{"type":"notification.created"}`))
+
+	for _, conn := range []*websocket.Conn{phone, laptop} {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, message, err := conn.ReadMessage()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"type":"notification.created"}`, string(message))
+	}
+
+	stranger.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err := stranger.ReadMessage()
+	assert.Error(t, err)
+}
+
+func TestHubForgetsClosedConnections(t *testing.T) {
+	server, hub := newServer(t)
+
+	userID := uuid.NewString()
+	conn := dial(t, server, userID)
+	waitConnections(t, hub, userID, 1)
+
+	conn.Close()
+
+	waitConnections(t, hub, userID, 0)
+}
@@ -3,30 +3,74 @@ package websocket
 import (
 	"log"
 	"net/http"
+	"strings"
 
-	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/kafka"
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
+	tokens "github.com/dostonshernazarov/mini-twitter/internal/pkg/token"
+	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/spf13/cast"
 )
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
-func WsHandler(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Println("WebSocket upgrade failed:", err)
-		return
-	}
-
-	kafka.AddClient(conn)
-	defer kafka.RemoveClient(conn)
+// NewHandler upgrades authenticated requests and registers the connection in hub under
+// its user, browsers that cannot set headers pass the access token as ?token=
+func NewHandler(hub *Hub, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := authenticate(c.Request, cfg)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, entity.Error{
+				Message: entity.NoAccess,
+			})
+			return
+		}
 
-	for {
-		_, _, err := conn.ReadMessage()
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
-			log.Println("WebSocket closed:", err)
+			log.Println("WebSocket upgrade failed:", err)
 			return
 		}
+
+		client := &client{
+			hub:    hub,
+			userID: userID,
+			conn:   conn,
+			send:   make(chan []byte, sendQueueSize),
+		}
+		hub.register(client)
+
+		go client.writePump()
+		client.readPump()
 	}
 }
+
+// authenticate returns the user of the access token sent with the upgrade request
+func authenticate(r *http.Request, cfg *config.Config) (string, bool) {
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+
+	token = strings.TrimPrefix(token, "Bearer ")
+	if token == "" {
+		return "", false
+	}
+
+	claims, err := tokens.ExtractClaim(token, []byte(cfg.SigningKey))
+	if err != nil {
+		log.Println(err.Error())
+		return "", false
+	}
+
+	role := cast.ToString(claims["role"])
+	userID := cast.ToString(claims["sub"])
+	if userID == "" || (role != entity.RoleUser && role != entity.RoleAdmin) {
+		return "", false
+	}
+
+	return userID, true
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	awss3 "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/awsS3"
+	configpkg "github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
+)
+
+// media-acl makes the objects of uploaded media in the bucket private, it is run once on
+// buckets holding media uploaded while they were stored public-read
+func main() {
+	// config
+	config := configpkg.Load()
+
+	if err := awss3.InitS3(config); err != nil {
+		log.Fatal(err)
+	}
+
+	reset, err := awss3.ResetMediaACL(context.Background(), config)
+	if err != nil {
+		log.Fatalf("reset the ACL of %d media objects before failing: %v", reset, err)
+	}
+
+	log.Printf("reset the ACL of %d media objects to private", reset)
+}
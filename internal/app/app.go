@@ -5,33 +5,51 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/dostonshernazarov/mini-twitter/api"
+	"github.com/dostonshernazarov/mini-twitter/api/websocket"
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
 	awss3 "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/awsS3"
 	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/kafka"
 	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
 	cache "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/redis"
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/eventbus"
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/logger"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/media"
 	postgresdb "github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/usecase"
+	"github.com/dostonshernazarov/mini-twitter/internal/worker"
 	"go.uber.org/zap"
 )
 
 type App struct {
-	Config   *config.Config
-	Logger   *zap.Logger
-	DB       *postgresdb.PostgresDB
-	server   *http.Server
-	Enforcer *casbin.Enforcer
-	User     usecase.User
-	Tweet    usecase.Twit
-	Follow   usecase.Follow
-	Search   usecase.Search
-	Like     usecase.Like
+	Config       *config.Config
+	Logger       *zap.Logger
+	DB           *postgresdb.PostgresDB
+	server       *http.Server
+	Enforcer     *casbin.Enforcer
+	User         usecase.User
+	Tweet        usecase.Twit
+	Follow       usecase.Follow
+	Block        usecase.Block
+	Bookmark     usecase.Bookmark
+	List         usecase.List
+	Poll         usecase.Poll
+	Draft        usecase.Draft
+	Media        usecase.Media
+	Search       usecase.Search
+	Like         usecase.Like
+	Trend        usecase.Trend
+	Notification usecase.Notification
+	Message      usecase.Message
+	Hub          *websocket.Hub
+	EventBus     eventbus.Bus
+	stopWorkers  context.CancelFunc
 }
 
 func NewApp(cfg config.Config) (*App, error) {
@@ -47,15 +65,20 @@ func NewApp(cfg config.Config) (*App, error) {
 		return nil, err
 	}
 
-	// kafka init
-	err = kafka.InitKafkaProducer([]string{cfg.Kafka.Brokers})
-	if err != nil {
-		log.Fatalf("failed to init : %v", err)
+	// event bus init
+	var bus eventbus.Bus
+	switch cfg.EventBus.Driver {
+	case eventbus.DriverKafka:
+		bus, err = kafka.NewBus([]string{cfg.Kafka.Brokers}, cfg.Kafka.Topic)
+		if err != nil {
+			return nil, err
+		}
+	case eventbus.DriverMemory:
+		bus = eventbus.NewMemoryBus()
+	default:
+		return nil, fmt.Errorf("unknown event bus driver %q", cfg.EventBus.Driver)
 	}
 
-	// Start Kafka Consumer in a goroutine
-	go kafka.StartConsumer([]string{cfg.Kafka.Brokers}, cfg.Kafka.Topic)
-
 	redisClient, err := cache.NewRedisStorage(&cfg)
 	if err != nil {
 		log.Fatalf("failed to create redis storage: %v", err)
@@ -75,36 +98,157 @@ func NewApp(cfg config.Config) (*App, error) {
 		return nil, err
 	}
 
-	// aws s3 init
-	err = awss3.InitS3(&cfg)
-	if err != nil {
-		log.Fatalf("error while initializing aws s3: %v", err)
+	// media store init
+	var store media.Store
+	switch cfg.Media.Driver {
+	case media.DriverS3:
+		err = awss3.InitS3(&cfg)
+		if err != nil {
+			log.Fatalf("error while initializing aws s3: %v", err)
+		}
+		store = awss3.NewStore(&cfg)
+	case media.DriverLocal:
+		store = media.NewLocalStore(cfg.Media.LocalDir)
+	default:
+		return nil, fmt.Errorf("unknown media driver %q", cfg.Media.Driver)
 	}
 
 	// Storage init
 	userRepo := postgres.NewUserRepo(db)
 	tweetRepo := postgres.NewTweetRepo(db)
 	followRepo := postgres.NewFollowRepo(db)
+	blockRepo := postgres.NewBlockRepo(db)
+	bookmarkRepo := postgres.NewBookmarkRepo(db)
+	listRepo := postgres.NewListRepo(db)
+	pollRepo := postgres.NewPollRepo(db)
+	draftRepo := postgres.NewDraftRepo(db)
+	mediaRepo := postgres.NewMediaRepo(db)
 	likeRepo := postgres.NewLikeRepo(db)
 	searchRepo := postgres.NewSearchRepo(db)
+	notificationRepo := postgres.NewNotificationRepo(db)
+	messageRepo := postgres.NewMessageRepo(db)
+
+	timeline, err := usecase.NewTimelineCache(&cfg, redisClient, tweetRepo, followRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	editWindow, err := time.ParseDuration(cfg.Tweet.EditWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadURLTTL, err := time.ParseDuration(cfg.Media.UploadURLTTL)
+	if err != nil {
+		return nil, err
+	}
 
 	//Usecase init
-	usecase.NewUserService(contextTimeout, userRepo)
-	usecase.NewTweetService(contextTimeout, tweetRepo)
-	usecase.NewFollowService(contextTimeout, followRepo)
-	usecase.NewLikeService(contextTimeout, likeRepo)
-	usecase.NewSearchService(contextTimeout, searchRepo)
+	userUseCase := usecase.NewUserService(contextTimeout, userRepo)
+	trendUseCase := usecase.NewTrendService(contextTimeout, redisClient)
+	tweetUseCase := usecase.NewTweetService(contextTimeout, tweetRepo, timeline, trendUseCase, editWindow, cfg.Tweet.MaxEdits)
+	followUseCase := usecase.NewFollowService(contextTimeout, followRepo, timeline)
+	blockUseCase := usecase.NewBlockService(contextTimeout, blockRepo, timeline)
+	bookmarkUseCase := usecase.NewBookmarkService(contextTimeout, bookmarkRepo)
+	listUseCase := usecase.NewListService(contextTimeout, listRepo)
+	pollUseCase := usecase.NewPollService(contextTimeout, pollRepo)
+	draftUseCase := usecase.NewDraftService(contextTimeout, draftRepo, timeline, trendUseCase)
+	mediaUseCase := usecase.NewMediaService(contextTimeout, mediaRepo, store, map[string]int64{
+		entity.MediaKindImage: cfg.Media.MaxImageSize,
+		entity.MediaKindGIF:   cfg.Media.MaxGIFSize,
+		entity.MediaKindVideo: cfg.Media.MaxVideoSize,
+	}, uploadURLTTL, cfg.Media.URL)
+	likeUseCase := usecase.NewLikeService(contextTimeout, likeRepo)
+	searchUseCase := usecase.NewSearchService(contextTimeout, searchRepo)
+	notificationUseCase := usecase.NewNotificationService(contextTimeout, notificationRepo)
+	messageUseCase := usecase.NewMessageService(contextTimeout, messageRepo)
+
+	outboxInterval, err := time.ParseDuration(cfg.Outbox.PollInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	outboxRetention, err := time.ParseDuration(cfg.Outbox.Retention)
+	if err != nil {
+		return nil, err
+	}
+
+	outboxRepo := postgres.NewOutboxRepo(db)
+	outboxRelay := worker.NewOutboxRelay(outboxRepo, bus, outboxInterval, cfg.Outbox.BatchSize, outboxRetention)
+
+	pollCloseInterval, err := time.ParseDuration(cfg.Poll.CloseInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	pollCloser := worker.NewPollCloser(pollRepo, pollCloseInterval, cfg.Poll.CloseBatchSize)
+
+	schedulerInterval, err := time.ParseDuration(cfg.Scheduler.Interval)
+	if err != nil {
+		return nil, err
+	}
+
+	tweetScheduler := worker.NewTweetScheduler(draftUseCase, schedulerInterval, cfg.Scheduler.BatchSize)
+
+	mediaSweepInterval, err := time.ParseDuration(cfg.Media.SweepInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaSweeper := worker.NewMediaSweeper(mediaUseCase, mediaSweepInterval, cfg.Media.SweepBatchSize)
+
+	// every instance has to push notifications to the websockets it holds, so the
+	// realtime subscriber joins a group of its own per host. A new host only needs
+	// the events from now on, replaying the topic would flood the first sockets.
+	// Its events are not deduplicated, the group changes with the host and keys
+	// stored for it would pile up without ever being looked at again
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	hub := websocket.NewHub()
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+
+	go outboxRelay.Run(workersCtx)
+	go pollCloser.Run(workersCtx)
+	go tweetScheduler.Run(workersCtx)
+	go mediaSweeper.Run(workersCtx)
+
+	go func() {
+		group := cfg.Kafka.GroupID + "-realtime-" + hostname
+		err := bus.Subscribe(workersCtx, group, eventbus.StartNewest, eventbus.Handlers{
+			entity.EventTypeNotificationCreated: hub.Deliver,
+			entity.EventTypeMessageCreated:      hub.Deliver,
+			entity.EventTypeMessageRead:         hub.Deliver,
+		})
+		if err != nil {
+			log.Printf("event bus subscriber stopped: %v", err)
+		}
+	}()
 
 	return &App{
-		Config:   &cfg,
-		Logger:   logger,
-		DB:       db,
-		Enforcer: enforcer,
-		User:     userRepo,
-		Tweet:    tweetRepo,
-		Follow:   followRepo,
-		Search:   searchRepo,
-		Like:     likeRepo,
+		Config:       &cfg,
+		Logger:       logger,
+		DB:           db,
+		Enforcer:     enforcer,
+		User:         userUseCase,
+		Tweet:        tweetUseCase,
+		Follow:       followUseCase,
+		Block:        blockUseCase,
+		Bookmark:     bookmarkUseCase,
+		List:         listUseCase,
+		Poll:         pollUseCase,
+		Draft:        draftUseCase,
+		Media:        mediaUseCase,
+		Search:       searchUseCase,
+		Like:         likeUseCase,
+		Trend:        trendUseCase,
+		Notification: notificationUseCase,
+		Message:      messageUseCase,
+		Hub:          hub,
+		EventBus:     bus,
+		stopWorkers:  stopWorkers,
 	}, nil
 }
 
@@ -123,8 +267,19 @@ func (a *App) Run() error {
 		User:           a.User,
 		Tweet:          a.Tweet,
 		Follow:         a.Follow,
+		Block:          a.Block,
+		Bookmark:       a.Bookmark,
+		List:           a.List,
+		Poll:           a.Poll,
+		Draft:          a.Draft,
+		Media:          a.Media,
 		Search:         a.Search,
 		Like:           a.Like,
+		Trend:          a.Trend,
+		Notification:   a.Notification,
+		Message:        a.Message,
+		Hub:            a.Hub,
+		EventBus:       a.EventBus,
 	})
 
 	// server init
@@ -138,6 +293,14 @@ func (a *App) Run() error {
 
 func (a *App) Stop() {
 
+	// stop outbox relay, poll closer, tweet scheduler and event bus subscribers
+	a.stopWorkers()
+
+	// close event bus
+	if err := a.EventBus.Close(); err != nil {
+		a.Logger.Error("close event bus ", zap.Error(err))
+	}
+
 	// close database
 	a.DB.Close()
 
@@ -0,0 +1,13 @@
+package entity
+
+// BlockAction toggles the block of BlockedID by UserID
+type BlockAction struct {
+	UserID    string `json:"-"`
+	BlockedID string `json:"user_id"`
+}
+
+// MuteAction toggles the mute of MutedID by UserID
+type MuteAction struct {
+	UserID  string `json:"-"`
+	MutedID string `json:"user_id"`
+}
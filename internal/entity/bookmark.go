@@ -0,0 +1,36 @@
+package entity
+
+import "time"
+
+// BookmarkAction saves TweetID for UserID, FolderID files it in one of their folders
+type BookmarkAction struct {
+	UserID   string  `json:"-"`
+	TweetID  string  `json:"-"`
+	FolderID *string `json:"folder_id"`
+}
+
+type BookmarkFolder struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Count     int       `json:"count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateBookmarkFolderRequest struct {
+	ID     string `json:"-"`
+	UserID string `json:"-"`
+	Name   string `json:"name"`
+}
+
+type BookmarkFoldersResponse struct {
+	Folders []BookmarkFolder `json:"folders"`
+}
+
+// BookmarkFilter pages through the bookmarks of a user newest first, the cursor
+// points at the time a tweet was saved, not the time it was posted
+type BookmarkFilter struct {
+	UserID   string
+	FolderID *string
+	Cursor   *Cursor
+	Limit    int
+}
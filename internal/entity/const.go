@@ -8,6 +8,87 @@ const (
 	RoleUnknown      = "unknown"
 )
 
+// tweet kinds
+const (
+	TweetKindTweet   = "tweet"
+	TweetKindReply   = "reply"
+	TweetKindRetweet = "retweet"
+	TweetKindQuote   = "quote"
+)
+
+// notification types
+const (
+	NotificationTypeFollow         = "follow"
+	NotificationTypeLike           = "like"
+	NotificationTypeReply          = "reply"
+	NotificationTypeMention        = "mention"
+	NotificationTypeRetweet        = "retweet"
+	NotificationTypeFollowRequest  = "follow_request"
+	NotificationTypeFollowAccepted = "follow_accepted"
+	NotificationTypePollClosed     = "poll_closed"
+)
+
+// media kinds
+const (
+	MediaKindImage = "image"
+	MediaKindGIF   = "gif"
+	MediaKindVideo = "video"
+)
+
+// media statuses, media uploaded straight to the bucket stay pending until the upload is completed
+const (
+	MediaStatusPending = "pending"
+	MediaStatusReady   = "ready"
+)
+
+// media purposes, they tell who may see a media besides its uploader: everyone for profile
+// pictures, the members of the conversation for group avatars and the viewers of the tweet for tweet media
+const (
+	MediaPurposeTweet        = "tweet"
+	MediaPurposeProfile      = "profile"
+	MediaPurposeConversation = "conversation"
+)
+
+// conversation kinds
+const (
+	ConversationKindDirect = "direct"
+	ConversationKindGroup  = "group"
+)
+
+// roles of group conversation members, each one can do what the roles below it can
+const (
+	MemberRoleOwner  = "owner"
+	MemberRoleAdmin  = "admin"
+	MemberRoleMember = "member"
+)
+
+// event types
+const (
+	EventTypeTweetCreated        = "tweet.created"
+	EventTypeTweetUpdated        = "tweet.updated"
+	EventTypeTweetDeleted        = "tweet.deleted"
+	EventTypeLikeCreated         = "like.created"
+	EventTypeLikeDeleted         = "like.deleted"
+	EventTypeFollowCreated       = "follow.created"
+	EventTypeFollowDeleted       = "follow.deleted"
+	EventTypeNotificationCreated = "notification.created"
+	EventTypeMessageCreated      = "message.created"
+	EventTypeMessageRead         = "message.read"
+)
+
+// outbox aggregate types, events of one aggregate are published in order
+const (
+	AggregateTweet        = "tweet"
+	AggregateUser         = "user"
+	AggregateConversation = "conversation"
+)
+
+// trend windows
+const (
+	TrendWindowHour = "1h"
+	TrendWindowDay  = "24h"
+)
+
 // error messages
 const (
 	NoAccess           string = "You have no access"
@@ -22,4 +103,18 @@ const (
 	TokenExpired       string = "Token Expired"
 	WrongLoginOrPasswd string = "Wrong login or password"
 	UploadingError     string = "Error happened while upload files"
+	AlreadyRetweeted   string = "Tweet already retweeted"
+	GroupFull          string = "Group is full"
+	Blocked            string = "You can not interact with this user"
+	FolderExists       string = "Folder already exists"
+	AlreadyVoted       string = "You already voted in this poll"
+	PollClosed         string = "Poll is closed"
+	EditWindowClosed   string = "Tweet can no longer be edited"
+	EditLimitReached   string = "Tweet was edited too many times"
+	EditConflict       string = "Tweet was edited meanwhile"
+	UnsupportedMedia   string = "File type is not supported"
+	MediaTooLarge      string = "File is too large"
+	MediaNotUploaded   string = "File was not uploaded yet"
+	MediaMismatch      string = "File does not match the announced upload"
+	DirectUploadOff    string = "Direct uploads are not supported"
 )
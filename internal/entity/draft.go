@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// DraftRequest saves a tweet for later, a draft with PublishAt set is scheduled and gets
+// published at that time, without it the draft stays hidden until it is scheduled or published
+type DraftRequest struct {
+	ID            string     `json:"-"`
+	UserID        string     `json:"-"`
+	ParentTweetID *string    `json:"parent_tweet_id"`
+	QuoteTweetID  *string    `json:"quote_tweet_id"`
+	Content       *string    `json:"content"`
+	MediaIDs      []string   `json:"media_ids"`
+	PublishAt     *time.Time `json:"publish_at"`
+}
+
+// Draft is an unpublished tweet, LastError tells why a scheduled draft could not be
+// published and went back to the drafts
+type Draft struct {
+	ID            string     `json:"id"`
+	UserID        string     `json:"user_id"`
+	ParentTweetID *string    `json:"parent_tweet_id"`
+	QuoteTweetID  *string    `json:"quote_tweet_id"`
+	Content       *string    `json:"content"`
+	MediaIDs      []string   `json:"media_ids"`
+	PublishAt     *time.Time `json:"publish_at"`
+	LastError     *string    `json:"last_error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type DraftsResponse struct {
+	Drafts []Draft `json:"drafts"`
+}
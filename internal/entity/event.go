@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventVersion is bumped when the envelope or a payload changes incompatibly
+const EventVersion = 1
+
+// Event is the envelope of every message published to kafka, ActorID did something
+// to SubjectID and Payload holds the details for the event type
+type Event struct {
+	ID        string          `json:"id"`
+	Version   int             `json:"version"`
+	Type      string          `json:"type"`
+	ActorID   string          `json:"actor_id"`
+	SubjectID string          `json:"subject_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// OutboxMessage is an event waiting in the outbox, AggregateID is used as the kafka key
+type OutboxMessage struct {
+	Seq         int64
+	AggregateID string
+	Attempts    int
+	Event       Event
+}
@@ -4,3 +4,15 @@ type FollowAction struct {
 	UserID      string `json:"-"`
 	FollowingID string `json:"following_id"`
 }
+
+// FollowResponse tells whether the user now follows, or asked to follow a protected account
+type FollowResponse struct {
+	Status    bool `json:"status"`
+	Requested bool `json:"requested"`
+}
+
+// FollowRequestAction answers the follow request RequesterID sent to UserID
+type FollowRequestAction struct {
+	UserID      string `json:"-"`
+	RequesterID string `json:"-"`
+}
@@ -0,0 +1,18 @@
+package entity
+
+type HashtagFilter struct {
+	Tag      string
+	ViewerID string
+	Cursor   *Cursor
+	Limit    int
+}
+
+type Trend struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+type TrendsResponse struct {
+	Window string  `json:"window"`
+	Trends []Trend `json:"trends"`
+}
@@ -0,0 +1,51 @@
+package entity
+
+import "time"
+
+// List is a curated group of users whose tweets make up the timeline of the list,
+// private lists are only seen by their owner
+type List struct {
+	ID              string    `json:"id"`
+	OwnerID         string    `json:"owner_id"`
+	Name            string    `json:"name"`
+	Description     *string   `json:"description"`
+	IsPrivate       bool      `json:"is_private"`
+	MemberCount     int       `json:"member_count"`
+	SubscriberCount int       `json:"subscriber_count"`
+	SubscribedByMe  bool      `json:"subscribed_by_me"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type CreateListRequest struct {
+	ID          string  `json:"-"`
+	OwnerID     string  `json:"-"`
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	IsPrivate   bool    `json:"is_private"`
+}
+
+// UpdateListRequest changes the fields that are set, the others are kept
+type UpdateListRequest struct {
+	ID          string  `json:"-"`
+	OwnerID     string  `json:"-"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	IsPrivate   *bool   `json:"is_private"`
+}
+
+type ListMemberRequest struct {
+	ListID  string `json:"-"`
+	OwnerID string `json:"-"`
+	UserID  string `json:"user_id"`
+}
+
+type ListsResponse struct {
+	Lists []List `json:"lists"`
+}
+
+type ListTimelineFilter struct {
+	ListID   string
+	ViewerID string
+	Cursor   *Cursor
+	Limit    int
+}
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"io"
+	"time"
+)
+
+// MediaUpload is a file on its way to storage, Kinds lists the media kinds it may turn out to be.
+// ConversationID is set on group avatars only
+type MediaUpload struct {
+	UserID         string
+	Purpose        string
+	ConversationID *string
+	Name           string
+	Size           int64
+	Kinds          []string
+	Body           io.ReadSeeker
+}
+
+// Media is an uploaded file owned by its uploader, it can be attached to one tweet once it is ready
+type Media struct {
+	ID             string    `json:"id"`
+	UserID         string    `json:"user_id"`
+	Kind           string    `json:"kind"`
+	ContentType    string    `json:"content_type"`
+	Size           int64     `json:"size"`
+	Status         string    `json:"status"`
+	Purpose        string    `json:"-"`
+	ConversationID *string   `json:"-"`
+	Key            string    `json:"-"`
+	URLs           MediaURLs `json:"urls"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// MediaFile is a rendition of a media on its way to a viewer, Body can be read from any offset
+type MediaFile struct {
+	ContentType string
+	Size        int64
+	ETag        string
+	ModTime     time.Time
+	Body        io.ReadSeekCloser
+}
+
+// DirectUploadRequest announces a file the client is going to upload straight to the bucket
+type DirectUploadRequest struct {
+	UserID      string `json:"-"`
+	ContentType string `json:"content_type" binding:"required"`
+	Size        int64  `json:"size" binding:"required"`
+}
+
+// DirectUploadResponse tells where to PUT the file of a direct upload, with the Headers given,
+// before ExpiresAt. The upload is completed with the id of the media afterwards
+type DirectUploadResponse struct {
+	MediaID   string            `json:"media_id"`
+	UploadURL string            `json:"upload_url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// MediaURLs are the addresses of the renditions of a media. Images come in three sizes, stripped
+// of their metadata, and a jpeg or png also as WebP, other media only have the original
+type MediaURLs struct {
+	Original      string `json:"original"`
+	Medium        string `json:"medium,omitempty"`
+	Thumbnail     string `json:"thumbnail,omitempty"`
+	OriginalWebP  string `json:"original_webp,omitempty"`
+	MediumWebP    string `json:"medium_webp,omitempty"`
+	ThumbnailWebP string `json:"thumbnail_webp,omitempty"`
+}
@@ -0,0 +1,117 @@
+package entity
+
+import "time"
+
+// Conversation is a private exchange of messages, Members includes the current user.
+// Title and AvatarURL are only set on groups, Muted is the setting of the current user
+type Conversation struct {
+	ID            string               `json:"id"`
+	Kind          string               `json:"kind"`
+	Title         *string              `json:"title"`
+	AvatarURL     *string              `json:"avatar_url"`
+	Muted         bool                 `json:"muted"`
+	MutedUntil    *time.Time           `json:"muted_until"`
+	Members       []ConversationMember `json:"members"`
+	LastMessage   *Message             `json:"last_message"`
+	UnreadCount   int                  `json:"unread_count"`
+	LastMessageAt time.Time            `json:"last_message_at"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
+
+// ConversationMember holds the read receipt of a member, LastReadAt is nil until they read it
+type ConversationMember struct {
+	UserID     string     `json:"user_id"`
+	Role       string     `json:"role"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+type Message struct {
+	ID             string    `json:"id"`
+	ConversationID string    `json:"conversation_id"`
+	SenderID       string    `json:"sender_id"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// StartConversationRequest opens the direct conversation with RecipientID, or returns the existing one
+type StartConversationRequest struct {
+	ID          string `json:"-"`
+	UserID      string `json:"-"`
+	RecipientID string `json:"user_id"`
+}
+
+type CreateGroupRequest struct {
+	ID        string   `json:"-"`
+	OwnerID   string   `json:"-"`
+	Title     string   `json:"title"`
+	MemberIDs []string `json:"member_ids"`
+}
+
+// UpdateGroupRequest changes the fields that are set
+type UpdateGroupRequest struct {
+	ID        string  `json:"-"`
+	UserID    string  `json:"-"`
+	Title     *string `json:"title"`
+	AvatarURL *string `json:"-"`
+}
+
+// GroupMembersRequest is UserID inviting MemberIDs to a group
+type GroupMembersRequest struct {
+	ConversationID string   `json:"-"`
+	UserID         string   `json:"-"`
+	MemberIDs      []string `json:"user_ids"`
+}
+
+// GroupMemberRequest is UserID acting on the membership of MemberID, Role is only read when it changes
+type GroupMemberRequest struct {
+	ConversationID string `json:"-"`
+	UserID         string `json:"-"`
+	MemberID       string `json:"-"`
+	Role           string `json:"role"`
+}
+
+// MuteConversationRequest silences the live delivery of a conversation, for Duration or until unmuted
+type MuteConversationRequest struct {
+	ConversationID string     `json:"-"`
+	UserID         string     `json:"-"`
+	Muted          bool       `json:"muted"`
+	Duration       string     `json:"duration"`
+	MutedUntil     *time.Time `json:"-"`
+}
+
+type SendMessageRequest struct {
+	ID             string `json:"-"`
+	ConversationID string `json:"-"`
+	SenderID       string `json:"-"`
+	Content        string `json:"content"`
+}
+
+type ConversationFilter struct {
+	UserID string
+	Cursor *Cursor
+	Limit  int
+}
+
+type ConversationsResponse struct {
+	Conversations []Conversation `json:"conversations"`
+	NextCursor    string         `json:"next_cursor"`
+}
+
+type MessageFilter struct {
+	ConversationID string
+	UserID         string
+	Cursor         *Cursor
+	Limit          int
+}
+
+type MessagesResponse struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor"`
+}
+
+// ReadReceipt tells the members of a conversation that UserID read every message sent until ReadAt
+type ReadReceipt struct {
+	ConversationID string    `json:"conversation_id"`
+	UserID         string    `json:"user_id"`
+	ReadAt         time.Time `json:"read_at"`
+}
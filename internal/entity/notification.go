@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// Notification tells UserID that ActorID did something, TweetID is set for tweet events
+type Notification struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	ActorID   string    `json:"actor_id"`
+	Type      string    `json:"type"`
+	TweetID   *string   `json:"tweet_id"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type NotificationFilter struct {
+	UserID string
+	Cursor *Cursor
+	Limit  int
+}
+
+type NotificationsResponse struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unread_count"`
+	NextCursor    string         `json:"next_cursor"`
+}
+
+// ReadNotificationsRequest marks the listed notifications read, an empty list marks all of them
+type ReadNotificationsRequest struct {
+	UserID string   `json:"-"`
+	IDs    []string `json:"ids"`
+}
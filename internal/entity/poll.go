@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// PollRequest attaches a poll to a new tweet, the poll closes DurationMinutes after the tweet is posted
+type PollRequest struct {
+	Options         []string `json:"options"`
+	DurationMinutes int      `json:"duration_minutes"`
+}
+
+// Poll is the poll of a tweet as seen by one user, vote counts stay hidden until
+// that user voted or the poll closed
+type Poll struct {
+	Options       []PollOption `json:"options"`
+	TotalVotes    *int         `json:"total_votes"`
+	VotedOptionID *string      `json:"voted_option_id"`
+	Closed        bool         `json:"closed"`
+	EndsAt        time.Time    `json:"ends_at"`
+}
+
+type PollOption struct {
+	ID        string `json:"id"`
+	Position  int    `json:"position"`
+	Label     string `json:"label"`
+	VoteCount *int   `json:"vote_count"`
+}
+
+type PollVote struct {
+	TweetID  string `json:"-"`
+	UserID   string `json:"-"`
+	OptionID string `json:"option_id"`
+}
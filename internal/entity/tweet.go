@@ -3,53 +3,163 @@ package entity
 import "time"
 
 type TweetRequest struct {
-	ParentTweetID *string  `json:"parent_tweet_id"`
-	Content       *string  `json:"content"`
-	URLs          []string `json:"files"`
+	ParentTweetID *string      `json:"parent_tweet_id"`
+	QuoteTweetID  *string      `json:"quote_tweet_id"`
+	Content       *string      `json:"content"`
+	MediaIDs      []string     `json:"media_ids"`
+	Poll          *PollRequest `json:"poll"`
 }
 
 type CreateTweetRequest struct {
-	ID            string    `json:"id"`
-	UserID        string    `json:"user_id"`
-	ParentTweetID *string   `json:"parent_tweet_id"`
-	Content       *string   `json:"content"`
-	URLs          []string  `json:"files"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID              string       `json:"id"`
+	UserID          string       `json:"user_id"`
+	Kind            string       `json:"kind"`
+	ParentTweetID   *string      `json:"parent_tweet_id"`
+	OriginalTweetID *string      `json:"original_tweet_id"`
+	Content         *string      `json:"content"`
+	MediaIDs        []string     `json:"media_ids"`
+	Poll            *PollRequest `json:"poll"`
+	Hashtags        []string     `json:"-"`
+	Mentions        []string     `json:"-"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
 }
 
 type CreateTweetResponse struct {
-	ID            string    `json:"id"`
-	UserID        string    `json:"user_id"`
-	ParentTweetID *string   `json:"parent_tweet_id"`
-	Content       *string   `json:"content"`
-	URLs          []string  `json:"urls"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID              string      `json:"id"`
+	UserID          string      `json:"user_id"`
+	Kind            string      `json:"kind"`
+	ParentTweetID   *string     `json:"parent_tweet_id"`
+	OriginalTweetID *string     `json:"original_tweet_id"`
+	Content         *string     `json:"content"`
+	URLs            []MediaURLs `json:"urls"`
+	Poll            *Poll       `json:"poll,omitempty"`
+	Hashtags        []string    `json:"-"`
+	MentionedIDs    []string    `json:"-"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+type RetweetAction struct {
+	ID      string `json:"-"`
+	UserID  string `json:"-"`
+	TweetID string `json:"tweet_id"`
+}
+
+// DeletedTweet is the payload of the event of a removed tweet or retweet
+type DeletedTweet struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Kind   string `json:"kind"`
 }
 
 type UpdateTweetRequest struct {
-	ID      string `json:"id"`
-	Content string `json:"content"`
+	ID        string   `json:"id"`
+	UserID    string   `json:"-"`
+	Content   string   `json:"content"`
+	EditCount int      `json:"-"`
+	Hashtags  []string `json:"-"`
+	Mentions  []string `json:"-"`
 }
 
 type UpdateTweetResponse struct {
-	ID            string   `json:"id"`
-	UserID        string   `json:"user_id"`
-	ParentTweetID *string  `json:"parent_tweet_id"`
-	Content       *string  `json:"content"`
-	URLs          []string `json:"urls"`
+	ID            string      `json:"id"`
+	UserID        string      `json:"user_id"`
+	ParentTweetID *string     `json:"parent_tweet_id"`
+	Content       *string     `json:"content"`
+	URLs          []MediaURLs `json:"urls"`
+	EditCount     int         `json:"edit_count"`
+	EditedAt      *time.Time  `json:"edited_at"`
+	MentionedIDs  []string    `json:"-"`
+}
+
+// TweetRevision is a version of the content of a tweet, revision 0 is the content it was posted with
+type TweetRevision struct {
+	Revision  int       `json:"revision"`
+	Content   *string   `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TweetHistoryResponse lists every version of a tweet, the current one first
+type TweetHistoryResponse struct {
+	TweetID   string          `json:"tweet_id"`
+	Revisions []TweetRevision `json:"revisions"`
 }
 
 type GetTweetResponse struct {
-	ID            string   `json:"id"`
-	UserID        string   `json:"user_id"`
-	ParentTweetID *string  `json:"parent_tweet_id"`
-	Content       *string  `json:"content"`
-	URLs          []string `json:"urls"`
+	ID              string            `json:"id"`
+	UserID          string            `json:"user_id"`
+	Kind            string            `json:"kind"`
+	ParentTweetID   *string           `json:"parent_tweet_id"`
+	OriginalTweetID *string           `json:"original_tweet_id"`
+	Content         *string           `json:"content"`
+	URLs            []MediaURLs       `json:"urls"`
+	LikeCount       int               `json:"like_count"`
+	ReplyCount      int               `json:"reply_count"`
+	RetweetCount    int               `json:"retweet_count"`
+	QuoteCount      int               `json:"quote_count"`
+	LikedByMe       bool              `json:"liked_by_me"`
+	BookmarkedByMe  bool              `json:"bookmarked_by_me"`
+	Poll            *Poll             `json:"poll,omitempty"`
+	EditCount       int               `json:"edit_count"`
+	EditedAt        *time.Time        `json:"edited_at"`
+	CreatedAt       time.Time         `json:"created_at"`
+	Original        *GetTweetResponse `json:"original,omitempty"`
 }
 
 type ListTweetsResponse struct {
 	Tweets []GetTweetResponse `json:"tweets"`
 	Count  int                `json:"count"`
 }
+
+// Cursor points at the last tweet of a page, pages are ordered by (created_at, id)
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+type TimelineFilter struct {
+	UserID string
+	Cursor *Cursor
+	Limit  int
+}
+
+type TimelineResponse struct {
+	Tweets     []GetTweetResponse `json:"tweets"`
+	NextCursor string             `json:"next_cursor"`
+}
+
+// TimelineEntry is the part of a tweet a materialized timeline keeps
+type TimelineEntry struct {
+	ID        string
+	UserID    string
+	CreatedAt time.Time
+}
+
+type TimelineEntriesFilter struct {
+	UserIDs []string
+	Cursor  *Cursor
+	Limit   int
+}
+
+type ThreadFilter struct {
+	TweetID  string
+	ViewerID string
+	Depth    int
+	Cursor   *Cursor
+	Limit    int
+}
+
+// ThreadNode is a tweet of a conversation with the first replies to it
+type ThreadNode struct {
+	GetTweetResponse
+	Replies []ThreadNode `json:"replies"`
+}
+
+// ThreadResponse holds the ancestors of a tweet from the root down and a page of
+// its replies, NextCursor pages through the direct replies
+type ThreadResponse struct {
+	Ancestors  []ThreadNode `json:"ancestors"`
+	Tweet      ThreadNode   `json:"tweet"`
+	NextCursor string       `json:"next_cursor"`
+}
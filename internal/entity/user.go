@@ -37,36 +37,38 @@ type CreateUserRequest struct {
 }
 
 type CreateUserResponse struct {
-	ID             string  `json:"id"`
-	Name           string  `json:"name"`
-	Username       string  `json:"username"`
-	Email          string  `json:"email"`
-	Bio            *string `json:"bio"`
-	Role           string  `json:"role"`
-	ProfilePicture *string `json:"profile_picture"`
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Username       string     `json:"username"`
+	Email          string     `json:"email"`
+	Bio            *string    `json:"bio"`
+	Role           string     `json:"role"`
+	ProfilePicture *MediaURLs `json:"profile_picture"`
 }
 
 type UpdateUserRequestSwag struct {
-	Name     string  `json:"name"`
-	Username string  `json:"username"`
-	Bio      *string `json:"bio"`
+	Name        string  `json:"name"`
+	Username    string  `json:"username"`
+	Bio         *string `json:"bio"`
+	IsProtected *bool   `json:"is_protected"`
 }
 
 type UpdateUserRequest struct {
-	ID       string  `json:"id"`
-	Name     string  `json:"name"`
-	Username string  `json:"username"`
-	Bio      *string `json:"bio"`
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Username    string  `json:"username"`
+	Bio         *string `json:"bio"`
+	IsProtected bool    `json:"is_protected"`
 }
 
 type UpdateUserResponse struct {
-	ID             string  `json:"id"`
-	Name           string  `json:"name"`
-	Username       string  `json:"username"`
-	Email          string  `json:"email"`
-	Bio            *string `json:"bio"`
-	Role           string  `json:"role"`
-	ProfilePicture *string `json:"profile_picture"`
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Username       string     `json:"username"`
+	Email          string     `json:"email"`
+	Bio            *string    `json:"bio"`
+	Role           string     `json:"role"`
+	ProfilePicture *MediaURLs `json:"profile_picture"`
 }
 
 type UpdateUserColumnsRequest struct {
@@ -75,16 +77,17 @@ type UpdateUserColumnsRequest struct {
 }
 
 type GetUserResponse struct {
-	ID             string  `json:"id"`
-	Name           string  `json:"name"`
-	Username       string  `json:"username"`
-	Email          string  `json:"email"`
-	Bio            *string `json:"bio"`
-	Role           string  `json:"role"`
-	Password       string  `json:"-"`
-	ProfilePicture *string `json:"profile_picture"`
-	FollowingCount int     `json:"following_count"`
-	FollowersCount int     `json:"followers_count"`
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Username       string     `json:"username"`
+	Email          string     `json:"email"`
+	Bio            *string    `json:"bio"`
+	Role           string     `json:"role"`
+	Password       string     `json:"-"`
+	ProfilePicture *MediaURLs `json:"profile_picture"`
+	IsProtected    bool       `json:"is_protected"`
+	FollowingCount int        `json:"following_count"`
+	FollowersCount int        `json:"followers_count"`
 }
 
 type Filter struct {
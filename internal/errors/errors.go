@@ -9,6 +9,18 @@ var (
 	ErrorNotFound       = NewErrNotFound("object")
 	ErrorInvalidOTPCode = errors.New("code is invalid")
 	ErrorOTPExpired     = errors.New("one time password has expired")
+	ErrorInvalidWindow  = errors.New("window is invalid")
+	ErrorNoPermission   = errors.New("no permission")
+	ErrorGroupFull      = errors.New("group is full")
+	ErrorBlocked        = errors.New("user is blocked")
+	ErrorPollClosed     = errors.New("poll is closed")
+	ErrorEditClosed     = errors.New("edit window is closed")
+	ErrorEditLimit      = errors.New("edit limit is reached")
+	ErrorMediaType      = errors.New("media type is not supported")
+	ErrorMediaSize      = errors.New("media is too large")
+	ErrorMediaMissing   = errors.New("media is not uploaded")
+	ErrorMediaMismatch  = errors.New("media does not match its upload")
+	ErrorDirectUpload   = errors.New("direct uploads are not supported")
 )
 
 // error not found
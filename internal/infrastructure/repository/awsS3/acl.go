@@ -0,0 +1,61 @@
+package awss3
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
+	"github.com/google/uuid"
+)
+
+// ResetMediaACL makes the objects of media private, the ones uploaded before the app served
+// media itself were public-read. Objects of media are kept under the id of their media, files
+// uploaded before media were recorded stay public since clients still reach them by their
+// bucket URLs. It returns how many objects were reset, running it again does no harm.
+// InitS3 has to run first
+func ResetMediaACL(ctx context.Context, conf *config.Config) (int, error) {
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: &conf.AWSS3.BucketName,
+	})
+
+	var reset int
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return reset, err
+		}
+
+		for _, object := range page.Contents {
+			if object.Key == nil || !isMediaKey(*object.Key) {
+				continue
+			}
+
+			_, err := s3Client.PutObjectAcl(ctx, &s3.PutObjectAclInput{
+				Bucket: &conf.AWSS3.BucketName,
+				Key:    object.Key,
+				ACL:    types.ObjectCannedACLPrivate,
+			})
+			if err != nil {
+				return reset, err
+			}
+
+			reset++
+		}
+	}
+
+	return reset, nil
+}
+
+// isMediaKey tells whether key is one of the <media id>/<file> keys media are stored under
+func isMediaKey(key string) bool {
+	id, file, ok := strings.Cut(key, "/")
+	if !ok || file == "" {
+		return false
+	}
+
+	_, err := uuid.Parse(id)
+
+	return err == nil && len(id) == 36
+}
@@ -2,44 +2,212 @@ package awss3
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"mime/multipart"
-	"path/filepath"
+	"io"
+	"net/url"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
-	"github.com/google/uuid"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/media"
 )
 
-func UploadFileToS3(conf *config.Config, file multipart.File, fileName string) (string, string, error) {
-	uniqueFileName := uuid.New().String() + filepath.Ext(fileName)
+type s3Store struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewStore returns a media store keeping private objects in the configured bucket, InitS3 has to run first
+func NewStore(conf *config.Config) media.DirectStore {
+	return &s3Store{
+		client: s3Client,
+		bucket: conf.AWSS3.BucketName,
+	}
+}
 
-	_, err := s3Client.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket: &conf.AWSS3.BucketName,
-		Key:    &uniqueFileName,
-		Body:   file,
-		ACL:    types.ObjectCannedACLPublicRead, // Set to PublicRead for public access
+func (s *s3Store) Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        &s.bucket,
+		Key:           &key,
+		Body:          body,
+		ContentType:   &contentType,
+		ContentLength: &size,
 	})
+
+	return err
+}
+
+func (s *s3Store) Open(ctx context.Context, key string) (io.ReadSeekCloser, media.Object, error) {
+	object, err := s.Stat(ctx, key)
 	if err != nil {
-		return "", "", err
+		return nil, media.Object{}, err
 	}
 
-	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", conf.AWSS3.BucketName, uniqueFileName), uniqueFileName, nil
+	return &objectReader{
+		ctx:   ctx,
+		store: s,
+		key:   key,
+		size:  object.Size,
+	}, object, nil
 }
 
-func GetPresignedURL(config *config.Config, fileName string) (string, error) {
-
-	presignClient := s3.NewPresignClient(s3Client)
+func (s *s3Store) PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (string, error) {
+	presignClient := s3.NewPresignClient(s.client)
 
-	req, err := presignClient.PresignGetObject(context.TODO(), &s3.GetObjectInput{
-		Bucket: &config.AWSS3.BucketName,
-		Key:    &fileName,
-	}, s3.WithPresignExpires(15*time.Minute))
+	req, err := presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        &s.bucket,
+		Key:           &key,
+		ContentType:   &contentType,
+		ContentLength: &size,
+	}, s3.WithPresignExpires(expires))
 	if err != nil {
 		return "", err
 	}
 
 	return req.URL, nil
 }
+
+func (s *s3Store) Stat(ctx context.Context, key string) (media.Object, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return media.Object{}, notFound(err)
+	}
+
+	object := media.Object{}
+	if head.ContentLength != nil {
+		object.Size = *head.ContentLength
+	}
+	if head.ETag != nil {
+		object.ETag = *head.ETag
+	}
+
+	return object, nil
+}
+
+func (s *s3Store) Read(ctx context.Context, key string, n int64) ([]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	byteRange := fmt.Sprintf("bytes=0-%d", n-1)
+
+	object, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+		Range:  &byteRange,
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	defer object.Body.Close()
+
+	return io.ReadAll(io.LimitReader(object.Body, n))
+}
+
+func (s *s3Store) Copy(ctx context.Context, from string, object media.Object, to, contentType string) error {
+	source := url.PathEscape(s.bucket) + "/" + (&url.URL{Path: from}).EscapedPath()
+
+	_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            &s.bucket,
+		Key:               &to,
+		CopySource:        &source,
+		CopySourceIfMatch: &object.ETag,
+		ContentType:       &contentType,
+		MetadataDirective: types.MetadataDirectiveReplace,
+	})
+
+	return notFound(err)
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	})
+
+	return err
+}
+
+// objectReader reads an object with a ranged GET from wherever it was sought to, so serving a
+// range of a large video only takes that range out of the bucket
+type objectReader struct {
+	ctx    context.Context
+	store  *s3Store
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil {
+		byteRange := fmt.Sprintf("bytes=%d-", r.offset)
+
+		object, err := r.store.client.GetObject(r.ctx, &s3.GetObjectInput{
+			Bucket: &r.store.bucket,
+			Key:    &r.key,
+			Range:  &byteRange,
+		})
+		if err != nil {
+			return 0, notFound(err)
+		}
+
+		r.body = object.Body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+
+	return n, err
+}
+
+func (r *objectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+
+	if offset < 0 {
+		return 0, errors.New("seek before the start of the object")
+	}
+
+	if offset != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = offset
+
+	return offset, nil
+}
+
+func (r *objectReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+
+	return r.body.Close()
+}
+
+// notFound turns the missing object errors of S3 into media.ErrObjectNotFound
+func notFound(err error) error {
+	var (
+		noSuchKey *types.NoSuchKey
+		notFound  *types.NotFound
+	)
+	if errors.As(err, &noSuchKey) || errors.As(err, &notFound) {
+		return media.ErrObjectNotFound
+	}
+
+	return err
+}
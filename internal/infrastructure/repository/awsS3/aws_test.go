@@ -0,0 +1,303 @@
+package awss3_test
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	awss3 "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/awsS3"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type object struct {
+	data        []byte
+	contentType string
+	etag        string
+	acl         string
+}
+
+// bucket is an S3 compatible stand-in keeping the objects of one bucket in memory, it
+// answers the path style requests the store sends and does not check signatures
+type bucket struct {
+	name    string
+	mu      sync.Mutex
+	objects map[string]object
+}
+
+func (b *bucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimSuffix(r.URL.Path, "/") == "/"+b.name && r.Method == http.MethodGet {
+		b.list(w)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+b.name+"/")
+	if !ok {
+		http.Error(w, "unknown bucket", http.StatusNotFound)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stored, found := b.objects[key]
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.URL.Query().Has("acl") {
+			if !found {
+				notFound(w, "NoSuchKey")
+				return
+			}
+
+			stored.acl = r.Header.Get("X-Amz-Acl")
+			b.objects[key] = stored
+			return
+		}
+
+		if source := r.Header.Get("X-Amz-Copy-Source"); source != "" {
+			source, _ = url.PathUnescape(source)
+			from, found := b.objects[strings.TrimPrefix(strings.TrimPrefix(source, "/"), b.name+"/")]
+			if !found {
+				notFound(w, "NoSuchKey")
+				return
+			}
+
+			if match := r.Header.Get("X-Amz-Copy-Source-If-Match"); match != "" && match != from.etag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code><Message>changed</Message></Error>`)
+				return
+			}
+
+			from.contentType = r.Header.Get("Content-Type")
+			from.acl = r.Header.Get("X-Amz-Acl")
+			b.objects[key] = from
+			fmt.Fprintf(w, `<CopyObjectResult><ETag>%s</ETag></CopyObjectResult>`, from.etag)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sum := md5.Sum(data)
+		stored = object{data: data, contentType: r.Header.Get("Content-Type"), etag: `"` + hex.EncodeToString(sum[:]) + `"`, acl: r.Header.Get("X-Amz-Acl")}
+		b.objects[key] = stored
+		w.Header().Set("ETag", stored.etag)
+	case http.MethodHead:
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("ETag", stored.etag)
+		w.Header().Set("Content-Type", stored.contentType)
+		w.Header().Set("Content-Length", fmt.Sprint(len(stored.data)))
+	case http.MethodGet:
+		if !found {
+			notFound(w, "NoSuchKey")
+			return
+		}
+
+		data := stored.data
+		if byteRange, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes="); ok {
+			first, last, _ := strings.Cut(byteRange, "-")
+			start, _ := strconv.Atoi(first)
+			end := len(data) - 1
+			if last != "" {
+				end, _ = strconv.Atoi(last)
+			}
+
+			data = data[start:min(end+1, len(data))]
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+len(data)-1, len(stored.data)))
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+		}
+
+		w.Write(data)
+	case http.MethodDelete:
+		delete(b.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list answers a listing of all the objects in one page
+func (b *bucket) list(w http.ResponseWriter) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	keys := make([]string, 0, len(b.objects))
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprintf(w, `<ListBucketResult><Name>%s</Name><KeyCount>%d</KeyCount><IsTruncated>false</IsTruncated>`, b.name, len(keys))
+	for _, key := range keys {
+		fmt.Fprintf(w, `<Contents><Key>%s</Key><Size>%d</Size></Contents>`, key, len(b.objects[key].data))
+	}
+	fmt.Fprint(w, `</ListBucketResult>`)
+}
+
+func notFound(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprintf(w, `<Error><Code>%s</Code><Message>not found</Message></Error>`, code)
+}
+
+func newStore(t *testing.T) (media.DirectStore, *bucket) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	stub := &bucket{name: "media", objects: make(map[string]object)}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	var cfg config.Config
+	cfg.AWSS3.BucketName = stub.name
+	cfg.AWSS3.Region = "us-east-1"
+	cfg.AWSS3.Endpoint = server.URL
+
+	require.NoError(t, awss3.InitS3(&cfg))
+
+	return awss3.NewStore(&cfg), stub
+}
+
+// put uploads data through a presigned URL the way a client does
+func put(t *testing.T, store media.DirectStore, key, contentType string, data string) {
+	presigned, err := store.PresignPut(context.Background(), key, contentType, int64(len(data)), time.Minute)
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(presigned)
+	require.NoError(t, err)
+	assert.Equal(t, "60", parsed.Query().Get("X-Amz-Expires"))
+	assert.NotEmpty(t, parsed.Query().Get("X-Amz-Signature"))
+
+	req, err := http.NewRequest(http.MethodPut, presigned, strings.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestStoreDirectUpload(t *testing.T) {
+	store, stub := newStore(t)
+	ctx := context.Background()
+
+	_, err := store.Stat(ctx, "uploads/1")
+	assert.ErrorIs(t, err, media.ErrObjectNotFound)
+
+	_, err = store.Read(ctx, "uploads/1", 3)
+	assert.ErrorIs(t, err, media.ErrObjectNotFound)
+
+	put(t, store, "uploads/1", "video/mp4", "hello")
+
+	object, err := store.Stat(ctx, "uploads/1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), object.Size)
+	assert.NotEmpty(t, object.ETag)
+
+	head, err := store.Read(ctx, "uploads/1", 3)
+	require.NoError(t, err)
+	assert.Equal(t, "hel", string(head))
+
+	require.NoError(t, store.Copy(ctx, "uploads/1", object, "1/original.mp4", "video/mp4"))
+	assert.Equal(t, "hello", string(stub.objects["1/original.mp4"].data))
+	assert.Equal(t, "video/mp4", stub.objects["1/original.mp4"].contentType)
+	assert.Empty(t, stub.objects["1/original.mp4"].acl)
+
+	// an object replaced after it was looked at is not copied
+	put(t, store, "uploads/1", "video/mp4", "bye")
+	assert.Error(t, store.Copy(ctx, "uploads/1", object, "1/original.mp4", "video/mp4"))
+	assert.Equal(t, "hello", string(stub.objects["1/original.mp4"].data))
+
+	require.NoError(t, store.Delete(ctx, "uploads/1"))
+
+	_, err = store.Stat(ctx, "uploads/1")
+	assert.ErrorIs(t, err, media.ErrObjectNotFound)
+}
+
+func TestStoreOpenReadsFromOffset(t *testing.T) {
+	store, stub := newStore(t)
+	ctx := context.Background()
+
+	_, _, err := store.Open(ctx, "1/original.mp4")
+	assert.ErrorIs(t, err, media.ErrObjectNotFound)
+
+	require.NoError(t, store.Put(ctx, "1/original.mp4", "video/mp4", strings.NewReader("0123456789"), 10))
+	assert.Empty(t, stub.objects["1/original.mp4"].acl)
+
+	file, object, err := store.Open(ctx, "1/original.mp4")
+	require.NoError(t, err)
+	defer file.Close()
+	assert.Equal(t, int64(10), object.Size)
+
+	end, err := file.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), end)
+
+	_, err = file.Seek(4, io.SeekStart)
+	require.NoError(t, err)
+
+	part := make([]byte, 3)
+	_, err = io.ReadFull(file, part)
+	require.NoError(t, err)
+	assert.Equal(t, "456", string(part))
+
+	_, err = file.Seek(-2, io.SeekEnd)
+	require.NoError(t, err)
+
+	rest, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, "89", string(rest))
+}
+
+func TestResetMediaACLMakesMediaObjectsPrivate(t *testing.T) {
+	_, stub := newStore(t)
+
+	const id = "6f1c1f0e-5a8b-4c47-9a53-2c1e0f4b7d10"
+	for _, key := range []string{id + "/original.png", id + "/thumbnail.webp", "legacy.png"} {
+		stub.objects[key] = object{data: []byte("file"), acl: "public-read"}
+	}
+	stub.objects["uploads/"+id] = object{data: []byte("file")}
+
+	var cfg config.Config
+	cfg.AWSS3.BucketName = stub.name
+
+	reset, err := awss3.ResetMediaACL(context.Background(), &cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reset)
+
+	assert.Equal(t, "private", stub.objects[id+"/original.png"].acl)
+	assert.Equal(t, "private", stub.objects[id+"/thumbnail.webp"].acl)
+	assert.Equal(t, "public-read", stub.objects["legacy.png"].acl)
+	assert.Empty(t, stub.objects["uploads/"+id].acl)
+
+	// a second run changes nothing it did not change already
+	reset, err = awss3.ResetMediaACL(context.Background(), &cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reset)
+	assert.Equal(t, "public-read", stub.objects["legacy.png"].acl)
+}
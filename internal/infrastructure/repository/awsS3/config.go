@@ -18,7 +18,13 @@ func InitS3(conf *cfg.Config) error {
 		return err
 	}
 
-	s3Client = s3.NewFromConfig(cfg)
+	s3Client = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		// S3 compatible stores such as MinIO are reached by their own endpoint with the bucket in the path
+		if conf.AWSS3.Endpoint != "" {
+			o.BaseEndpoint = &conf.AWSS3.Endpoint
+			o.UsePathStyle = true
+		}
+	})
 
 	return nil
 }
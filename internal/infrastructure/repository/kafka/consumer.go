@@ -1,58 +1,92 @@
 package kafka
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"log"
-	"sync"
 
 	"github.com/IBM/sarama"
-	"github.com/gorilla/websocket"
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/eventbus"
 )
 
-var clients = make(map[*websocket.Conn]bool)
-var mutex sync.Mutex
+// consumeGroup consumes topics as a member of groupID until ctx is done. Offsets
+// are committed once the handler of an event returned, so a restarted member resumes
+// where the group stopped, a new group begins at start
+func consumeGroup(ctx context.Context, brokers []string, groupID string, topics []string, start eventbus.Start, handlers eventbus.Handlers) error {
+	config := sarama.NewConfig()
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	if start == eventbus.StartNewest {
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	}
 
-func AddClient(client *websocket.Conn) {
-	mutex.Lock()
-	clients[client] = true
-	mutex.Unlock()
+	group, err := sarama.NewConsumerGroup(brokers, groupID, config)
+	if err != nil {
+		return err
+	}
+	defer group.Close()
+
+	handler := &groupHandler{
+		handlers: handlers,
+	}
+
+	for {
+		// Consume returns on every rebalance and has to be called again
+		if err := group.Consume(ctx, topics, handler); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return nil
+			}
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+type groupHandler struct {
+	handlers eventbus.Handlers
+}
+
+func (h *groupHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
 }
 
-func RemoveClient(client *websocket.Conn) {
-	mutex.Lock()
-	delete(clients, client)
-	mutex.Unlock()
+func (h *groupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
 }
 
-func BroadcastMessage(message string) {
-	mutex.Lock()
-	defer mutex.Unlock()
+func (h *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
 
-	for client := range clients {
-		err := client.WriteMessage(websocket.TextMessage, []byte(message))
-		if err != nil {
-			log.Printf("WebSocket error: %v", err)
-			client.Close()
-			delete(clients, client)
+			h.dispatch(session.Context(), msg)
+			session.MarkMessage(msg, "")
+		case <-session.Context().Done():
+			return nil
 		}
 	}
 }
 
-func StartConsumer(brokers []string, topic string) {
-	consumer, err := sarama.NewConsumer(brokers, nil)
-	if err != nil {
-		log.Fatalf("Failed to start Kafka consumer: %v", err)
+func (h *groupHandler) dispatch(ctx context.Context, msg *sarama.ConsumerMessage) {
+	var event entity.Event
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		log.Printf("Kafka message at %s/%d/%d is not an event: %v", msg.Topic, msg.Partition, msg.Offset, err)
+		return
 	}
-	defer consumer.Close()
 
-	partitionConsumer, err := consumer.ConsumePartition(topic, 0, sarama.OffsetNewest)
-	if err != nil {
-		log.Fatalf("Failed to consume Kafka partition: %v", err)
+	handler, ok := h.handlers[event.Type]
+	if !ok {
+		return
 	}
-	defer partitionConsumer.Close()
-
-	for msg := range partitionConsumer.Messages() {
-		log.Printf("Kafka message received: %s", string(msg.Value))
 
-		BroadcastMessage(string(msg.Value))
+	if err := handler(ctx, event); err != nil {
+		log.Printf("Kafka event %s of type %s failed: %v", event.ID, event.Type, err)
 	}
 }
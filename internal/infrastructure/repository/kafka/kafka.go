@@ -1,34 +1,64 @@
 package kafka
 
 import (
+	"context"
+	"encoding/json"
+
 	"github.com/IBM/sarama"
-	"log"
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/eventbus"
 )
 
-var producer sarama.SyncProducer
+// IdempotencyKeyHeader carries the event id, consumers drop events whose key they already stored
+const IdempotencyKeyHeader = "idempotency-key"
+
+type kafkaBus struct {
+	brokers  []string
+	topic    string
+	producer sarama.SyncProducer
+}
 
-func InitKafkaProducer(brokerList []string) error {
+// NewBus returns an event bus publishing to and consuming from one topic
+func NewBus(brokers []string, topic string) (eventbus.Bus, error) {
 	config := sarama.NewConfig()
 	config.Producer.Return.Successes = true
 
-	var err error
-	producer, err = sarama.NewSyncProducer(brokerList, config)
+	producer, err := sarama.NewSyncProducer(brokers, config)
 	if err != nil {
-		log.Fatalf("Failed to start IBM producer: %v", err)
-		return err
+		return nil, err
 	}
-	return nil
-}
 
-func SendMessage(topic, message string) error {
-	msg := &sarama.ProducerMessage{
-		Topic: topic,
-		Value: sarama.StringEncoder(message),
-	}
+	return &kafkaBus{
+		brokers:  brokers,
+		topic:    topic,
+		producer: producer,
+	}, nil
+}
 
-	_, _, err := producer.SendMessage(msg)
+// Publish sends an event keyed by key, events sharing a key land on one partition and keep their order
+func (k *kafkaBus) Publish(ctx context.Context, key string, event entity.Event) error {
+	value, err := json.Marshal(event)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	msg := &sarama.ProducerMessage{
+		Topic: k.topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.ByteEncoder(value),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte(IdempotencyKeyHeader), Value: []byte(event.ID)},
+		},
+	}
+
+	_, _, err = k.producer.SendMessage(msg)
+	return err
+}
+
+func (k *kafkaBus) Subscribe(ctx context.Context, group string, start eventbus.Start, handlers eventbus.Handlers) error {
+	return consumeGroup(ctx, k.brokers, group, []string{k.topic}, start, handlers)
+}
+
+func (k *kafkaBus) Close() error {
+	return k.producer.Close()
 }
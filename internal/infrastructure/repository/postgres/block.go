@@ -0,0 +1,317 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/jackc/pgx/v4"
+)
+
+// optionalViewer turns a text placeholder into the viewer id, an empty viewer gives NULL
+// and filters nothing, the placeholder must not be used as a UUID elsewhere in the query
+func optionalViewer(placeholder string) string {
+	return fmt.Sprintf("NULLIF(%s::TEXT, '')::UUID", placeholder)
+}
+
+// notBlocked filters out rows whose author column has a block with the viewer in either direction
+func notBlocked(column, viewer string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM blocks
+		WHERE (user_id = %[2]s AND blocked_id = %[1]s) OR (user_id = %[1]s AND blocked_id = %[2]s)
+	)`, column, viewer)
+}
+
+// notMuted filters out rows whose author column is muted by the viewer
+func notMuted(column, viewer string) string {
+	return fmt.Sprintf(`NOT EXISTS (SELECT 1 FROM mutes WHERE user_id = %[2]s AND muted_id = %[1]s)`, column, viewer)
+}
+
+// visibleTo filters out rows whose author column is blocked or muted for the viewer
+func visibleTo(column, viewer string) string {
+	return notBlocked(column, viewer) + " AND " + notMuted(column, viewer)
+}
+
+// blockedBetween tells whether one of the users blocked the other
+func blockedBetween(ctx context.Context, tx pgx.Tx, userID, otherID string) (bool, error) {
+	query := `
+	SELECT EXISTS (
+		SELECT 1 FROM blocks
+		WHERE (user_id = $1 AND blocked_id = $2) OR (user_id = $2 AND blocked_id = $1)
+	)
+	`
+
+	var blocked bool
+	if err := tx.QueryRow(ctx, query, userID, otherID).Scan(&blocked); err != nil {
+		return false, err
+	}
+
+	return blocked, nil
+}
+
+type blockRepo struct {
+	db *postgres.PostgresDB
+}
+
+func NewBlockRepo(db *postgres.PostgresDB) repo.BlockStorageI {
+	return &blockRepo{
+		db: db,
+	}
+}
+
+// Block toggles the block of a user, blocking removes the follows, follow requests and list
+// memberships between the two users and records a follow.deleted event for each removed follow
+func (b *blockRepo) Block(ctx context.Context, block entity.BlockAction) (bool, error) {
+	tx, err := b.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var exists int
+	err = tx.QueryRow(ctx, `SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL`, block.BlockedID).Scan(&exists)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return false, err
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, sql.ErrNoRows
+		}
+		return false, err
+	}
+
+	insertQuery := `INSERT INTO blocks (user_id, blocked_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+	tag, err := tx.Exec(ctx, insertQuery, block.UserID, block.BlockedID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		deleteQuery := `DELETE FROM blocks WHERE user_id = $1 AND blocked_id = $2`
+
+		if _, err := tx.Exec(ctx, deleteQuery, block.UserID, block.BlockedID); err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return false, err
+			}
+			return false, err
+		}
+
+		return false, tx.Commit(ctx)
+	}
+
+	unfollowQuery := `
+	DELETE FROM
+		follows
+	WHERE
+	    (user_id = $1 AND following_id = $2) OR (user_id = $2 AND following_id = $1)
+	RETURNING
+		user_id,
+		following_id
+	`
+
+	rows, err := tx.Query(ctx, unfollowQuery, block.UserID, block.BlockedID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+
+	var unfollows []entity.FollowAction
+	for rows.Next() {
+		var follow entity.FollowAction
+		if err := rows.Scan(&follow.UserID, &follow.FollowingID); err != nil {
+			rows.Close()
+			if err := tx.Rollback(ctx); err != nil {
+				return false, err
+			}
+			return false, err
+		}
+
+		unfollows = append(unfollows, follow)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+
+	requestsQuery := `
+	DELETE FROM
+		follow_requests
+	WHERE
+	    (user_id = $1 AND target_id = $2) OR (user_id = $2 AND target_id = $1)
+	`
+
+	if _, err := tx.Exec(ctx, requestsQuery, block.UserID, block.BlockedID); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+
+	listsQuery := `
+	DELETE FROM
+		list_members AS m
+	USING
+		lists AS l
+	WHERE
+	    m.list_id = l.id
+		AND ((l.owner_id = $1 AND m.user_id = $2) OR (l.owner_id = $2 AND m.user_id = $1))
+	`
+
+	if _, err := tx.Exec(ctx, listsQuery, block.UserID, block.BlockedID); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+
+	for _, follow := range unfollows {
+		err := writeOutbox(ctx, tx, entity.AggregateUser, follow.UserID, entity.EventTypeFollowDeleted, follow.UserID, follow.FollowingID, follow)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return false, err
+			}
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// Mute toggles the mute of a user
+func (b *blockRepo) Mute(ctx context.Context, mute entity.MuteAction) (bool, error) {
+	tx, err := b.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var exists int
+	err = tx.QueryRow(ctx, `SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL`, mute.MutedID).Scan(&exists)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return false, err
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, sql.ErrNoRows
+		}
+		return false, err
+	}
+
+	insertQuery := `INSERT INTO mutes (user_id, muted_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+	tag, err := tx.Exec(ctx, insertQuery, mute.UserID, mute.MutedID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+
+	muted := tag.RowsAffected() == 1
+
+	if !muted {
+		deleteQuery := `DELETE FROM mutes WHERE user_id = $1 AND muted_id = $2`
+
+		if _, err := tx.Exec(ctx, deleteQuery, mute.UserID, mute.MutedID); err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return false, err
+			}
+			return false, err
+		}
+	}
+
+	return muted, tx.Commit(ctx)
+}
+
+// Blocked returns the users blocked by id
+func (b *blockRepo) Blocked(ctx context.Context, id string) (entity.ListUser, error) {
+	query := `
+	SELECT
+		u.id,
+		u.name,
+		u.username,
+		u.email,
+		u.role,
+		u.bio,
+		u.profile_picture
+	FROM
+	    users AS u
+	INNER JOIN
+	    blocks AS b ON u.id = b.blocked_id
+	WHERE
+	    u.deleted_at IS NULL AND b.user_id = $1
+	ORDER BY
+	    b.created_at DESC
+	`
+
+	return b.listUsers(ctx, query, id)
+}
+
+// Muted returns the users muted by id
+func (b *blockRepo) Muted(ctx context.Context, id string) (entity.ListUser, error) {
+	query := `
+	SELECT
+		u.id,
+		u.name,
+		u.username,
+		u.email,
+		u.role,
+		u.bio,
+		u.profile_picture
+	FROM
+	    users AS u
+	INNER JOIN
+	    mutes AS m ON u.id = m.muted_id
+	WHERE
+	    u.deleted_at IS NULL AND m.user_id = $1
+	ORDER BY
+	    m.created_at DESC
+	`
+
+	return b.listUsers(ctx, query, id)
+}
+
+func (b *blockRepo) listUsers(ctx context.Context, query string, args ...interface{}) (entity.ListUser, error) {
+	rows, err := b.db.Query(ctx, query, args...)
+	if err != nil {
+		return entity.ListUser{}, err
+	}
+	defer rows.Close()
+
+	var response entity.ListUser
+	for rows.Next() {
+		var user entity.GetUserResponse
+		err := rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Username,
+			&user.Email,
+			&user.Role,
+			&user.Bio,
+			&user.ProfilePicture,
+		)
+		if err != nil {
+			return entity.ListUser{}, err
+		}
+
+		response.Users = append(response.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return entity.ListUser{}, err
+	}
+
+	response.Count = len(response.Users)
+
+	return response, nil
+}
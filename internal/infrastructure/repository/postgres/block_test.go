@@ -0,0 +1,97 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockTogglesAndRemovesFollowsBothWays(t *testing.T) {
+	db := testDB(t)
+	blocks := postgresql.NewBlockRepo(db)
+	ctx := context.Background()
+
+	user, other := newUser(t, db), newUser(t, db)
+	follow(t, db, user, other)
+	follow(t, db, other, user)
+
+	blocked, err := blocks.Block(ctx, entity.BlockAction{UserID: user, BlockedID: other})
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	var follows int
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM follows WHERE user_id = ANY($1) AND following_id = ANY($1)`, []string{user, other}).Scan(&follows))
+	assert.Zero(t, follows)
+
+	blocked, err = blocks.Block(ctx, entity.BlockAction{UserID: user, BlockedID: other})
+	require.NoError(t, err)
+	assert.False(t, blocked)
+}
+
+func TestBlockHidesTweetsBothWays(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	user, blocked := newUser(t, db), newUser(t, db)
+	block(t, db, user, blocked)
+
+	for _, pair := range [][2]string{{user, blocked}, {blocked, user}} {
+		_, err := tweets.GetTweet(ctx, newTweet(t, db, pair[0]), pair[1])
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	}
+}
+
+func TestMutedAuthorLeavesHomeTimeline(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	viewer, muted, followed := newUser(t, db), newUser(t, db), newUser(t, db)
+	follow(t, db, viewer, muted)
+	follow(t, db, viewer, followed)
+	mute(t, db, viewer, muted)
+
+	newTweet(t, db, muted)
+	kept := newTweet(t, db, followed)
+
+	page, err := tweets.HomeTimeline(ctx, entity.TimelineFilter{UserID: viewer, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{kept}, tweetIDs(page.Tweets))
+
+	// muting does not block, the tweet itself can still be opened
+	_, err = tweets.GetTweet(ctx, newTweet(t, db, muted), viewer)
+	assert.NoError(t, err)
+}
+
+func TestRetweetComesWithoutHiddenOriginal(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	viewer, author, reposter := newUser(t, db), newUser(t, db), newUser(t, db)
+	original := newTweet(t, db, author)
+	repost, err := retweet(t, tweets, reposter, original)
+	require.NoError(t, err)
+
+	shown, err := tweets.GetTweet(ctx, repost.ID, viewer)
+	require.NoError(t, err)
+	require.NotNil(t, shown.Original)
+	assert.Equal(t, original, shown.Original.ID)
+
+	for _, hide := range []func(){
+		func() { mute(t, db, viewer, author) },
+		func() { exec(t, db, `DELETE FROM mutes WHERE user_id = $1`, viewer); block(t, db, author, viewer) },
+	} {
+		hide()
+
+		hidden, err := tweets.GetTweet(ctx, repost.ID, viewer)
+		require.NoError(t, err)
+		assert.Nil(t, hidden.Original)
+	}
+}
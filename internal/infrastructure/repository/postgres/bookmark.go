@@ -0,0 +1,223 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/jackc/pgx/v4"
+)
+
+type bookmarkRepo struct {
+	db *postgres.PostgresDB
+}
+
+func NewBookmarkRepo(db *postgres.PostgresDB) repo.BookmarkStorageI {
+	return &bookmarkRepo{
+		db: db,
+	}
+}
+
+// Bookmark saves a tweet the user can see and reports whether it was saved for the first time,
+// saving it again moves it to FolderID. Unknown tweets and folders of other users give sql.ErrNoRows
+func (b *bookmarkRepo) Bookmark(ctx context.Context, bookmark entity.BookmarkAction) (bool, error) {
+	query := fmt.Sprintf(`
+	INSERT INTO bookmarks (user_id, tweet_id, folder_id)
+	SELECT $1::UUID, t.id, $3::UUID
+	FROM
+	    tweets AS t
+	WHERE
+	    t.id = $2
+		AND t.deleted_at IS NULL
+		AND ($3::UUID IS NULL OR EXISTS (SELECT 1 FROM bookmark_folders WHERE id = $3::UUID AND user_id = $1::UUID))
+		AND %s
+		AND %s
+	ON CONFLICT (user_id, tweet_id) DO UPDATE SET folder_id = EXCLUDED.folder_id
+	RETURNING
+		xmax = 0
+	`, notBlocked("t.user_id", "$1::UUID"), canSee("t.user_id", "$1::UUID"))
+
+	var created bool
+	if err := b.db.QueryRow(ctx, query, bookmark.UserID, bookmark.TweetID, bookmark.FolderID).Scan(&created); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, sql.ErrNoRows
+		}
+		return false, err
+	}
+
+	return created, nil
+}
+
+// RemoveBookmark deletes a saved tweet
+func (b *bookmarkRepo) RemoveBookmark(ctx context.Context, userID, tweetID string) error {
+	tag, err := b.db.Exec(ctx, `DELETE FROM bookmarks WHERE user_id = $1 AND tweet_id = $2`, userID, tweetID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// Bookmarks returns the saved tweets of a user, the most recently saved first, a folder
+// narrows the page to the tweets filed in it. Tweets the user can no longer see, after a
+// block, a mute or an unfollow of a protected author, are left out of the page
+func (b *bookmarkRepo) Bookmarks(ctx context.Context, filter entity.BookmarkFilter) (entity.TimelineResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		b.tweet_id,
+		b.created_at
+	FROM
+	    bookmarks AS b
+	INNER JOIN
+	    tweets AS t ON t.id = b.tweet_id
+	WHERE
+	    b.user_id = $1
+		AND ($2::UUID IS NULL OR b.folder_id = $2::UUID)
+		AND ($3::TIMESTAMP IS NULL OR (b.created_at, b.tweet_id) < ($3::TIMESTAMP, $4::UUID))
+		AND t.deleted_at IS NULL
+		AND %s
+		AND %s
+	ORDER BY
+	    b.created_at DESC, b.tweet_id DESC
+	LIMIT $5
+	`, visibleTo("t.user_id", "$1::UUID"), canSee("t.user_id", "$1::UUID"))
+
+	var (
+		cursorTime *time.Time
+		cursorID   *string
+	)
+	if filter.Cursor != nil {
+		cursorTime = &filter.Cursor.CreatedAt
+		cursorID = &filter.Cursor.ID
+	}
+
+	// one extra row tells whether there is a next page
+	rows, err := b.db.Query(ctx, query, filter.UserID, filter.FolderID, cursorTime, cursorID, filter.Limit+1)
+	if err != nil {
+		return entity.TimelineResponse{}, err
+	}
+	defer rows.Close()
+
+	var entries []entity.Cursor
+	for rows.Next() {
+		var entry entity.Cursor
+		if err := rows.Scan(&entry.ID, &entry.CreatedAt); err != nil {
+			return entity.TimelineResponse{}, err
+		}
+
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	var response entity.TimelineResponse
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+		response.NextCursor = utils.EncodeCursor(entries[len(entries)-1])
+	}
+
+	if len(entries) == 0 {
+		return response, nil
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+
+	// tweets hidden between the two queries are dropped here
+	response.Tweets, err = getTweetsByIDs(ctx, b.db, ids, filter.UserID)
+	if err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	if err := hydrateTweets(ctx, b.db, filter.UserID, response.Tweets); err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	return response, nil
+}
+
+// CreateFolder opens a bookmark folder, names are unique per user
+func (b *bookmarkRepo) CreateFolder(ctx context.Context, request entity.CreateBookmarkFolderRequest) (entity.BookmarkFolder, error) {
+	query := `
+	INSERT INTO bookmark_folders (id, user_id, name) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, name) DO NOTHING
+	RETURNING
+		id,
+		name,
+		created_at
+	`
+
+	var folder entity.BookmarkFolder
+	err := b.db.QueryRow(ctx, query, request.ID, request.UserID, request.Name).Scan(&folder.ID, &folder.Name, &folder.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.BookmarkFolder{}, errorspkg.ErrorConflict
+		}
+		return entity.BookmarkFolder{}, err
+	}
+
+	return folder, nil
+}
+
+// Folders returns the bookmark folders of a user with the number of tweets in each, newest first
+func (b *bookmarkRepo) Folders(ctx context.Context, userID string) (entity.BookmarkFoldersResponse, error) {
+	query := `
+	SELECT
+		f.id,
+		f.name,
+		(SELECT COUNT(*) FROM bookmarks WHERE folder_id = f.id),
+		f.created_at
+	FROM
+	    bookmark_folders AS f
+	WHERE
+	    f.user_id = $1
+	ORDER BY
+	    f.created_at DESC
+	`
+
+	rows, err := b.db.Query(ctx, query, userID)
+	if err != nil {
+		return entity.BookmarkFoldersResponse{}, err
+	}
+	defer rows.Close()
+
+	var response entity.BookmarkFoldersResponse
+	for rows.Next() {
+		var folder entity.BookmarkFolder
+		if err := rows.Scan(&folder.ID, &folder.Name, &folder.Count, &folder.CreatedAt); err != nil {
+			return entity.BookmarkFoldersResponse{}, err
+		}
+
+		response.Folders = append(response.Folders, folder)
+	}
+
+	return response, rows.Err()
+}
+
+// DeleteFolder removes a bookmark folder, the tweets filed in it stay bookmarked
+func (b *bookmarkRepo) DeleteFolder(ctx context.Context, userID, folderID string) error {
+	tag, err := b.db.Exec(ctx, `DELETE FROM bookmark_folders WHERE id = $1 AND user_id = $2`, folderID, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bookmark(t *testing.T, bookmarks repo.BookmarkStorageI, userID, tweetID string, folderID *string) {
+	t.Helper()
+
+	_, err := bookmarks.Bookmark(context.Background(), entity.BookmarkAction{UserID: userID, TweetID: tweetID, FolderID: folderID})
+	require.NoError(t, err)
+}
+
+func TestBookmarkNeedsVisibleTweetAndOwnFolder(t *testing.T) {
+	db := testDB(t)
+	bookmarks := postgresql.NewBookmarkRepo(db)
+	ctx := context.Background()
+
+	user, other, protected := newUser(t, db), newUser(t, db), newProtectedUser(t, db)
+
+	_, err := bookmarks.Bookmark(ctx, entity.BookmarkAction{UserID: user, TweetID: newTweet(t, db, protected)})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	folder, err := bookmarks.CreateFolder(ctx, entity.CreateBookmarkFolderRequest{ID: uuid.NewString(), UserID: other, Name: "later"})
+	require.NoError(t, err)
+
+	_, err = bookmarks.Bookmark(ctx, entity.BookmarkAction{UserID: user, TweetID: newTweet(t, db, other), FolderID: &folder.ID})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestBookmarksLeaveOutTweetsHiddenSince(t *testing.T) {
+	db := testDB(t)
+	bookmarks := postgresql.NewBookmarkRepo(db)
+	ctx := context.Background()
+
+	user, public, protected, blocker := newUser(t, db), newUser(t, db), newProtectedUser(t, db), newUser(t, db)
+	follow(t, db, user, protected)
+
+	kept := newTweet(t, db, public)
+	bookmark(t, bookmarks, user, kept, nil)
+	bookmark(t, bookmarks, user, newTweet(t, db, protected), nil)
+	bookmark(t, bookmarks, user, newTweet(t, db, blocker), nil)
+
+	exec(t, db, `DELETE FROM follows WHERE user_id = $1`, user)
+	block(t, db, blocker, user)
+
+	// hidden tweets do not take up the page
+	page, err := bookmarks.Bookmarks(ctx, entity.BookmarkFilter{UserID: user, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{kept}, tweetIDs(page.Tweets))
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestBookmarksOfFolder(t *testing.T) {
+	db := testDB(t)
+	bookmarks := postgresql.NewBookmarkRepo(db)
+	ctx := context.Background()
+
+	user, author := newUser(t, db), newUser(t, db)
+	folder, err := bookmarks.CreateFolder(ctx, entity.CreateBookmarkFolderRequest{ID: uuid.NewString(), UserID: user, Name: "later"})
+	require.NoError(t, err)
+
+	filed := newTweet(t, db, author)
+	bookmark(t, bookmarks, user, newTweet(t, db, author), nil)
+	bookmark(t, bookmarks, user, filed, nil)
+
+	// saving it again files it
+	created, err := bookmarks.Bookmark(ctx, entity.BookmarkAction{UserID: user, TweetID: filed, FolderID: &folder.ID})
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	page, err := bookmarks.Bookmarks(ctx, entity.BookmarkFilter{UserID: user, FolderID: &folder.ID, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filed}, tweetIDs(page.Tweets))
+}
@@ -0,0 +1,359 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/jackc/pgx/v4"
+	"github.com/lib/pq"
+)
+
+const (
+	// draftMaxAttempts is how many times a scheduled draft is tried before it goes back to the drafts
+	draftMaxAttempts = 5
+	// draftMaxBackoffMinutes caps the delay between two tries of a failing draft
+	draftMaxBackoffMinutes = 60
+)
+
+// draftColumns is the select list shared by every draft read
+const draftColumns = `
+		id,
+		user_id,
+		parent_tweet_id,
+		quote_tweet_id,
+		content,
+		media_ids,
+		publish_at,
+		last_error,
+		created_at,
+		updated_at`
+
+// scanDraft reads one row selected with draftColumns
+func scanDraft(row pgx.Row) (entity.Draft, error) {
+	var draft entity.Draft
+	err := row.Scan(
+		&draft.ID,
+		&draft.UserID,
+		&draft.ParentTweetID,
+		&draft.QuoteTweetID,
+		&draft.Content,
+		pq.Array(&draft.MediaIDs),
+		&draft.PublishAt,
+		&draft.LastError,
+		&draft.CreatedAt,
+		&draft.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Draft{}, sql.ErrNoRows
+	}
+
+	return draft, err
+}
+
+// publishDraft turns a draft locked in tx into a tweet with the id of the draft, so it can
+// never be published twice, and records the reply and mention notifications of the tweet
+func publishDraft(ctx context.Context, tx pgx.Tx, draft entity.Draft, prepare func(*entity.CreateTweetRequest)) (entity.CreateTweetResponse, error) {
+	tweet := entity.CreateTweetRequest{
+		ID:              draft.ID,
+		UserID:          draft.UserID,
+		ParentTweetID:   draft.ParentTweetID,
+		OriginalTweetID: draft.QuoteTweetID,
+		Content:         draft.Content,
+		MediaIDs:        draft.MediaIDs,
+	}
+	prepare(&tweet)
+
+	response, err := insertTweet(ctx, tx, tweet)
+	if err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM drafts WHERE id = $1`, draft.ID); err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	if err := notifyTweet(ctx, tx, response); err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	return response, nil
+}
+
+type draftRepo struct {
+	db *postgres.PostgresDB
+}
+
+func NewDraftRepo(db *postgres.PostgresDB) repo.DraftStorageI {
+	return &draftRepo{
+		db: db,
+	}
+}
+
+// CreateDraft saves a draft, it is scheduled when PublishAt is set
+func (d *draftRepo) CreateDraft(ctx context.Context, request entity.DraftRequest) (entity.Draft, error) {
+	query := `
+	INSERT INTO drafts (
+	    id,
+	    user_id,
+	    parent_tweet_id,
+	    quote_tweet_id,
+	    content,
+	    media_ids,
+	    publish_at
+	) VALUES ($1, $2, $3, $4, $5, COALESCE($6::UUID[], '{}'), $7)
+	RETURNING` + draftColumns
+
+	return scanDraft(d.db.QueryRow(
+		ctx,
+		query,
+		request.ID,
+		request.UserID,
+		request.ParentTweetID,
+		request.QuoteTweetID,
+		request.Content,
+		request.MediaIDs,
+		request.PublishAt,
+	))
+}
+
+// UpdateDraft replaces a draft of the user, clearing PublishAt takes it off the schedule
+func (d *draftRepo) UpdateDraft(ctx context.Context, request entity.DraftRequest) (entity.Draft, error) {
+	query := `
+	UPDATE
+		drafts
+	SET
+		parent_tweet_id = $3,
+		quote_tweet_id = $4,
+		content = $5,
+		media_ids = COALESCE($6::UUID[], '{}'),
+		publish_at = $7,
+		last_error = NULL,
+		attempts = 0,
+		updated_at = NOW()
+	WHERE
+	    id = $1 AND user_id = $2
+	RETURNING` + draftColumns
+
+	return scanDraft(d.db.QueryRow(
+		ctx,
+		query,
+		request.ID,
+		request.UserID,
+		request.ParentTweetID,
+		request.QuoteTweetID,
+		request.Content,
+		request.MediaIDs,
+		request.PublishAt,
+	))
+}
+
+// DeleteDraft discards a draft or cancels a scheduled tweet
+func (d *draftRepo) DeleteDraft(ctx context.Context, userID, id string) error {
+	tag, err := d.db.Exec(ctx, `DELETE FROM drafts WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// Drafts returns either the scheduled tweets of a user, the next one to go out first,
+// or the unscheduled drafts, the most recently edited first
+func (d *draftRepo) Drafts(ctx context.Context, userID string, scheduled bool) (entity.DraftsResponse, error) {
+	query := `
+	SELECT` + draftColumns + `
+	FROM
+	    drafts
+	WHERE
+	    user_id = $1 AND (publish_at IS NOT NULL) = $2
+	ORDER BY
+	    publish_at, updated_at DESC
+	`
+
+	rows, err := d.db.Query(ctx, query, userID, scheduled)
+	if err != nil {
+		return entity.DraftsResponse{}, err
+	}
+	defer rows.Close()
+
+	var response entity.DraftsResponse
+	for rows.Next() {
+		draft, err := scanDraft(rows)
+		if err != nil {
+			return entity.DraftsResponse{}, err
+		}
+
+		response.Drafts = append(response.Drafts, draft)
+	}
+
+	return response, rows.Err()
+}
+
+// PublishDraft publishes a draft of the user right away, a draft the scheduler is
+// publishing at the same time is waited for and then gives sql.ErrNoRows
+func (d *draftRepo) PublishDraft(ctx context.Context, userID, id string, prepare func(*entity.CreateTweetRequest)) (entity.CreateTweetResponse, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	query := `SELECT` + draftColumns + ` FROM drafts WHERE id = $1 AND user_id = $2 FOR UPDATE`
+
+	draft, err := scanDraft(tx.QueryRow(ctx, query, id, userID))
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+		return entity.CreateTweetResponse{}, err
+	}
+
+	response, err := publishDraft(ctx, tx, draft, prepare)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+		return entity.CreateTweetResponse{}, err
+	}
+
+	return response, tx.Commit(ctx)
+}
+
+// PublishDue publishes up to limit scheduled drafts whose time came, the oldest first. Each one
+// is published in a transaction of its own holding its row, so schedulers running side by side
+// skip each other's drafts and no draft is published twice. Drafts that can no longer be
+// published, because the tweet they answer is gone, a block came up or their media went
+// to another tweet, go back to the drafts. Drafts failing for any other reason are tried again
+// later with a growing delay and go back to the drafts after draftMaxAttempts tries
+func (d *draftRepo) PublishDue(ctx context.Context, limit int, prepare func(*entity.CreateTweetRequest)) ([]entity.CreateTweetResponse, error) {
+	var published []entity.CreateTweetResponse
+
+	for len(published) < limit {
+		response, due, err := d.publishNext(ctx, prepare)
+		if err != nil {
+			return published, err
+		}
+
+		if !due {
+			break
+		}
+
+		if response != nil {
+			published = append(published, *response)
+		}
+	}
+
+	return published, nil
+}
+
+// publishNext publishes the next due draft and reports whether there was one, the
+// response is nil when the draft could not be published
+func (d *draftRepo) publishNext(ctx context.Context, prepare func(*entity.CreateTweetRequest)) (*entity.CreateTweetResponse, bool, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := `
+	SELECT` + draftColumns + `
+	FROM
+	    drafts
+	WHERE
+	    publish_at <= NOW()
+	ORDER BY
+	    publish_at
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+	`
+
+	draft, err := scanDraft(tx.QueryRow(ctx, query))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, tx.Rollback(ctx)
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, false, err
+		}
+		return nil, false, err
+	}
+
+	response, err := publishDraft(ctx, tx, draft, prepare)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, false, err
+		}
+
+		var reason string
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			reason = entity.NotFoundData
+		case errors.Is(err, errorspkg.ErrorBlocked):
+			reason = entity.Blocked
+		case errors.Is(err, errorspkg.ErrorNoPermission):
+			reason = entity.NoAccess
+		default:
+			if ctx.Err() != nil {
+				return nil, false, err
+			}
+
+			if err := d.postponeDraft(ctx, draft.ID); err != nil {
+				return nil, false, err
+			}
+
+			return nil, true, nil
+		}
+
+		failedQuery := `
+		UPDATE
+			drafts
+		SET
+			publish_at = NULL,
+			last_error = $2,
+			updated_at = NOW()
+		WHERE
+		    id = $1 AND publish_at <= NOW()
+		`
+
+		if _, err := d.db.Exec(ctx, failedQuery, draft.ID, reason); err != nil {
+			return nil, false, err
+		}
+
+		return nil, true, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+
+	return &response, true, nil
+}
+
+// postponeDraft pushes back a draft that failed to publish, doubling the delay on each try,
+// and takes it off the schedule once it used up draftMaxAttempts
+func (d *draftRepo) postponeDraft(ctx context.Context, id string) error {
+	query := `
+	UPDATE
+		drafts
+	SET
+		attempts = attempts + 1,
+		last_error = $2,
+		publish_at = CASE
+			WHEN attempts + 1 >= $3 THEN NULL
+			ELSE NOW() + LEAST(POWER(2, attempts), $4) * INTERVAL '1 minute'
+		END,
+		updated_at = NOW()
+	WHERE
+	    id = $1 AND publish_at <= NOW()
+	`
+
+	_, err := d.db.Exec(ctx, query, id, entity.ServerError, draftMaxAttempts, draftMaxBackoffMinutes)
+	return err
+}
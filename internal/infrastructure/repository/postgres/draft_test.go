@@ -0,0 +1,157 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func keepTweet(*entity.CreateTweetRequest) {}
+
+// dueDraft saves a draft of userID scheduled a minute ago and returns its id
+func dueDraft(t *testing.T, drafts repo.DraftStorageI, userID string, parentID *string) string {
+	t.Helper()
+
+	content := "scheduled"
+	publishAt := time.Now().Add(-time.Minute)
+	draft, err := drafts.CreateDraft(context.Background(), entity.DraftRequest{
+		ID:            uuid.NewString(),
+		UserID:        userID,
+		ParentTweetID: parentID,
+		Content:       &content,
+		PublishAt:     &publishAt,
+	})
+	require.NoError(t, err)
+
+	return draft.ID
+}
+
+func TestSchedulersPublishEveryDueDraftOnce(t *testing.T) {
+	db := testDB(t)
+	drafts := postgresql.NewDraftRepo(db)
+	ctx := context.Background()
+
+	user := newUser(t, db)
+	scheduled := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		scheduled[dueDraft(t, drafts, user, nil)] = true
+	}
+
+	var (
+		mu        sync.Mutex
+		published = make(map[string]int)
+		wg        sync.WaitGroup
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			responses, err := drafts.PublishDue(ctx, 100, keepTweet)
+			assert.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, response := range responses {
+				published[response.ID]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	for id := range scheduled {
+		assert.Equal(t, 1, published[id], "draft %s", id)
+	}
+
+	var tweets int
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM tweets WHERE user_id = $1`, user).Scan(&tweets))
+	assert.Equal(t, len(scheduled), tweets)
+
+	response, err := drafts.Drafts(ctx, user, true)
+	require.NoError(t, err)
+	assert.Empty(t, response.Drafts)
+}
+
+func TestDraftAnsweringDeletedTweetGoesBackToDrafts(t *testing.T) {
+	db := testDB(t)
+	drafts := postgresql.NewDraftRepo(db)
+	ctx := context.Background()
+
+	user := newUser(t, db)
+	parent := newTweet(t, db, user)
+	id := dueDraft(t, drafts, user, &parent)
+	exec(t, db, `UPDATE tweets SET deleted_at = NOW() WHERE id = $1`, parent)
+
+	_, err := drafts.PublishDue(ctx, 100, keepTweet)
+	require.NoError(t, err)
+
+	response, err := drafts.Drafts(ctx, user, false)
+	require.NoError(t, err)
+	require.Len(t, response.Drafts, 1)
+	assert.Equal(t, id, response.Drafts[0].ID)
+	assert.Nil(t, response.Drafts[0].PublishAt)
+	require.NotNil(t, response.Drafts[0].LastError)
+	assert.Equal(t, entity.NotFoundData, *response.Drafts[0].LastError)
+}
+
+func TestFailingDraftDoesNotHoldBackTheOthers(t *testing.T) {
+	db := testDB(t)
+	drafts := postgresql.NewDraftRepo(db)
+	ctx := context.Background()
+
+	user := newUser(t, db)
+	failing := dueDraft(t, drafts, user, nil)
+	exec(t, db, `UPDATE drafts SET publish_at = publish_at - INTERVAL '1 minute' WHERE id = $1`, failing)
+	others := []string{dueDraft(t, drafts, user, nil), dueDraft(t, drafts, user, nil)}
+
+	// the kind does not fit its column, the insert of the oldest draft fails
+	breakFailing := func(tweet *entity.CreateTweetRequest) {
+		if tweet.ID == failing {
+			tweet.Kind = "not a tweet kind"
+		}
+	}
+
+	responses, err := drafts.PublishDue(ctx, 100, breakFailing)
+	require.NoError(t, err)
+
+	var published []string
+	for _, response := range responses {
+		published = append(published, response.ID)
+	}
+	assert.ElementsMatch(t, others, published)
+
+	var (
+		attempts  int
+		lastError sql.NullString
+		publishAt time.Time
+	)
+	require.NoError(t, db.QueryRow(ctx, `SELECT attempts, last_error, publish_at FROM drafts WHERE id = $1`, failing).Scan(&attempts, &lastError, &publishAt))
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, entity.ServerError, lastError.String)
+	assert.True(t, publishAt.After(time.Now()), "the draft is tried again later")
+}
+
+func TestPublishedDraftIsGone(t *testing.T) {
+	db := testDB(t)
+	drafts := postgresql.NewDraftRepo(db)
+	ctx := context.Background()
+
+	user := newUser(t, db)
+	id := dueDraft(t, drafts, user, nil)
+
+	response, err := drafts.PublishDraft(ctx, user, id, keepTweet)
+	require.NoError(t, err)
+	assert.Equal(t, id, response.ID)
+
+	_, err = drafts.PublishDraft(ctx, user, id, keepTweet)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
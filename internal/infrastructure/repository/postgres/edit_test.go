@@ -0,0 +1,62 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateTweetKeepsRevisions(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	user := newUser(t, db)
+	tweet := newTweet(t, db, user)
+
+	for i, content := range []string{"first edit", "second edit"} {
+		updated, err := tweets.UpdateTweet(ctx, entity.UpdateTweetRequest{ID: tweet, UserID: user, Content: content, EditCount: i})
+		require.NoError(t, err)
+		assert.Equal(t, i+1, updated.EditCount)
+		assert.NotNil(t, updated.EditedAt)
+	}
+
+	history, err := tweets.TweetHistory(ctx, tweet, user)
+	require.NoError(t, err)
+
+	var contents []string
+	for _, revision := range history.Revisions {
+		contents = append(contents, *revision.Content)
+	}
+	assert.Equal(t, []string{"second edit", "first edit", "hello"}, contents)
+}
+
+func TestUpdateTweetConflictsWithStaleEditCount(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	user := newUser(t, db)
+	tweet := newTweet(t, db, user)
+
+	// both edits read the tweet before either was saved
+	_, err := tweets.UpdateTweet(ctx, entity.UpdateTweetRequest{ID: tweet, UserID: user, Content: "first", EditCount: 0})
+	require.NoError(t, err)
+
+	_, err = tweets.UpdateTweet(ctx, entity.UpdateTweetRequest{ID: tweet, UserID: user, Content: "second", EditCount: 0})
+	assert.ErrorIs(t, err, errorspkg.ErrorConflict)
+
+	current, err := tweets.GetTweet(ctx, tweet, user)
+	require.NoError(t, err)
+	assert.Equal(t, "first", *current.Content)
+	assert.Equal(t, 1, current.EditCount)
+
+	history, err := tweets.TweetHistory(ctx, tweet, user)
+	require.NoError(t, err)
+	assert.Len(t, history.Revisions, 2)
+}
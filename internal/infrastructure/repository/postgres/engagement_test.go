@@ -0,0 +1,60 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngagementCounters(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	likes := postgresql.NewLikeRepo(db)
+	ctx := context.Background()
+
+	author := newUser(t, db)
+	fan := newUser(t, db)
+	tweet := newTweet(t, db, author)
+
+	liked, err := likes.Like(ctx, entity.LikeAction{UserID: fan, TweetID: tweet})
+	require.NoError(t, err)
+	assert.True(t, liked)
+
+	content := "a reply"
+	reply, err := tweets.CreateTweet(ctx, entity.CreateTweetRequest{
+		ID:            uuid.NewString(),
+		UserID:        fan,
+		Kind:          entity.TweetKindReply,
+		ParentTweetID: &tweet,
+		Content:       &content,
+	})
+	require.NoError(t, err)
+
+	got, err := tweets.GetTweet(ctx, tweet, fan)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.LikeCount)
+	assert.Equal(t, 1, got.ReplyCount)
+	assert.True(t, got.LikedByMe)
+
+	got, err = tweets.GetTweet(ctx, tweet, author)
+	require.NoError(t, err)
+	assert.False(t, got.LikedByMe)
+
+	// liking again takes the like back
+	liked, err = likes.Like(ctx, entity.LikeAction{UserID: fan, TweetID: tweet})
+	require.NoError(t, err)
+	assert.False(t, liked)
+
+	require.NoError(t, tweets.DeleteTweet(ctx, reply.ID))
+
+	got, err = tweets.GetTweet(ctx, tweet, fan)
+	require.NoError(t, err)
+	assert.Zero(t, got.LikeCount)
+	assert.Zero(t, got.ReplyCount)
+	assert.False(t, got.LikedByMe)
+}
@@ -0,0 +1,111 @@
+package postgres_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// testDB connects to the database of the environment, the tests of this package need a
+// migrated database and are skipped when there is none to reach. Rows are written with
+// fresh ids, so tests do not see each other
+func testDB(t *testing.T) *postgres.PostgresDB {
+	t.Helper()
+
+	db, err := postgres.New(config.Load())
+	if err != nil {
+		t.Skipf("database is not reachable: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	return db
+}
+
+func exec(t *testing.T, db *postgres.PostgresDB, query string, args ...interface{}) {
+	t.Helper()
+
+	_, err := db.Exec(context.Background(), query, args...)
+	require.NoError(t, err)
+}
+
+// newUser saves a public user and returns its id
+func newUser(t *testing.T, db *postgres.PostgresDB) string {
+	t.Helper()
+
+	id := uuid.NewString()
+	username := "u" + strings.ReplaceAll(id, "-", "")[:19]
+	exec(t, db, `INSERT INTO users (id, name, username, email, password, role) VALUES ($1, 'Test User', $2, $3, 'password', 'user')`,
+		id, username, username+"@test.com")
+
+	return id
+}
+
+// newProtectedUser saves a user whose tweets only followers see
+func newProtectedUser(t *testing.T, db *postgres.PostgresDB) string {
+	t.Helper()
+
+	id := newUser(t, db)
+	exec(t, db, `UPDATE users SET is_protected = TRUE WHERE id = $1`, id)
+
+	return id
+}
+
+func username(t *testing.T, db *postgres.PostgresDB, id string) string {
+	t.Helper()
+
+	var name string
+	require.NoError(t, db.QueryRow(context.Background(), `SELECT username FROM users WHERE id = $1`, id).Scan(&name))
+
+	return name
+}
+
+func follow(t *testing.T, db *postgres.PostgresDB, userID, followingID string) {
+	t.Helper()
+	exec(t, db, `INSERT INTO follows (user_id, following_id) VALUES ($1, $2)`, userID, followingID)
+}
+
+func block(t *testing.T, db *postgres.PostgresDB, userID, blockedID string) {
+	t.Helper()
+	exec(t, db, `INSERT INTO blocks (user_id, blocked_id) VALUES ($1, $2)`, userID, blockedID)
+}
+
+func mute(t *testing.T, db *postgres.PostgresDB, userID, mutedID string) {
+	t.Helper()
+	exec(t, db, `INSERT INTO mutes (user_id, muted_id) VALUES ($1, $2)`, userID, mutedID)
+}
+
+// newTweet saves a plain tweet of userID and returns its id
+func newTweet(t *testing.T, db *postgres.PostgresDB, userID string) string {
+	t.Helper()
+
+	id := uuid.NewString()
+	exec(t, db, `INSERT INTO tweets (id, user_id, kind, content, created_at) VALUES ($1, $2, 'tweet', 'hello', clock_timestamp())`, id, userID)
+
+	return id
+}
+
+// newReply saves a reply of userID to parentID and returns its id
+func newReply(t *testing.T, db *postgres.PostgresDB, userID, parentID string) string {
+	t.Helper()
+
+	id := uuid.NewString()
+	exec(t, db, `INSERT INTO tweets (id, user_id, kind, parent_tweet_id, content, created_at) VALUES ($1, $2, 'reply', $3, 'reply', clock_timestamp())`, id, userID, parentID)
+
+	return id
+}
+
+// tweetIDs lists the ids of tweets in their order
+func tweetIDs(tweets []entity.GetTweetResponse) []string {
+	ids := make([]string, 0, len(tweets))
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.ID)
+	}
+
+	return ids
+}
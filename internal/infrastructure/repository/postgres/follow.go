@@ -2,10 +2,15 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
 	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/jackc/pgx/v4"
 )
 
 type followRepo struct {
@@ -18,33 +23,244 @@ func NewFollowRepo(db *postgres.PostgresDB) repo.FollowStorageI {
 	}
 }
 
-func (f *followRepo) Follow(ctx context.Context, follow entity.FollowAction) (bool, error) {
-	countQuery := `SELECT COUNT(*) FROM follows WHERE user_id = $1 AND following_id = $2`
+// canSee filters out rows whose author column is a protected account the viewer
+// does not follow, an empty viewer only sees public accounts
+func canSee(column, viewer string) string {
+	return fmt.Sprintf(`(
+		NOT EXISTS (SELECT 1 FROM users WHERE id = %[1]s AND is_protected)
+		OR %[1]s = %[2]s
+		OR EXISTS (SELECT 1 FROM follows WHERE user_id = %[2]s AND following_id = %[1]s)
+	)`, column, viewer)
+}
 
-	var count int
-	if err := f.db.QueryRow(ctx, countQuery, follow.UserID, follow.FollowingID).Scan(&count); err != nil {
-		return false, err
+// Follow toggles the follow of a user and records the change in the outbox in the same transaction,
+// following a protected account sends a follow request instead and calling it again withdraws
+// the request. The followed user is notified in the same transaction. Users with a block between
+// them can not follow each other
+func (f *followRepo) Follow(ctx context.Context, follow entity.FollowAction) (entity.FollowResponse, error) {
+	tx, err := f.db.Begin(ctx)
+	if err != nil {
+		return entity.FollowResponse{}, err
+	}
+
+	var isProtected bool
+	err = tx.QueryRow(ctx, `SELECT is_protected FROM users WHERE id = $1 AND deleted_at IS NULL`, follow.FollowingID).Scan(&isProtected)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = sql.ErrNoRows
+	}
+	if err == nil {
+		var blocked bool
+		blocked, err = blockedBetween(ctx, tx, follow.UserID, follow.FollowingID)
+		if err == nil && blocked {
+			err = errorspkg.ErrorBlocked
+		}
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.FollowResponse{}, err
+		}
+		return entity.FollowResponse{}, err
 	}
 
-	if count == 0 {
-		insertQuery := `INSERT INTO follows (user_id, following_id) VALUES ($1, $2)`
+	unfollowQuery := `DELETE FROM follows WHERE user_id = $1 AND following_id = $2`
 
-		_, err := f.db.Exec(ctx, insertQuery, follow.UserID, follow.FollowingID)
+	tag, err := tx.Exec(ctx, unfollowQuery, follow.UserID, follow.FollowingID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.FollowResponse{}, err
+		}
+		return entity.FollowResponse{}, err
+	}
+
+	if tag.RowsAffected() > 0 {
+		err := writeOutbox(ctx, tx, entity.AggregateUser, follow.UserID, entity.EventTypeFollowDeleted, follow.UserID, follow.FollowingID, follow)
 		if err != nil {
-			return false, err
+			if err := tx.Rollback(ctx); err != nil {
+				return entity.FollowResponse{}, err
+			}
+			return entity.FollowResponse{}, err
+		}
+
+		return entity.FollowResponse{}, tx.Commit(ctx)
+	}
+
+	withdrawQuery := `DELETE FROM follow_requests WHERE user_id = $1 AND target_id = $2`
+
+	tag, err = tx.Exec(ctx, withdrawQuery, follow.UserID, follow.FollowingID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.FollowResponse{}, err
 		}
+		return entity.FollowResponse{}, err
+	}
+
+	if tag.RowsAffected() > 0 {
+		return entity.FollowResponse{}, tx.Commit(ctx)
+	}
 
-		return true, nil
-	} else {
-		deleteQuery := `DELETE FROM follows WHERE user_id = $1 AND following_id = $2`
+	if isProtected {
+		requestQuery := `INSERT INTO follow_requests (user_id, target_id) VALUES ($1, $2)`
 
-		_, err := f.db.Exec(ctx, deleteQuery, follow.UserID, follow.FollowingID)
+		_, err := tx.Exec(ctx, requestQuery, follow.UserID, follow.FollowingID)
+		if err == nil {
+			err = notify(ctx, tx, follow.FollowingID, follow.UserID, entity.NotificationTypeFollowRequest, nil)
+		}
 		if err != nil {
-			return false, err
+			if err := tx.Rollback(ctx); err != nil {
+				return entity.FollowResponse{}, err
+			}
+			return entity.FollowResponse{}, err
 		}
 
-		return false, nil
+		return entity.FollowResponse{Requested: true}, tx.Commit(ctx)
+	}
+
+	err = insertFollow(ctx, tx, follow)
+	if err == nil {
+		err = notify(ctx, tx, follow.FollowingID, follow.UserID, entity.NotificationTypeFollow, nil)
 	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.FollowResponse{}, err
+		}
+		return entity.FollowResponse{}, err
+	}
+
+	return entity.FollowResponse{Status: true}, tx.Commit(ctx)
+}
+
+// insertFollow saves a follow with its follow.created event
+func insertFollow(ctx context.Context, tx pgx.Tx, follow entity.FollowAction) error {
+	insertQuery := `INSERT INTO follows (user_id, following_id) VALUES ($1, $2) ON CONFLICT (user_id, following_id) DO NOTHING`
+
+	tag, err := tx.Exec(ctx, insertQuery, follow.UserID, follow.FollowingID)
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+
+	return writeOutbox(ctx, tx, entity.AggregateUser, follow.UserID, entity.EventTypeFollowCreated, follow.UserID, follow.FollowingID, follow)
+}
+
+// acceptFollowRequests turns the pending follow requests to userID into follows
+func acceptFollowRequests(ctx context.Context, tx pgx.Tx, userID string) error {
+	rows, err := tx.Query(ctx, `DELETE FROM follow_requests WHERE target_id = $1 RETURNING user_id`, userID)
+	if err != nil {
+		return err
+	}
+
+	var requesters []string
+	for rows.Next() {
+		var requesterID string
+		if err := rows.Scan(&requesterID); err != nil {
+			rows.Close()
+			return err
+		}
+
+		requesters = append(requesters, requesterID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, requesterID := range requesters {
+		err := insertFollow(ctx, tx, entity.FollowAction{
+			UserID:      requesterID,
+			FollowingID: userID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FollowRequests returns the users waiting for id to answer their follow request, newest first
+func (f *followRepo) FollowRequests(ctx context.Context, id string) (entity.ListUser, error) {
+	query := `
+	SELECT
+		u.id,
+		u.name,
+		u.username,
+		u.email,
+		u.role,
+		u.bio,
+		u.profile_picture
+	FROM
+	    users AS u
+	INNER JOIN
+	    follow_requests AS r ON u.id = r.user_id
+	WHERE
+	    u.deleted_at IS NULL AND r.target_id = $1
+	ORDER BY
+	    r.created_at DESC
+	`
+
+	rows, err := f.db.Query(ctx, query, id)
+	if err != nil {
+		return entity.ListUser{}, err
+	}
+	defer rows.Close()
+
+	var response entity.ListUser
+	for rows.Next() {
+		var user entity.GetUserResponse
+		err := rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Username,
+			&user.Email,
+			&user.Role,
+			&user.Bio,
+			&user.ProfilePicture,
+		)
+		if err != nil {
+			return entity.ListUser{}, err
+		}
+
+		response.Users = append(response.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return entity.ListUser{}, err
+	}
+
+	response.Count = len(response.Users)
+
+	return response, nil
+}
+
+// AnswerFollowRequest removes a pending follow request, an approved one becomes a follow the
+// requester is notified of
+func (f *followRepo) AnswerFollowRequest(ctx context.Context, request entity.FollowRequestAction, approve bool) error {
+	tx, err := f.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	deleteQuery := `DELETE FROM follow_requests WHERE user_id = $1 AND target_id = $2`
+
+	tag, err := tx.Exec(ctx, deleteQuery, request.RequesterID, request.UserID)
+	if err == nil && tag.RowsAffected() == 0 {
+		err = sql.ErrNoRows
+	}
+	if err == nil && approve {
+		err = insertFollow(ctx, tx, entity.FollowAction{
+			UserID:      request.RequesterID,
+			FollowingID: request.UserID,
+		})
+		if err == nil {
+			err = notify(ctx, tx, request.RequesterID, request.UserID, entity.NotificationTypeFollowAccepted, nil)
+		}
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (f *followRepo) GetFollowings(ctx context.Context, id string) (entity.ListUser, error) {
@@ -150,3 +366,52 @@ func (f *followRepo) GetFollowers(ctx context.Context, id string) (entity.ListUs
 
 	return response, nil
 }
+
+// FollowerIDs returns up to limit ids of users following id
+func (f *followRepo) FollowerIDs(ctx context.Context, id string, limit int) ([]string, error) {
+	query := `SELECT user_id FROM follows WHERE following_id = $1 LIMIT $2`
+
+	return f.queryIDs(ctx, query, id, limit)
+}
+
+// FollowingIDs returns ids of all users followed by id
+func (f *followRepo) FollowingIDs(ctx context.Context, id string) ([]string, error) {
+	query := `SELECT following_id FROM follows WHERE user_id = $1`
+
+	return f.queryIDs(ctx, query, id)
+}
+
+// HeavyFollowings returns ids of users followed by id who have more than threshold followers
+func (f *followRepo) HeavyFollowings(ctx context.Context, id string, threshold int) ([]string, error) {
+	query := `
+	SELECT
+		f.following_id
+	FROM
+	    follows AS f
+	WHERE
+	    f.user_id = $1
+		AND (SELECT COUNT(*) FROM follows WHERE following_id = f.following_id) > $2
+	`
+
+	return f.queryIDs(ctx, query, id, threshold)
+}
+
+func (f *followRepo) queryIDs(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := f.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
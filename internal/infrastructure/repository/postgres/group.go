@@ -0,0 +1,327 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/jackc/pgx/v4"
+)
+
+// maxGroupMembers caps the members of a group conversation, the owner included
+const maxGroupMembers = 256
+
+// roleRank orders the roles of group members, a member only manages the ones ranked below
+var roleRank = map[string]int{
+	entity.MemberRoleMember: 1,
+	entity.MemberRoleAdmin:  2,
+	entity.MemberRoleOwner:  3,
+}
+
+// groupRole locks a group for a membership change and returns the role of userID in it,
+// direct conversations and groups userID is not in give sql.ErrNoRows
+func groupRole(ctx context.Context, tx pgx.Tx, conversationID, userID string) (string, error) {
+	query := `
+	SELECT
+		m.role
+	FROM
+	    conversations AS c
+	INNER JOIN
+	    conversation_members AS m ON m.conversation_id = c.id AND m.user_id = $2
+	WHERE
+	    c.id = $1 AND c.kind = 'group'
+	FOR UPDATE OF c
+	`
+
+	var role string
+	if err := tx.QueryRow(ctx, query, conversationID, userID).Scan(&role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", sql.ErrNoRows
+		}
+		return "", err
+	}
+
+	return role, nil
+}
+
+// addGroupMembers adds the existing users of ids to a group as members on behalf of inviterID,
+// ids already in it and users with a block between them and the inviter are skipped
+func addGroupMembers(ctx context.Context, tx pgx.Tx, conversationID, inviterID string, ids []string) error {
+	query := fmt.Sprintf(`
+	INSERT INTO conversation_members (conversation_id, user_id, role)
+	SELECT $1, id, 'member' FROM users WHERE id = ANY($2::UUID[]) AND deleted_at IS NULL AND %s
+	ON CONFLICT DO NOTHING
+	`, notBlocked("id", "$3::UUID"))
+
+	if _, err := tx.Exec(ctx, query, conversationID, ids, inviterID); err != nil {
+		return err
+	}
+
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM conversation_members WHERE conversation_id = $1`, conversationID).Scan(&count); err != nil {
+		return err
+	}
+
+	if count > maxGroupMembers {
+		return errorspkg.ErrorGroupFull
+	}
+
+	return nil
+}
+
+// CreateGroup opens a group owned by its creator with the existing users of MemberIDs as members
+func (m *messageRepo) CreateGroup(ctx context.Context, request entity.CreateGroupRequest) (entity.Conversation, error) {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return entity.Conversation{}, err
+	}
+
+	insertQuery := `
+	INSERT INTO conversations (id, kind, title, created_by) VALUES ($1, 'group', $2, $3)
+	`
+
+	if _, err := tx.Exec(ctx, insertQuery, request.ID, request.Title, request.OwnerID); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Conversation{}, err
+		}
+		return entity.Conversation{}, err
+	}
+
+	ownerQuery := `INSERT INTO conversation_members (conversation_id, user_id, role) VALUES ($1, $2, 'owner')`
+
+	if _, err := tx.Exec(ctx, ownerQuery, request.ID, request.OwnerID); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Conversation{}, err
+		}
+		return entity.Conversation{}, err
+	}
+
+	if err := addGroupMembers(ctx, tx, request.ID, request.OwnerID, request.MemberIDs); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Conversation{}, err
+		}
+		return entity.Conversation{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return entity.Conversation{}, err
+	}
+
+	return m.GetConversation(ctx, request.ID, request.OwnerID)
+}
+
+// UpdateGroup changes the title or the avatar of a group, owners and admins only
+func (m *messageRepo) UpdateGroup(ctx context.Context, request entity.UpdateGroupRequest) (entity.Conversation, error) {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return entity.Conversation{}, err
+	}
+
+	role, err := groupRole(ctx, tx, request.ID, request.UserID)
+	if err == nil && roleRank[role] < roleRank[entity.MemberRoleAdmin] {
+		err = errorspkg.ErrorNoPermission
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Conversation{}, err
+		}
+		return entity.Conversation{}, err
+	}
+
+	query := `
+	UPDATE
+		conversations
+	SET
+		title = COALESCE($2, title),
+		avatar_url = COALESCE($3, avatar_url)
+	WHERE
+	    id = $1
+	`
+
+	if _, err := tx.Exec(ctx, query, request.ID, request.Title, request.AvatarURL); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Conversation{}, err
+		}
+		return entity.Conversation{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return entity.Conversation{}, err
+	}
+
+	return m.GetConversation(ctx, request.ID, request.UserID)
+}
+
+// AddMembers invites users to a group, owners and admins only
+func (m *messageRepo) AddMembers(ctx context.Context, request entity.GroupMembersRequest) (entity.Conversation, error) {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return entity.Conversation{}, err
+	}
+
+	role, err := groupRole(ctx, tx, request.ConversationID, request.UserID)
+	if err == nil && roleRank[role] < roleRank[entity.MemberRoleAdmin] {
+		err = errorspkg.ErrorNoPermission
+	}
+	if err == nil {
+		err = addGroupMembers(ctx, tx, request.ConversationID, request.UserID, request.MemberIDs)
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Conversation{}, err
+		}
+		return entity.Conversation{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return entity.Conversation{}, err
+	}
+
+	return m.GetConversation(ctx, request.ConversationID, request.UserID)
+}
+
+// RemoveMember takes a member out of a group, the remover has to rank above them
+func (m *messageRepo) RemoveMember(ctx context.Context, request entity.GroupMemberRequest) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	role, err := groupRole(ctx, tx, request.ConversationID, request.UserID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	query := `DELETE FROM conversation_members WHERE conversation_id = $1 AND user_id = $2 RETURNING role`
+
+	var memberRole string
+	err = tx.QueryRow(ctx, query, request.ConversationID, request.MemberID).Scan(&memberRole)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = sql.ErrNoRows
+	}
+	if err == nil && roleRank[role] <= roleRank[memberRole] {
+		err = errorspkg.ErrorNoPermission
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SetMemberRole lets the owner promote or demote a member, making someone else the owner
+// hands the group over and leaves the previous owner an admin
+func (m *messageRepo) SetMemberRole(ctx context.Context, request entity.GroupMemberRequest) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	role, err := groupRole(ctx, tx, request.ConversationID, request.UserID)
+	if err == nil && role != entity.MemberRoleOwner {
+		err = errorspkg.ErrorNoPermission
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	query := `UPDATE conversation_members SET role = $3 WHERE conversation_id = $1 AND user_id = $2`
+
+	tag, err := tx.Exec(ctx, query, request.ConversationID, request.MemberID, request.Role)
+	if err == nil && tag.RowsAffected() == 0 {
+		err = sql.ErrNoRows
+	}
+	if err == nil && request.Role == entity.MemberRoleOwner {
+		_, err = tx.Exec(ctx, query, request.ConversationID, request.UserID, entity.MemberRoleAdmin)
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// LeaveGroup takes a user out of a group, an owner leaving hands the group over to the
+// longest standing admin, or member when there is no admin
+func (m *messageRepo) LeaveGroup(ctx context.Context, conversationID, userID string) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	role, err := groupRole(ctx, tx, conversationID, userID)
+	if err == nil {
+		_, err = tx.Exec(ctx, `DELETE FROM conversation_members WHERE conversation_id = $1 AND user_id = $2`, conversationID, userID)
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	if role == entity.MemberRoleOwner {
+		successorQuery := `
+		UPDATE
+			conversation_members
+		SET
+			role = 'owner'
+		WHERE
+		    conversation_id = $1
+			AND user_id = (
+				SELECT user_id FROM conversation_members
+				WHERE conversation_id = $1
+				ORDER BY role = 'admin' DESC, joined_at, user_id
+				LIMIT 1
+			)
+		`
+
+		if _, err := tx.Exec(ctx, successorQuery, conversationID); err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Mute sets the mute setting of a member, direct conversations included
+func (m *messageRepo) Mute(ctx context.Context, request entity.MuteConversationRequest) error {
+	query := `
+	UPDATE
+		conversation_members
+	SET
+		muted = $3,
+		muted_until = $4
+	WHERE
+	    conversation_id = $1 AND user_id = $2
+	`
+
+	tag, err := m.db.Exec(ctx, query, request.ConversationID, request.UserID, request.Muted, request.MutedUntil)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
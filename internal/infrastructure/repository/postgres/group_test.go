@@ -0,0 +1,164 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// group opens a group of ownerID with memberIDs and returns it
+func group(t *testing.T, messages repo.MessageStorageI, ownerID string, memberIDs ...string) entity.Conversation {
+	t.Helper()
+
+	conversation, err := messages.CreateGroup(context.Background(), entity.CreateGroupRequest{
+		ID:        uuid.NewString(),
+		OwnerID:   ownerID,
+		Title:     "group",
+		MemberIDs: memberIDs,
+	})
+	require.NoError(t, err)
+
+	return conversation
+}
+
+// roles maps the members of a conversation to their roles as seen by userID
+func roles(t *testing.T, messages repo.MessageStorageI, conversationID, userID string) map[string]string {
+	t.Helper()
+
+	conversation, err := messages.GetConversation(context.Background(), conversationID, userID)
+	require.NoError(t, err)
+
+	roles := make(map[string]string, len(conversation.Members))
+	for _, member := range conversation.Members {
+		roles[member.UserID] = member.Role
+	}
+
+	return roles
+}
+
+func setRole(t *testing.T, messages repo.MessageStorageI, conversationID, ownerID, memberID, role string) {
+	t.Helper()
+
+	require.NoError(t, messages.SetMemberRole(context.Background(), entity.GroupMemberRequest{
+		ConversationID: conversationID,
+		UserID:         ownerID,
+		MemberID:       memberID,
+		Role:           role,
+	}))
+}
+
+func TestCreateGroupSkipsUsersBlockingOwner(t *testing.T) {
+	db := testDB(t)
+	messages := postgresql.NewMessageRepo(db)
+
+	owner, member, blocker := newUser(t, db), newUser(t, db), newUser(t, db)
+	block(t, db, blocker, owner)
+
+	conversation := group(t, messages, owner, member, blocker)
+
+	assert.Equal(t, map[string]string{
+		owner:  entity.MemberRoleOwner,
+		member: entity.MemberRoleMember,
+	}, roles(t, messages, conversation.ID, owner))
+}
+
+func TestOnlyAdminsManageGroup(t *testing.T) {
+	db := testDB(t)
+	messages := postgresql.NewMessageRepo(db)
+	ctx := context.Background()
+
+	owner, admin, member, invitee := newUser(t, db), newUser(t, db), newUser(t, db), newUser(t, db)
+	conversation := group(t, messages, owner, admin, member)
+	setRole(t, messages, conversation.ID, owner, admin, entity.MemberRoleAdmin)
+
+	_, err := messages.AddMembers(ctx, entity.GroupMembersRequest{ConversationID: conversation.ID, UserID: member, MemberIDs: []string{invitee}})
+	assert.ErrorIs(t, err, errorspkg.ErrorNoPermission)
+
+	title := "renamed"
+	_, err = messages.UpdateGroup(ctx, entity.UpdateGroupRequest{ID: conversation.ID, UserID: member, Title: &title})
+	assert.ErrorIs(t, err, errorspkg.ErrorNoPermission)
+
+	err = messages.SetMemberRole(ctx, entity.GroupMemberRequest{ConversationID: conversation.ID, UserID: admin, MemberID: member, Role: entity.MemberRoleAdmin})
+	assert.ErrorIs(t, err, errorspkg.ErrorNoPermission)
+
+	updated, err := messages.AddMembers(ctx, entity.GroupMembersRequest{ConversationID: conversation.ID, UserID: admin, MemberIDs: []string{invitee}})
+	require.NoError(t, err)
+	assert.Len(t, updated.Members, 4)
+}
+
+func TestRemoveMemberNeedsHigherRole(t *testing.T) {
+	db := testDB(t)
+	messages := postgresql.NewMessageRepo(db)
+	ctx := context.Background()
+
+	owner, admin, other, member := newUser(t, db), newUser(t, db), newUser(t, db), newUser(t, db)
+	conversation := group(t, messages, owner, admin, other, member)
+	setRole(t, messages, conversation.ID, owner, admin, entity.MemberRoleAdmin)
+	setRole(t, messages, conversation.ID, owner, other, entity.MemberRoleAdmin)
+
+	for _, target := range []string{owner, other} {
+		err := messages.RemoveMember(ctx, entity.GroupMemberRequest{ConversationID: conversation.ID, UserID: admin, MemberID: target})
+		assert.ErrorIs(t, err, errorspkg.ErrorNoPermission)
+	}
+
+	require.NoError(t, messages.RemoveMember(ctx, entity.GroupMemberRequest{ConversationID: conversation.ID, UserID: admin, MemberID: member}))
+	assert.NotContains(t, roles(t, messages, conversation.ID, owner), member)
+}
+
+func TestGroupOwnershipIsHandedOver(t *testing.T) {
+	db := testDB(t)
+	messages := postgresql.NewMessageRepo(db)
+	ctx := context.Background()
+
+	owner, member, admin := newUser(t, db), newUser(t, db), newUser(t, db)
+	conversation := group(t, messages, owner, member, admin)
+	setRole(t, messages, conversation.ID, owner, admin, entity.MemberRoleAdmin)
+
+	// the previous owner stays an admin
+	setRole(t, messages, conversation.ID, owner, member, entity.MemberRoleOwner)
+	assert.Equal(t, map[string]string{
+		owner:  entity.MemberRoleAdmin,
+		member: entity.MemberRoleOwner,
+		admin:  entity.MemberRoleAdmin,
+	}, roles(t, messages, conversation.ID, member))
+
+	// an owner leaving hands over to an admin
+	setRole(t, messages, conversation.ID, member, owner, entity.MemberRoleMember)
+	require.NoError(t, messages.LeaveGroup(ctx, conversation.ID, member))
+	assert.Equal(t, map[string]string{
+		owner: entity.MemberRoleMember,
+		admin: entity.MemberRoleOwner,
+	}, roles(t, messages, conversation.ID, admin))
+}
+
+func TestMutedMemberIsNotSentMessages(t *testing.T) {
+	db := testDB(t)
+	messages := postgresql.NewMessageRepo(db)
+	ctx := context.Background()
+
+	owner, listener, muter := newUser(t, db), newUser(t, db), newUser(t, db)
+	conversation := group(t, messages, owner, listener, muter)
+	require.NoError(t, messages.Mute(ctx, entity.MuteConversationRequest{ConversationID: conversation.ID, UserID: muter, Muted: true}))
+
+	send(t, messages, conversation.ID, owner)
+
+	rows, err := db.Query(ctx, `SELECT event->>'subject_id' FROM outbox WHERE aggregate_id = $1 AND event_type = $2`,
+		conversation.ID, entity.EventTypeMessageCreated)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var recipients []string
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		recipients = append(recipients, id)
+	}
+	assert.Equal(t, []string{listener}, recipients)
+}
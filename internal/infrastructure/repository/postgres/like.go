@@ -2,10 +2,14 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
 	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
 )
 
 type likeRepo struct {
@@ -18,31 +22,77 @@ func NewLikeRepo(db *postgres.PostgresDB) repo.LikeStorageI {
 	}
 }
 
+// Like toggles the like of a user on a tweet and keeps like_count of the tweet in the same transaction,
+// the author is notified of a new like there as well
 func (l *likeRepo) Like(ctx context.Context, like entity.LikeAction) (bool, error) {
-	countQuery := `SELECT COUNT(*) FROM likes WHERE user_id = $1 AND tweet_id = $2`
+	tx, err := l.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
 
-	var count int
-	if err := l.db.QueryRow(ctx, countQuery, like.UserID, like.TweetID).Scan(&count); err != nil {
+	var authorID string
+	err = tx.QueryRow(ctx, `SELECT user_id FROM tweets WHERE id = $1 AND deleted_at IS NULL`, like.TweetID).Scan(&authorID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return false, err
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, sql.ErrNoRows
+		}
 		return false, err
 	}
 
-	if count == 0 {
-		insertQuery := `INSERT INTO likes (user_id, tweet_id) VALUES ($1, $2)`
+	insertQuery := `INSERT INTO likes (id, user_id, tweet_id) VALUES ($1, $2, $3) ON CONFLICT (user_id, tweet_id) DO NOTHING`
 
-		_, err := l.db.Exec(ctx, insertQuery, like.UserID, like.TweetID)
-		if err != nil {
+	tag, err := tx.Exec(ctx, insertQuery, uuid.NewString(), like.UserID, like.TweetID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
 			return false, err
 		}
+		return false, err
+	}
+
+	liked := tag.RowsAffected() == 1
+	delta := int64(1)
 
-		return true, nil
-	} else {
+	if !liked {
 		deleteQuery := `DELETE FROM likes WHERE user_id = $1 AND tweet_id = $2`
 
-		_, err := l.db.Exec(ctx, deleteQuery, like.UserID, like.TweetID)
+		tag, err := tx.Exec(ctx, deleteQuery, like.UserID, like.TweetID)
 		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return false, err
+			}
 			return false, err
 		}
 
-		return false, nil
+		delta = -tag.RowsAffected()
 	}
+
+	if _, err := tx.Exec(ctx, `UPDATE tweets SET like_count = like_count + $2 WHERE id = $1`, like.TweetID, delta); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+
+	if delta != 0 {
+		eventType := entity.EventTypeLikeCreated
+		if !liked {
+			eventType = entity.EventTypeLikeDeleted
+		}
+
+		err := writeOutbox(ctx, tx, entity.AggregateTweet, like.TweetID, eventType, like.UserID, like.TweetID, like)
+		if err == nil && liked {
+			err = notify(ctx, tx, authorID, like.UserID, entity.NotificationTypeLike, &like.TweetID)
+		}
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return false, err
+			}
+			return false, err
+		}
+	}
+
+	return liked, tx.Commit(ctx)
 }
@@ -0,0 +1,395 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/jackc/pgx/v4"
+)
+
+// listColumns is the select list of a list seen by the user $1, the list must be aliased as l
+const listColumns = `
+		l.id,
+		l.owner_id,
+		l.name,
+		l.description,
+		l.is_private,
+		(SELECT COUNT(*) FROM list_members WHERE list_id = l.id),
+		(SELECT COUNT(*) FROM list_subscriptions WHERE list_id = l.id),
+		EXISTS (SELECT 1 FROM list_subscriptions WHERE list_id = l.id AND user_id = $1),
+		l.created_at`
+
+// scanList reads one row selected with listColumns
+func scanList(row pgx.Row) (entity.List, error) {
+	var list entity.List
+	err := row.Scan(
+		&list.ID,
+		&list.OwnerID,
+		&list.Name,
+		&list.Description,
+		&list.IsPrivate,
+		&list.MemberCount,
+		&list.SubscriberCount,
+		&list.SubscribedByMe,
+		&list.CreatedAt,
+	)
+
+	return list, err
+}
+
+type listRepo struct {
+	db *postgres.PostgresDB
+}
+
+func NewListRepo(db *postgres.PostgresDB) repo.ListStorageI {
+	return &listRepo{
+		db: db,
+	}
+}
+
+// CreateList saves a list owned by its creator
+func (l *listRepo) CreateList(ctx context.Context, request entity.CreateListRequest) (entity.List, error) {
+	query := `INSERT INTO lists (id, owner_id, name, description, is_private) VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := l.db.Exec(ctx, query, request.ID, request.OwnerID, request.Name, request.Description, request.IsPrivate); err != nil {
+		return entity.List{}, err
+	}
+
+	return l.GetList(ctx, request.ID, request.OwnerID)
+}
+
+// GetList returns a list seen by viewerID, private lists of others give sql.ErrNoRows
+func (l *listRepo) GetList(ctx context.Context, id, viewerID string) (entity.List, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    lists AS l
+	WHERE
+	    l.id = $2 AND (NOT l.is_private OR l.owner_id = $1)
+	`, listColumns)
+
+	list, err := scanList(l.db.QueryRow(ctx, query, viewerID, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.List{}, sql.ErrNoRows
+		}
+		return entity.List{}, err
+	}
+
+	return list, nil
+}
+
+// Lists returns the lists userID owns or subscribed to, newest first
+func (l *listRepo) Lists(ctx context.Context, userID string) (entity.ListsResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    lists AS l
+	WHERE
+	    l.owner_id = $1
+		OR (NOT l.is_private AND EXISTS (SELECT 1 FROM list_subscriptions WHERE list_id = l.id AND user_id = $1))
+	ORDER BY
+	    l.created_at DESC
+	`, listColumns)
+
+	rows, err := l.db.Query(ctx, query, userID)
+	if err != nil {
+		return entity.ListsResponse{}, err
+	}
+	defer rows.Close()
+
+	var response entity.ListsResponse
+	for rows.Next() {
+		list, err := scanList(rows)
+		if err != nil {
+			return entity.ListsResponse{}, err
+		}
+
+		response.Lists = append(response.Lists, list)
+	}
+
+	return response, rows.Err()
+}
+
+// listDenied tells why userID could not change a list, lists they can not see are
+// not found and the lists of others they can see are not theirs to change
+func (l *listRepo) listDenied(ctx context.Context, listID, userID string) error {
+	if _, err := l.GetList(ctx, listID, userID); err != nil {
+		return err
+	}
+
+	return errorspkg.ErrorNoPermission
+}
+
+// UpdateList changes the set fields of a list, owners only
+func (l *listRepo) UpdateList(ctx context.Context, request entity.UpdateListRequest) (entity.List, error) {
+	query := `
+	UPDATE
+		lists
+	SET
+		name = COALESCE($3, name),
+		description = COALESCE($4, description),
+		is_private = COALESCE($5, is_private),
+		updated_at = NOW()
+	WHERE
+	    id = $1 AND owner_id = $2
+	`
+
+	tag, err := l.db.Exec(ctx, query, request.ID, request.OwnerID, request.Name, request.Description, request.IsPrivate)
+	if err != nil {
+		return entity.List{}, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return entity.List{}, l.listDenied(ctx, request.ID, request.OwnerID)
+	}
+
+	return l.GetList(ctx, request.ID, request.OwnerID)
+}
+
+// DeleteList removes a list with its members and subscriptions, owners only
+func (l *listRepo) DeleteList(ctx context.Context, id, ownerID string) error {
+	tag, err := l.db.Exec(ctx, `DELETE FROM lists WHERE id = $1 AND owner_id = $2`, id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return l.listDenied(ctx, id, ownerID)
+	}
+
+	return nil
+}
+
+// AddMember puts a user on a list, owners only, users with a block between them
+// and the owner can not be added
+func (l *listRepo) AddMember(ctx context.Context, request entity.ListMemberRequest) error {
+	tx, err := l.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	var owned bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM lists WHERE id = $1 AND owner_id = $2)`, request.ListID, request.OwnerID).Scan(&owned)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	if !owned {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return l.listDenied(ctx, request.ListID, request.OwnerID)
+	}
+
+	var exists int
+	err = tx.QueryRow(ctx, `SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL`, request.UserID).Scan(&exists)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = sql.ErrNoRows
+	}
+	if err == nil {
+		var blocked bool
+		blocked, err = blockedBetween(ctx, tx, request.OwnerID, request.UserID)
+		if err == nil && blocked {
+			err = errorspkg.ErrorBlocked
+		}
+	}
+	if err == nil {
+		_, err = tx.Exec(ctx, `INSERT INTO list_members (list_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, request.ListID, request.UserID)
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RemoveMember takes a user off a list, owners only
+func (l *listRepo) RemoveMember(ctx context.Context, request entity.ListMemberRequest) error {
+	query := `
+	DELETE FROM
+		list_members AS m
+	USING
+		lists AS l
+	WHERE
+	    m.list_id = l.id AND l.id = $1 AND l.owner_id = $2 AND m.user_id = $3
+	`
+
+	tag, err := l.db.Exec(ctx, query, request.ListID, request.OwnerID, request.UserID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	list, err := l.GetList(ctx, request.ListID, request.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	if list.OwnerID != request.OwnerID {
+		return errorspkg.ErrorNoPermission
+	}
+
+	return sql.ErrNoRows
+}
+
+// Members returns the users on a list seen by viewerID, the most recently added first
+func (l *listRepo) Members(ctx context.Context, listID, viewerID string) (entity.ListUser, error) {
+	if _, err := l.GetList(ctx, listID, viewerID); err != nil {
+		return entity.ListUser{}, err
+	}
+
+	query := `
+	SELECT
+		u.id,
+		u.name,
+		u.username,
+		u.email,
+		u.role,
+		u.bio,
+		u.profile_picture
+	FROM
+	    users AS u
+	INNER JOIN
+	    list_members AS m ON u.id = m.user_id
+	WHERE
+	    u.deleted_at IS NULL AND m.list_id = $1
+	ORDER BY
+	    m.created_at DESC
+	`
+
+	rows, err := l.db.Query(ctx, query, listID)
+	if err != nil {
+		return entity.ListUser{}, err
+	}
+	defer rows.Close()
+
+	var response entity.ListUser
+	for rows.Next() {
+		var user entity.GetUserResponse
+		err := rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Username,
+			&user.Email,
+			&user.Role,
+			&user.Bio,
+			&user.ProfilePicture,
+		)
+		if err != nil {
+			return entity.ListUser{}, err
+		}
+
+		response.Users = append(response.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return entity.ListUser{}, err
+	}
+
+	response.Count = len(response.Users)
+
+	return response, nil
+}
+
+// Subscribe toggles the subscription of a user to a list they can see
+func (l *listRepo) Subscribe(ctx context.Context, listID, userID string) (bool, error) {
+	if _, err := l.GetList(ctx, listID, userID); err != nil {
+		return false, err
+	}
+
+	tx, err := l.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	insertQuery := `INSERT INTO list_subscriptions (list_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+	tag, err := tx.Exec(ctx, insertQuery, listID, userID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+
+	subscribed := tag.RowsAffected() == 1
+
+	if !subscribed {
+		deleteQuery := `DELETE FROM list_subscriptions WHERE list_id = $1 AND user_id = $2`
+
+		if _, err := tx.Exec(ctx, deleteQuery, listID, userID); err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return false, err
+			}
+			return false, err
+		}
+	}
+
+	return subscribed, tx.Commit(ctx)
+}
+
+// Timeline returns the tweets of the members of a list, newest first, leaving out what
+// the viewer is not allowed to see
+func (l *listRepo) Timeline(ctx context.Context, filter entity.ListTimelineFilter) (entity.TimelineResponse, error) {
+	if _, err := l.GetList(ctx, filter.ListID, filter.ViewerID); err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    tweets AS t
+	WHERE
+	    t.deleted_at IS NULL
+		AND t.user_id IN (SELECT user_id FROM list_members WHERE list_id = $1)
+		AND ($2::TIMESTAMP IS NULL OR (t.created_at, t.id) < ($2::TIMESTAMP, $3::UUID))
+		AND %s
+		AND %s
+	ORDER BY
+	    t.created_at DESC, t.id DESC
+	LIMIT $4
+	`, tweetColumns, visibleTo("t.user_id", "$5"), canSee("t.user_id", "$5"))
+
+	var (
+		cursorTime *time.Time
+		cursorID   *string
+	)
+	if filter.Cursor != nil {
+		cursorTime = &filter.Cursor.CreatedAt
+		cursorID = &filter.Cursor.ID
+	}
+
+	// one extra row tells whether there is a next page
+	rows, err := l.db.Query(ctx, query, filter.ListID, cursorTime, cursorID, filter.Limit+1, filter.ViewerID)
+	if err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	tweets, err := scanTweets(rows)
+	if err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	if err := hydrateTweets(ctx, l.db, filter.ViewerID, tweets); err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	return utils.NewTimelineResponse(tweets, filter.Limit), nil
+}
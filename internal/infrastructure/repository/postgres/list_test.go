@@ -0,0 +1,120 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newList saves a list of ownerID with memberIDs and returns its id
+func newList(t *testing.T, lists repo.ListStorageI, ownerID string, private bool, memberIDs ...string) string {
+	t.Helper()
+	ctx := context.Background()
+
+	list, err := lists.CreateList(ctx, entity.CreateListRequest{ID: uuid.NewString(), OwnerID: ownerID, Name: "list", IsPrivate: private})
+	require.NoError(t, err)
+
+	for _, memberID := range memberIDs {
+		require.NoError(t, lists.AddMember(ctx, entity.ListMemberRequest{ListID: list.ID, OwnerID: ownerID, UserID: memberID}))
+	}
+
+	return list.ID
+}
+
+func TestPrivateListIsOnlySeenByOwner(t *testing.T) {
+	db := testDB(t)
+	lists := postgresql.NewListRepo(db)
+	ctx := context.Background()
+
+	owner, other := newUser(t, db), newUser(t, db)
+	listID := newList(t, lists, owner, true)
+
+	_, err := lists.GetList(ctx, listID, owner)
+	assert.NoError(t, err)
+
+	_, err = lists.GetList(ctx, listID, other)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = lists.Subscribe(ctx, listID, other)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = lists.Timeline(ctx, entity.ListTimelineFilter{ListID: listID, ViewerID: other, Limit: 10})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestOnlyOwnerChangesList(t *testing.T) {
+	db := testDB(t)
+	lists := postgresql.NewListRepo(db)
+	ctx := context.Background()
+
+	owner, other, member, blocker := newUser(t, db), newUser(t, db), newUser(t, db), newUser(t, db)
+	listID := newList(t, lists, owner, false)
+
+	name := "renamed"
+	_, err := lists.UpdateList(ctx, entity.UpdateListRequest{ID: listID, OwnerID: other, Name: &name})
+	assert.ErrorIs(t, err, errorspkg.ErrorNoPermission)
+
+	err = lists.AddMember(ctx, entity.ListMemberRequest{ListID: listID, OwnerID: other, UserID: member})
+	assert.ErrorIs(t, err, errorspkg.ErrorNoPermission)
+
+	err = lists.DeleteList(ctx, listID, other)
+	assert.ErrorIs(t, err, errorspkg.ErrorNoPermission)
+
+	block(t, db, blocker, owner)
+	err = lists.AddMember(ctx, entity.ListMemberRequest{ListID: listID, OwnerID: owner, UserID: blocker})
+	assert.ErrorIs(t, err, errorspkg.ErrorBlocked)
+}
+
+func TestSubscribeToggles(t *testing.T) {
+	db := testDB(t)
+	lists := postgresql.NewListRepo(db)
+	ctx := context.Background()
+
+	owner, subscriber := newUser(t, db), newUser(t, db)
+	listID := newList(t, lists, owner, false)
+
+	for _, want := range []bool{true, false} {
+		subscribed, err := lists.Subscribe(ctx, listID, subscriber)
+		require.NoError(t, err)
+		assert.Equal(t, want, subscribed)
+	}
+}
+
+func TestListTimelinePagesVisibleMemberTweets(t *testing.T) {
+	db := testDB(t)
+	lists := postgresql.NewListRepo(db)
+	ctx := context.Background()
+
+	owner, viewer := newUser(t, db), newUser(t, db)
+	member, muted, protected, outsider := newUser(t, db), newUser(t, db), newProtectedUser(t, db), newUser(t, db)
+	listID := newList(t, lists, owner, false, member, muted, protected)
+	mute(t, db, viewer, muted)
+
+	first := newTweet(t, db, member)
+	newTweet(t, db, muted)
+	newTweet(t, db, protected)
+	newTweet(t, db, outsider)
+	second := newTweet(t, db, member)
+
+	page, err := lists.Timeline(ctx, entity.ListTimelineFilter{ListID: listID, ViewerID: viewer, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{second}, tweetIDs(page.Tweets))
+	require.NotEmpty(t, page.NextCursor)
+
+	cursor, err := utils.DecodeCursor(page.NextCursor)
+	require.NoError(t, err)
+
+	page, err = lists.Timeline(ctx, entity.ListTimelineFilter{ListID: listID, ViewerID: viewer, Cursor: cursor, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{first}, tweetIDs(page.Tweets))
+	assert.Empty(t, page.NextCursor)
+}
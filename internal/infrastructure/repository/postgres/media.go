@@ -0,0 +1,338 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/jackc/pgx/v4"
+)
+
+// attachMedia attaches the media of userID to a tweet created in tx, keeping their order, and
+// returns their URLs. Media of someone else, still pending or already attached to a tweet give ErrorNoPermission
+func attachMedia(ctx context.Context, tx pgx.Tx, tweetID, userID string, mediaIDs []string) ([]entity.MediaURLs, error) {
+	if len(mediaIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+	UPDATE
+		media
+	SET
+		tweet_id = $1
+	WHERE
+	    id = ANY($2::UUID[]) AND user_id = $3 AND status = 'ready' AND tweet_id IS NULL
+	RETURNING
+		id,
+		url,
+		urls
+	`
+
+	rows, err := tx.Query(ctx, query, tweetID, mediaIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	type attached struct {
+		url  string
+		urls entity.MediaURLs
+	}
+
+	media := make(map[string]attached, len(mediaIDs))
+	for rows.Next() {
+		var (
+			id   string
+			file attached
+		)
+		if err := rows.Scan(&id, &file.url, &file.urls); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		media[id] = file
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(media) != len(mediaIDs) {
+		return nil, errorspkg.ErrorNoPermission
+	}
+
+	// the files of a tweet share the ids of their media
+	fileQuery := `INSERT INTO files (id, tweet_id, file_url, position) VALUES ($1, $2, $3, $4)`
+
+	urls := make([]entity.MediaURLs, 0, len(mediaIDs))
+	for i, id := range mediaIDs {
+		if _, err := tx.Exec(ctx, fileQuery, id, tweetID, media[id].url, i); err != nil {
+			return nil, err
+		}
+
+		urls = append(urls, media[id].urls)
+	}
+
+	return urls, nil
+}
+
+type mediaRepo struct {
+	db *postgres.PostgresDB
+}
+
+func NewMediaRepo(db *postgres.PostgresDB) repo.MediaStorageI {
+	return &mediaRepo{
+		db: db,
+	}
+}
+
+// CreateMedia records an uploaded file owned by its uploader, or a pending direct upload
+func (m *mediaRepo) CreateMedia(ctx context.Context, media entity.Media) (entity.Media, error) {
+	query := `
+	INSERT INTO media (
+	    id,
+	    user_id,
+	    kind,
+	    content_type,
+	    size,
+	    status,
+	    purpose,
+	    conversation_id,
+	    storage_key,
+	    url,
+	    urls
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	RETURNING
+		created_at
+	`
+
+	err := m.db.QueryRow(
+		ctx,
+		query,
+		media.ID,
+		media.UserID,
+		media.Kind,
+		media.ContentType,
+		media.Size,
+		media.Status,
+		media.Purpose,
+		media.ConversationID,
+		media.Key,
+		media.URLs.Original,
+		media.URLs,
+	).Scan(&media.CreatedAt)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	return media, nil
+}
+
+// ViewableMedia returns a ready media the viewer may see, the viewer is empty for guests. Everyone
+// sees profile pictures, the members of a conversation its group avatars and the viewers of a
+// tweet its media, the way GetTweet lets them see the tweet
+func (m *mediaRepo) ViewableMedia(ctx context.Context, id, viewerID string) (entity.Media, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		m.id,
+		m.user_id,
+		m.kind,
+		m.content_type,
+		m.size,
+		m.status,
+		m.purpose,
+		m.conversation_id,
+		m.storage_key,
+		m.urls,
+		m.created_at
+	FROM
+	    media AS m
+	WHERE
+	    m.id = $1 AND m.status = 'ready' AND (
+	        m.user_id = %[1]s
+	        OR m.purpose = 'profile'
+	        OR (m.purpose = 'conversation' AND EXISTS (
+	            SELECT 1 FROM conversation_members WHERE conversation_id = m.conversation_id AND user_id = %[1]s
+	        ))
+	        OR EXISTS (
+	            SELECT 1 FROM tweets AS t
+	            WHERE t.id = m.tweet_id AND t.deleted_at IS NULL AND %[2]s AND %[3]s
+	        )
+	    )
+	`, optionalViewer("$2"), notBlocked("t.user_id", optionalViewer("$2")), canSee("t.user_id", optionalViewer("$2")))
+
+	var media entity.Media
+	err := m.db.QueryRow(ctx, query, id, viewerID).Scan(
+		&media.ID,
+		&media.UserID,
+		&media.Kind,
+		&media.ContentType,
+		&media.Size,
+		&media.Status,
+		&media.Purpose,
+		&media.ConversationID,
+		&media.Key,
+		&media.URLs,
+		&media.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Media{}, sql.ErrNoRows
+	}
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	return media, nil
+}
+
+// PendingMedia returns a direct upload of the user that was not completed yet
+func (m *mediaRepo) PendingMedia(ctx context.Context, userID, id string) (entity.Media, error) {
+	query := `
+	SELECT
+		id,
+		user_id,
+		kind,
+		content_type,
+		size,
+		status,
+		purpose,
+		conversation_id,
+		storage_key,
+		urls,
+		created_at
+	FROM
+	    media
+	WHERE
+	    id = $1 AND user_id = $2 AND status = 'pending'
+	`
+
+	var media entity.Media
+	err := m.db.QueryRow(ctx, query, id, userID).Scan(
+		&media.ID,
+		&media.UserID,
+		&media.Kind,
+		&media.ContentType,
+		&media.Size,
+		&media.Status,
+		&media.Purpose,
+		&media.ConversationID,
+		&media.Key,
+		&media.URLs,
+		&media.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Media{}, sql.ErrNoRows
+	}
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	return media, nil
+}
+
+// CompleteMedia makes a pending direct upload ready with the size, key and URLs it was
+// checked to have, an upload completed meanwhile gives sql.ErrNoRows
+func (m *mediaRepo) CompleteMedia(ctx context.Context, media entity.Media) (entity.Media, error) {
+	query := `
+	UPDATE
+		media
+	SET
+		size = $3,
+		storage_key = $4,
+		url = $5,
+		urls = $6,
+		status = 'ready'
+	WHERE
+	    id = $1 AND user_id = $2 AND status = 'pending'
+	`
+
+	tag, err := m.db.Exec(
+		ctx,
+		query,
+		media.ID,
+		media.UserID,
+		media.Size,
+		media.Key,
+		media.URLs.Original,
+		media.URLs,
+	)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return entity.Media{}, sql.ErrNoRows
+	}
+
+	media.Status = entity.MediaStatusReady
+
+	return media, nil
+}
+
+// StalePendingMedia returns up to limit direct uploads started before before and not completed,
+// the oldest first
+func (m *mediaRepo) StalePendingMedia(ctx context.Context, before time.Time, limit int) ([]entity.Media, error) {
+	query := `
+	SELECT
+		id,
+		user_id,
+		kind,
+		content_type,
+		size,
+		status,
+		purpose,
+		conversation_id,
+		storage_key,
+		urls,
+		created_at
+	FROM
+	    media
+	WHERE
+	    status = 'pending' AND created_at < $1
+	ORDER BY
+	    created_at
+	LIMIT $2
+	`
+
+	rows, err := m.db.Query(ctx, query, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stale []entity.Media
+	for rows.Next() {
+		var media entity.Media
+		if err := rows.Scan(
+			&media.ID,
+			&media.UserID,
+			&media.Kind,
+			&media.ContentType,
+			&media.Size,
+			&media.Status,
+			&media.Purpose,
+			&media.ConversationID,
+			&media.Key,
+			&media.URLs,
+			&media.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		stale = append(stale, media)
+	}
+
+	return stale, rows.Err()
+}
+
+// DeletePendingMedia drops a direct upload of the user that was not completed
+func (m *mediaRepo) DeletePendingMedia(ctx context.Context, userID, id string) error {
+	_, err := m.db.Exec(ctx, `DELETE FROM media WHERE id = $1 AND user_id = $2 AND status = 'pending'`, id, userID)
+
+	return err
+}
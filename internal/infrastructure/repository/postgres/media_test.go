@@ -0,0 +1,188 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMedia records an image of userID with the status given and returns it
+func newMedia(t *testing.T, files repo.MediaStorageI, userID, status string) entity.Media {
+	t.Helper()
+
+	id := uuid.NewString()
+	file, err := files.CreateMedia(context.Background(), entity.Media{
+		ID:          id,
+		UserID:      userID,
+		Kind:        entity.MediaKindImage,
+		ContentType: "image/png",
+		Size:        1,
+		Status:      status,
+		Purpose:     entity.MediaPurposeTweet,
+		Key:         id + "/original.png",
+		URLs:        entity.MediaURLs{Original: "/media/" + id + "/original.png"},
+	})
+	require.NoError(t, err)
+
+	return file
+}
+
+// tweetWithMedia posts a tweet of userID carrying mediaIDs
+func tweetWithMedia(tweets repo.TweetStorageI, userID string, mediaIDs ...string) (entity.CreateTweetResponse, error) {
+	content := "with media"
+
+	return tweets.CreateTweet(context.Background(), entity.CreateTweetRequest{
+		ID:       uuid.NewString(),
+		UserID:   userID,
+		Kind:     "tweet",
+		Content:  &content,
+		MediaIDs: mediaIDs,
+	})
+}
+
+func TestTweetAttachesOwnReadyMediaInOrder(t *testing.T) {
+	db := testDB(t)
+	files, tweets := postgresql.NewMediaRepo(db), postgresql.NewTweetRepo(db)
+
+	user := newUser(t, db)
+	first, second := newMedia(t, files, user, entity.MediaStatusReady), newMedia(t, files, user, entity.MediaStatusReady)
+
+	tweet, err := tweetWithMedia(tweets, user, second.ID, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.MediaURLs{second.URLs, first.URLs}, tweet.URLs)
+
+	saved, err := tweets.GetTweet(context.Background(), tweet.ID, user)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.MediaURLs{second.URLs, first.URLs}, saved.URLs)
+}
+
+func TestTweetCanNotAttachMediaItDoesNotOwn(t *testing.T) {
+	db := testDB(t)
+	files, tweets := postgresql.NewMediaRepo(db), postgresql.NewTweetRepo(db)
+
+	user, other := newUser(t, db), newUser(t, db)
+	mine := newMedia(t, files, user, entity.MediaStatusReady)
+
+	attached := newMedia(t, files, user, entity.MediaStatusReady)
+	_, err := tweetWithMedia(tweets, user, attached.ID)
+	require.NoError(t, err)
+
+	for name, id := range map[string]string{
+		"of another user":    newMedia(t, files, other, entity.MediaStatusReady).ID,
+		"still pending":      newMedia(t, files, user, entity.MediaStatusPending).ID,
+		"already on a tweet": attached.ID,
+		"missing":            uuid.NewString(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// the media of the user is not attached either, the tweet is not posted at all
+			_, err := tweetWithMedia(tweets, user, mine.ID, id)
+			assert.ErrorIs(t, err, errorspkg.ErrorNoPermission)
+		})
+	}
+
+	tweet, err := tweetWithMedia(tweets, user, mine.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.MediaURLs{mine.URLs}, tweet.URLs)
+}
+
+func TestStalePendingMediaOldestFirst(t *testing.T) {
+	db := testDB(t)
+	files := postgresql.NewMediaRepo(db)
+	ctx := context.Background()
+
+	user := newUser(t, db)
+	older, old := newMedia(t, files, user, entity.MediaStatusPending), newMedia(t, files, user, entity.MediaStatusPending)
+	recent := newMedia(t, files, user, entity.MediaStatusPending)
+	ready := newMedia(t, files, user, entity.MediaStatusReady)
+
+	exec(t, db, `UPDATE media SET created_at = created_at - INTERVAL '2 hours' WHERE id = $1`, older.ID)
+	exec(t, db, `UPDATE media SET created_at = created_at - INTERVAL '1 hour' WHERE id IN ($1, $2)`, old.ID, ready.ID)
+
+	before := older.CreatedAt.Add(-30 * time.Minute)
+
+	// uploads of earlier runs are stale as well, only the ones of user are looked at
+	stale, err := files.StalePendingMedia(ctx, before, 1000)
+	require.NoError(t, err)
+
+	var ids []string
+	for _, file := range stale {
+		assert.Equal(t, entity.MediaStatusPending, file.Status)
+		assert.True(t, file.CreatedAt.Before(before))
+
+		if file.UserID == user {
+			ids = append(ids, file.ID)
+		}
+	}
+	assert.Equal(t, []string{older.ID, old.ID}, ids)
+	assert.NotContains(t, ids, recent.ID)
+
+	stale, err = files.StalePendingMedia(ctx, before, 1)
+	require.NoError(t, err)
+	assert.Len(t, stale, 1)
+}
+
+func TestViewableMediaFollowsWhatViewerMaySee(t *testing.T) {
+	db := testDB(t)
+	files, tweets, messages := postgresql.NewMediaRepo(db), postgresql.NewTweetRepo(db), postgresql.NewMessageRepo(db)
+	ctx := context.Background()
+
+	author, protected := newUser(t, db), newProtectedUser(t, db)
+	follower, member, stranger, blocked := newUser(t, db), newUser(t, db), newUser(t, db), newUser(t, db)
+	const guest = ""
+
+	follow(t, db, follower, protected)
+	block(t, db, author, blocked)
+
+	attached := func(userID string) string {
+		file := newMedia(t, files, userID, entity.MediaStatusReady)
+		_, err := tweetWithMedia(tweets, userID, file.ID)
+		require.NoError(t, err)
+		return file.ID
+	}
+
+	public, protectedTweet := attached(author), attached(protected)
+	unattached, pending := newMedia(t, files, author, entity.MediaStatusReady).ID, newMedia(t, files, author, entity.MediaStatusPending).ID
+
+	profile := newMedia(t, files, author, entity.MediaStatusReady).ID
+	exec(t, db, `UPDATE media SET purpose = 'profile' WHERE id = $1`, profile)
+
+	conversation := group(t, messages, author, member)
+	avatar := newMedia(t, files, author, entity.MediaStatusReady).ID
+	exec(t, db, `UPDATE media SET purpose = 'conversation', conversation_id = $2 WHERE id = $1`, avatar, conversation.ID)
+
+	for name, tc := range map[string]struct {
+		media  string
+		allows []string
+		denies []string
+	}{
+		"public tweet":    {media: public, allows: []string{author, follower, stranger, guest}, denies: []string{blocked}},
+		"protected tweet": {media: protectedTweet, allows: []string{protected, follower}, denies: []string{stranger, blocked, guest}},
+		"not attached":    {media: unattached, allows: []string{author}, denies: []string{follower, stranger, guest}},
+		"pending":         {media: pending, denies: []string{author, stranger, guest}},
+		"profile picture": {media: profile, allows: []string{author, stranger, blocked, guest}},
+		"group avatar":    {media: avatar, allows: []string{author, member}, denies: []string{stranger, guest}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, viewer := range tc.allows {
+				found, err := files.ViewableMedia(ctx, tc.media, viewer)
+				if assert.NoError(t, err, "viewer %q", viewer) {
+					assert.Equal(t, tc.media, found.ID)
+				}
+			}
+
+			for _, viewer := range tc.denies {
+				_, err := files.ViewableMedia(ctx, tc.media, viewer)
+				assert.ErrorIs(t, err, sql.ErrNoRows, "viewer %q", viewer)
+			}
+		})
+	}
+}
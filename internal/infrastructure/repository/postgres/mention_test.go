@@ -0,0 +1,49 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMentionsAreReportedOnce(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	author := newUser(t, db)
+	friend := newUser(t, db)
+	blocked := newUser(t, db)
+	later := newUser(t, db)
+	block(t, db, blocked, author)
+
+	content := "hello"
+	created, err := tweets.CreateTweet(ctx, entity.CreateTweetRequest{
+		ID:       uuid.NewString(),
+		UserID:   author,
+		Kind:     entity.TweetKindTweet,
+		Content:  &content,
+		Mentions: []string{username(t, db, author), username(t, db, friend), username(t, db, blocked), "nobody_at_all"},
+	})
+	require.NoError(t, err)
+
+	// authors mentioning themselves and users with a block are skipped
+	assert.Equal(t, []string{friend}, created.MentionedIDs)
+
+	updated, err := tweets.UpdateTweet(ctx, entity.UpdateTweetRequest{
+		ID:       created.ID,
+		Content:  "edited",
+		Mentions: []string{username(t, db, friend), username(t, db, later)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{later}, updated.MentionedIDs)
+
+	page, err := tweets.MentionTweets(ctx, entity.TimelineFilter{UserID: friend, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{created.ID}, tweetIDs(page.Tweets))
+}
@@ -0,0 +1,623 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/jackc/pgx/v4"
+)
+
+// conversationColumns is the select list of a conversation seen by the member $1, the
+// conversation must be aliased as c and the membership of $1 as me
+const conversationColumns = `
+		c.id,
+		c.kind,
+		c.title,
+		c.avatar_url,
+		me.muted AND (me.muted_until IS NULL OR me.muted_until > NOW()),
+		CASE WHEN me.muted THEN me.muted_until END,
+		c.last_message_at,
+		c.created_at,
+		(SELECT COUNT(*) FROM messages AS m
+		 WHERE m.conversation_id = c.id AND m.sender_id <> $1
+			AND (me.last_read_at IS NULL OR m.created_at > me.last_read_at))`
+
+// directKey identifies the direct conversation of two users whatever the order they are given in
+func directKey(userID, otherID string) string {
+	if userID > otherID {
+		userID, otherID = otherID, userID
+	}
+
+	return userID + ":" + otherID
+}
+
+// scanConversations reads all rows selected with conversationColumns
+func scanConversations(rows pgx.Rows) ([]entity.Conversation, error) {
+	defer rows.Close()
+
+	var conversations []entity.Conversation
+	for rows.Next() {
+		var conversation entity.Conversation
+		err := rows.Scan(
+			&conversation.ID,
+			&conversation.Kind,
+			&conversation.Title,
+			&conversation.AvatarURL,
+			&conversation.Muted,
+			&conversation.MutedUntil,
+			&conversation.LastMessageAt,
+			&conversation.CreatedAt,
+			&conversation.UnreadCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		conversations = append(conversations, conversation)
+	}
+
+	return conversations, rows.Err()
+}
+
+// attachConversationDetails loads the members and the last message of conversations, one query each
+func attachConversationDetails(ctx context.Context, db *postgres.PostgresDB, conversations []entity.Conversation) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(conversations))
+	byID := make(map[string]*entity.Conversation, len(conversations))
+	for i := range conversations {
+		ids = append(ids, conversations[i].ID)
+		byID[conversations[i].ID] = &conversations[i]
+	}
+
+	membersQuery := `
+	SELECT
+		conversation_id,
+		user_id,
+		role,
+		last_read_at
+	FROM
+	    conversation_members
+	WHERE
+	    conversation_id = ANY($1::UUID[])
+	ORDER BY
+	    joined_at, user_id
+	`
+
+	rows, err := db.Query(ctx, membersQuery, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			conversationID string
+			member         entity.ConversationMember
+		)
+		if err := rows.Scan(&conversationID, &member.UserID, &member.Role, &member.LastReadAt); err != nil {
+			return err
+		}
+
+		conversation := byID[conversationID]
+		conversation.Members = append(conversation.Members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	lastMessagesQuery := `
+	SELECT DISTINCT ON (conversation_id)
+		id,
+		conversation_id,
+		sender_id,
+		content,
+		created_at
+	FROM
+	    messages
+	WHERE
+	    conversation_id = ANY($1::UUID[])
+	ORDER BY
+	    conversation_id, created_at DESC, id DESC
+	`
+
+	messages, err := db.Query(ctx, lastMessagesQuery, ids)
+	if err != nil {
+		return err
+	}
+
+	lastMessages, err := scanMessages(messages)
+	if err != nil {
+		return err
+	}
+
+	for i := range lastMessages {
+		byID[lastMessages[i].ConversationID].LastMessage = &lastMessages[i]
+	}
+
+	return nil
+}
+
+// scanMessages reads all rows of messages selected in their column order
+func scanMessages(rows pgx.Rows) ([]entity.Message, error) {
+	defer rows.Close()
+
+	var messages []entity.Message
+	for rows.Next() {
+		var message entity.Message
+		err := rows.Scan(
+			&message.ID,
+			&message.ConversationID,
+			&message.SenderID,
+			&message.Content,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// otherMembers returns who has to hear about what userID did in a conversation,
+// members who muted it are left out unless includeMuted is set
+func otherMembers(ctx context.Context, tx pgx.Tx, conversationID, userID string, includeMuted bool) ([]string, error) {
+	query := `
+	SELECT
+		user_id
+	FROM
+	    conversation_members
+	WHERE
+	    conversation_id = $1 AND user_id <> $2
+		AND ($3 OR NOT muted OR muted_until <= NOW())
+	`
+
+	rows, err := tx.Query(ctx, query, conversationID, userID, includeMuted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// announce writes one event per recipient, each of them gets it on their own websockets
+func announce(ctx context.Context, tx pgx.Tx, conversationID, eventType, actorID string, recipients []string, payload interface{}) error {
+	for _, recipientID := range recipients {
+		err := writeOutbox(ctx, tx, entity.AggregateConversation, conversationID, eventType, actorID, recipientID, payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type messageRepo struct {
+	db *postgres.PostgresDB
+}
+
+func NewMessageRepo(db *postgres.PostgresDB) repo.MessageStorageI {
+	return &messageRepo{
+		db: db,
+	}
+}
+
+// StartConversation opens the direct conversation of two users and reports whether it was
+// created, starting it again returns the existing one, users with a block between them can not start one
+func (m *messageRepo) StartConversation(ctx context.Context, request entity.StartConversationRequest) (entity.Conversation, bool, error) {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return entity.Conversation{}, false, err
+	}
+
+	var exists int
+	err = tx.QueryRow(ctx, `SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL`, request.RecipientID).Scan(&exists)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Conversation{}, false, err
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.Conversation{}, false, sql.ErrNoRows
+		}
+		return entity.Conversation{}, false, err
+	}
+
+	blocked, err := blockedBetween(ctx, tx, request.UserID, request.RecipientID)
+	if err == nil && blocked {
+		err = errorspkg.ErrorBlocked
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Conversation{}, false, err
+		}
+		return entity.Conversation{}, false, err
+	}
+
+	insertQuery := `
+	INSERT INTO conversations (id, direct_key, created_by) VALUES ($1, $2, $3)
+	ON CONFLICT (direct_key) DO NOTHING
+	`
+
+	tag, err := tx.Exec(ctx, insertQuery, request.ID, directKey(request.UserID, request.RecipientID), request.UserID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Conversation{}, false, err
+		}
+		return entity.Conversation{}, false, err
+	}
+
+	created := tag.RowsAffected() == 1
+	conversationID := request.ID
+
+	if created {
+		membersQuery := `
+		INSERT INTO conversation_members (conversation_id, user_id)
+		SELECT $1, unnest($2::UUID[])
+		`
+
+		_, err := tx.Exec(ctx, membersQuery, conversationID, []string{request.UserID, request.RecipientID})
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return entity.Conversation{}, false, err
+			}
+			return entity.Conversation{}, false, err
+		}
+	} else {
+		query := `SELECT id FROM conversations WHERE direct_key = $1`
+
+		err := tx.QueryRow(ctx, query, directKey(request.UserID, request.RecipientID)).Scan(&conversationID)
+		if err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return entity.Conversation{}, false, err
+			}
+			return entity.Conversation{}, false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return entity.Conversation{}, false, err
+	}
+
+	conversation, err := m.GetConversation(ctx, conversationID, request.UserID)
+	if err != nil {
+		return entity.Conversation{}, false, err
+	}
+
+	return conversation, created, nil
+}
+
+// GetConversation returns a conversation to one of its members, anyone else gets sql.ErrNoRows
+func (m *messageRepo) GetConversation(ctx context.Context, id, userID string) (entity.Conversation, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    conversations AS c
+	INNER JOIN
+	    conversation_members AS me ON me.conversation_id = c.id AND me.user_id = $1
+	WHERE
+	    c.id = $2
+	`, conversationColumns)
+
+	rows, err := m.db.Query(ctx, query, userID, id)
+	if err != nil {
+		return entity.Conversation{}, err
+	}
+
+	conversations, err := scanConversations(rows)
+	if err != nil {
+		return entity.Conversation{}, err
+	}
+
+	if len(conversations) == 0 {
+		return entity.Conversation{}, sql.ErrNoRows
+	}
+
+	if err := attachConversationDetails(ctx, m.db, conversations); err != nil {
+		return entity.Conversation{}, err
+	}
+
+	return conversations[0], nil
+}
+
+// ListConversations returns the conversations of a user, the most recently active first
+func (m *messageRepo) ListConversations(ctx context.Context, filter entity.ConversationFilter) (entity.ConversationsResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    conversations AS c
+	INNER JOIN
+	    conversation_members AS me ON me.conversation_id = c.id AND me.user_id = $1
+	WHERE
+	    ($2::TIMESTAMP IS NULL OR (c.last_message_at, c.id) < ($2::TIMESTAMP, $3::UUID))
+	ORDER BY
+	    c.last_message_at DESC, c.id DESC
+	LIMIT $4
+	`, conversationColumns)
+
+	var (
+		cursorTime *time.Time
+		cursorID   *string
+	)
+	if filter.Cursor != nil {
+		cursorTime = &filter.Cursor.CreatedAt
+		cursorID = &filter.Cursor.ID
+	}
+
+	// one extra row tells whether there is a next page
+	rows, err := m.db.Query(ctx, query, filter.UserID, cursorTime, cursorID, filter.Limit+1)
+	if err != nil {
+		return entity.ConversationsResponse{}, err
+	}
+
+	conversations, err := scanConversations(rows)
+	if err != nil {
+		return entity.ConversationsResponse{}, err
+	}
+
+	var response entity.ConversationsResponse
+	if len(conversations) > filter.Limit {
+		conversations = conversations[:filter.Limit]
+		last := conversations[len(conversations)-1]
+		response.NextCursor = utils.EncodeCursor(entity.Cursor{
+			CreatedAt: last.LastMessageAt,
+			ID:        last.ID,
+		})
+	}
+
+	if err := attachConversationDetails(ctx, m.db, conversations); err != nil {
+		return entity.ConversationsResponse{}, err
+	}
+
+	response.Conversations = conversations
+
+	return response, nil
+}
+
+// SendMessage saves a message of a member and queues its delivery to the other members,
+// the sender has read the conversation up to their own message. Direct conversations
+// take no messages once one of the two users blocked the other
+func (m *messageRepo) SendMessage(ctx context.Context, request entity.SendMessageRequest) (entity.Message, error) {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return entity.Message{}, err
+	}
+
+	touchQuery := `
+	UPDATE
+		conversation_members
+	SET
+		last_read_at = NOW()
+	WHERE
+	    conversation_id = $1 AND user_id = $2
+	`
+
+	tag, err := tx.Exec(ctx, touchQuery, request.ConversationID, request.SenderID)
+	if err == nil && tag.RowsAffected() == 0 {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Message{}, err
+		}
+		return entity.Message{}, err
+	}
+
+	blockedQuery := `
+	SELECT EXISTS (
+		SELECT 1
+		FROM
+		    conversations AS c
+		INNER JOIN
+		    conversation_members AS o ON o.conversation_id = c.id AND o.user_id <> $2
+		INNER JOIN
+		    blocks AS b ON (b.user_id = $2 AND b.blocked_id = o.user_id) OR (b.user_id = o.user_id AND b.blocked_id = $2)
+		WHERE
+		    c.id = $1 AND c.kind = 'direct'
+	)
+	`
+
+	var blocked bool
+	err = tx.QueryRow(ctx, blockedQuery, request.ConversationID, request.SenderID).Scan(&blocked)
+	if err == nil && blocked {
+		err = errorspkg.ErrorBlocked
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Message{}, err
+		}
+		return entity.Message{}, err
+	}
+
+	insertQuery := `
+	INSERT INTO messages (
+	    id,
+	    conversation_id,
+	    sender_id,
+	    content
+	) VALUES ($1, $2, $3, $4)
+	RETURNING
+		id,
+		conversation_id,
+		sender_id,
+		content,
+		created_at
+	`
+
+	var message entity.Message
+	err = tx.QueryRow(ctx, insertQuery, request.ID, request.ConversationID, request.SenderID, request.Content).Scan(
+		&message.ID,
+		&message.ConversationID,
+		&message.SenderID,
+		&message.Content,
+		&message.CreatedAt,
+	)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Message{}, err
+		}
+		return entity.Message{}, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE conversations SET last_message_at = $2 WHERE id = $1`, message.ConversationID, message.CreatedAt); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Message{}, err
+		}
+		return entity.Message{}, err
+	}
+
+	recipients, err := otherMembers(ctx, tx, message.ConversationID, message.SenderID, false)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Message{}, err
+		}
+		return entity.Message{}, err
+	}
+
+	err = announce(ctx, tx, message.ConversationID, entity.EventTypeMessageCreated, message.SenderID, recipients, message)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Message{}, err
+		}
+		return entity.Message{}, err
+	}
+
+	return message, tx.Commit(ctx)
+}
+
+// ListMessages returns the messages of a conversation to one of its members, newest first
+func (m *messageRepo) ListMessages(ctx context.Context, filter entity.MessageFilter) (entity.MessagesResponse, error) {
+	var exists int
+	memberQuery := `SELECT 1 FROM conversation_members WHERE conversation_id = $1 AND user_id = $2`
+
+	if err := m.db.QueryRow(ctx, memberQuery, filter.ConversationID, filter.UserID).Scan(&exists); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.MessagesResponse{}, sql.ErrNoRows
+		}
+		return entity.MessagesResponse{}, err
+	}
+
+	query := `
+	SELECT
+		id,
+		conversation_id,
+		sender_id,
+		content,
+		created_at
+	FROM
+	    messages
+	WHERE
+	    conversation_id = $1
+		AND ($2::TIMESTAMP IS NULL OR (created_at, id) < ($2::TIMESTAMP, $3::UUID))
+	ORDER BY
+	    created_at DESC, id DESC
+	LIMIT $4
+	`
+
+	var (
+		cursorTime *time.Time
+		cursorID   *string
+	)
+	if filter.Cursor != nil {
+		cursorTime = &filter.Cursor.CreatedAt
+		cursorID = &filter.Cursor.ID
+	}
+
+	// one extra row tells whether there is a next page
+	rows, err := m.db.Query(ctx, query, filter.ConversationID, cursorTime, cursorID, filter.Limit+1)
+	if err != nil {
+		return entity.MessagesResponse{}, err
+	}
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return entity.MessagesResponse{}, err
+	}
+
+	var response entity.MessagesResponse
+	if len(messages) > filter.Limit {
+		messages = messages[:filter.Limit]
+		last := messages[len(messages)-1]
+		response.NextCursor = utils.EncodeCursor(entity.Cursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		})
+	}
+
+	response.Messages = messages
+
+	return response, nil
+}
+
+// MarkRead records that a member read the whole conversation and sends the receipt to the others
+func (m *messageRepo) MarkRead(ctx context.Context, conversationID, userID string) (entity.ReadReceipt, error) {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return entity.ReadReceipt{}, err
+	}
+
+	query := `
+	UPDATE
+		conversation_members
+	SET
+		last_read_at = NOW()
+	WHERE
+	    conversation_id = $1 AND user_id = $2
+	RETURNING
+		conversation_id,
+		user_id,
+		last_read_at
+	`
+
+	var receipt entity.ReadReceipt
+	err = tx.QueryRow(ctx, query, conversationID, userID).Scan(&receipt.ConversationID, &receipt.UserID, &receipt.ReadAt)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.ReadReceipt{}, err
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.ReadReceipt{}, sql.ErrNoRows
+		}
+		return entity.ReadReceipt{}, err
+	}
+
+	recipients, err := otherMembers(ctx, tx, conversationID, userID, true)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.ReadReceipt{}, err
+		}
+		return entity.ReadReceipt{}, err
+	}
+
+	if err := announce(ctx, tx, conversationID, entity.EventTypeMessageRead, userID, recipients, receipt); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.ReadReceipt{}, err
+		}
+		return entity.ReadReceipt{}, err
+	}
+
+	return receipt, tx.Commit(ctx)
+}
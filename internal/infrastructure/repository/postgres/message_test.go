@@ -0,0 +1,143 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conversation starts the direct conversation of two users and returns its id
+func conversation(t *testing.T, messages repo.MessageStorageI, userID, recipientID string) string {
+	t.Helper()
+
+	conversation, _, err := messages.StartConversation(context.Background(), entity.StartConversationRequest{
+		ID:          uuid.NewString(),
+		UserID:      userID,
+		RecipientID: recipientID,
+	})
+	require.NoError(t, err)
+
+	return conversation.ID
+}
+
+func send(t *testing.T, messages repo.MessageStorageI, conversationID, senderID string) entity.Message {
+	t.Helper()
+
+	message, err := messages.SendMessage(context.Background(), entity.SendMessageRequest{
+		ID:             uuid.NewString(),
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Content:        "hi",
+	})
+	require.NoError(t, err)
+
+	return message
+}
+
+func TestStartConversationReturnsExistingOne(t *testing.T) {
+	db := testDB(t)
+	messages := postgresql.NewMessageRepo(db)
+	ctx := context.Background()
+
+	alice, bob := newUser(t, db), newUser(t, db)
+
+	first, created, err := messages.StartConversation(ctx, entity.StartConversationRequest{ID: uuid.NewString(), UserID: alice, RecipientID: bob})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	second, created, err := messages.StartConversation(ctx, entity.StartConversationRequest{ID: uuid.NewString(), UserID: bob, RecipientID: alice})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first.ID, second.ID)
+}
+
+func TestBlockedUsersCanNotMessage(t *testing.T) {
+	db := testDB(t)
+	messages := postgresql.NewMessageRepo(db)
+	ctx := context.Background()
+
+	alice, bob, carol := newUser(t, db), newUser(t, db), newUser(t, db)
+	block(t, db, bob, alice)
+
+	_, _, err := messages.StartConversation(ctx, entity.StartConversationRequest{ID: uuid.NewString(), UserID: alice, RecipientID: bob})
+	assert.ErrorIs(t, err, errorspkg.ErrorBlocked)
+
+	conversationID := conversation(t, messages, alice, carol)
+	block(t, db, carol, alice)
+
+	_, err = messages.SendMessage(ctx, entity.SendMessageRequest{ID: uuid.NewString(), ConversationID: conversationID, SenderID: alice, Content: "hi"})
+	assert.ErrorIs(t, err, errorspkg.ErrorBlocked)
+}
+
+func TestConversationIsHiddenFromNonMembers(t *testing.T) {
+	db := testDB(t)
+	messages := postgresql.NewMessageRepo(db)
+	ctx := context.Background()
+
+	alice, bob, stranger := newUser(t, db), newUser(t, db), newUser(t, db)
+	conversationID := conversation(t, messages, alice, bob)
+	send(t, messages, conversationID, alice)
+
+	_, err := messages.GetConversation(ctx, conversationID, stranger)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = messages.ListMessages(ctx, entity.MessageFilter{ConversationID: conversationID, UserID: stranger, Limit: 10})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = messages.SendMessage(ctx, entity.SendMessageRequest{ID: uuid.NewString(), ConversationID: conversationID, SenderID: stranger, Content: "hi"})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = messages.MarkRead(ctx, conversationID, stranger)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	response, err := messages.ListConversations(ctx, entity.ConversationFilter{UserID: stranger, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, response.Conversations)
+}
+
+func TestMarkReadSendsReceiptToOtherMembers(t *testing.T) {
+	db := testDB(t)
+	messages := postgresql.NewMessageRepo(db)
+	ctx := context.Background()
+
+	alice, bob := newUser(t, db), newUser(t, db)
+	conversationID := conversation(t, messages, alice, bob)
+	send(t, messages, conversationID, alice)
+	send(t, messages, conversationID, alice)
+
+	unread, err := messages.GetConversation(ctx, conversationID, bob)
+	require.NoError(t, err)
+	assert.Equal(t, 2, unread.UnreadCount)
+
+	receipt, err := messages.MarkRead(ctx, conversationID, bob)
+	require.NoError(t, err)
+	assert.Equal(t, bob, receipt.UserID)
+
+	read, err := messages.GetConversation(ctx, conversationID, bob)
+	require.NoError(t, err)
+	assert.Zero(t, read.UnreadCount)
+
+	// the sender sees when the recipient read the conversation
+	sent, err := messages.GetConversation(ctx, conversationID, alice)
+	require.NoError(t, err)
+	for _, member := range sent.Members {
+		if member.UserID == bob {
+			require.NotNil(t, member.LastReadAt)
+			assert.WithinDuration(t, receipt.ReadAt, *member.LastReadAt, 0)
+		}
+	}
+
+	var receipts int
+	err = db.QueryRow(ctx, `SELECT COUNT(*) FROM outbox WHERE aggregate_id = $1 AND event_type = $2 AND event->>'subject_id' = $3`,
+		conversationID, entity.EventTypeMessageRead, alice).Scan(&receipts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, receipts)
+}
@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type notificationRepo struct {
+	db *postgres.PostgresDB
+}
+
+func NewNotificationRepo(db *postgres.PostgresDB) repo.NotificationStorageI {
+	return &notificationRepo{
+		db: db,
+	}
+}
+
+// insertNotification saves a notification with its notification.created event in tx and reports
+// whether it was saved, the event is addressed to the recipient so it reaches their open websockets.
+// Notifications from actors the recipient blocked or muted, or who blocked the recipient, are dropped,
+// and so is a repeated one of an actor about the same tweet, or about none, like the one of a like
+// taken back and given again. The unique index on them keeps concurrent repeats out as well
+func insertNotification(ctx context.Context, tx pgx.Tx, notification entity.Notification) (entity.Notification, bool, error) {
+	query := fmt.Sprintf(`
+	INSERT INTO notifications (
+	    id,
+	    user_id,
+	    actor_id,
+	    type,
+	    tweet_id
+	)
+	SELECT $1::UUID, $2::UUID, $3::UUID, $4::VARCHAR, $5::UUID
+	WHERE %s
+	ON CONFLICT (user_id, actor_id, type, tweet_id) DO NOTHING
+	RETURNING
+		created_at
+	`, visibleTo("$3", "$2"))
+
+	err := tx.QueryRow(
+		ctx,
+		query,
+		notification.ID,
+		notification.UserID,
+		notification.ActorID,
+		notification.Type,
+		notification.TweetID,
+	).Scan(&notification.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return notification, false, nil
+	}
+	if err != nil {
+		return entity.Notification{}, false, err
+	}
+
+	err = writeOutbox(
+		ctx,
+		tx,
+		entity.AggregateUser,
+		notification.UserID,
+		entity.EventTypeNotificationCreated,
+		notification.ActorID,
+		notification.UserID,
+		notification,
+	)
+	if err != nil {
+		return entity.Notification{}, false, err
+	}
+
+	return notification, true, nil
+}
+
+// notify saves a notification of the action actorID took in tx, users are not notified of their own actions
+func notify(ctx context.Context, tx pgx.Tx, userID, actorID, kind string, tweetID *string) error {
+	if userID == actorID {
+		return nil
+	}
+
+	_, _, err := insertNotification(ctx, tx, entity.Notification{
+		ID:      uuid.NewString(),
+		UserID:  userID,
+		ActorID: actorID,
+		Type:    kind,
+		TweetID: tweetID,
+	})
+	return err
+}
+
+// notifyTweet notifies the author of the tweet a new tweet answers and the users it mentions
+func notifyTweet(ctx context.Context, tx pgx.Tx, response entity.CreateTweetResponse) error {
+	if response.ParentTweetID != nil {
+		var parentUserID string
+		err := tx.QueryRow(ctx, `SELECT user_id FROM tweets WHERE id = $1`, *response.ParentTweetID).Scan(&parentUserID)
+		if err != nil {
+			return err
+		}
+
+		if err := notify(ctx, tx, parentUserID, response.UserID, entity.NotificationTypeReply, &response.ID); err != nil {
+			return err
+		}
+	}
+
+	return notifyMentions(ctx, tx, response.UserID, response.ID, response.MentionedIDs)
+}
+
+// notifyMentions notifies every user a tweet mentions for the first time
+func notifyMentions(ctx context.Context, tx pgx.Tx, authorID, tweetID string, userIDs []string) error {
+	for _, userID := range userIDs {
+		if err := notify(ctx, tx, userID, authorID, entity.NotificationTypeMention, &tweetID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Create saves a notification unless the recipient does not want to hear from the actor
+func (n *notificationRepo) Create(ctx context.Context, notification entity.Notification) (entity.Notification, error) {
+	tx, err := n.db.Begin(ctx)
+	if err != nil {
+		return entity.Notification{}, err
+	}
+
+	notification, saved, err := insertNotification(ctx, tx, notification)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Notification{}, err
+		}
+		return entity.Notification{}, err
+	}
+
+	if !saved {
+		return notification, tx.Rollback(ctx)
+	}
+
+	return notification, tx.Commit(ctx)
+}
+
+// List returns the notifications of a user newest first with the number of unread ones,
+// leaving out the ones from actors blocked or muted since
+func (n *notificationRepo) List(ctx context.Context, filter entity.NotificationFilter) (entity.NotificationsResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		id,
+		user_id,
+		actor_id,
+		type,
+		tweet_id,
+		read_at IS NOT NULL,
+		created_at
+	FROM
+	    notifications
+	WHERE
+	    user_id = $1
+		AND ($2::TIMESTAMP IS NULL OR (created_at, id) < ($2::TIMESTAMP, $3::UUID))
+		AND %s
+	ORDER BY
+	    created_at DESC, id DESC
+	LIMIT $4
+	`, visibleTo("actor_id", "$1"))
+
+	var (
+		cursorTime *time.Time
+		cursorID   *string
+	)
+	if filter.Cursor != nil {
+		cursorTime = &filter.Cursor.CreatedAt
+		cursorID = &filter.Cursor.ID
+	}
+
+	// one extra row tells whether there is a next page
+	rows, err := n.db.Query(ctx, query, filter.UserID, cursorTime, cursorID, filter.Limit+1)
+	if err != nil {
+		return entity.NotificationsResponse{}, err
+	}
+	defer rows.Close()
+
+	var response entity.NotificationsResponse
+	for rows.Next() {
+		var notification entity.Notification
+		err := rows.Scan(
+			&notification.ID,
+			&notification.UserID,
+			&notification.ActorID,
+			&notification.Type,
+			&notification.TweetID,
+			&notification.Read,
+			&notification.CreatedAt,
+		)
+		if err != nil {
+			return entity.NotificationsResponse{}, err
+		}
+
+		response.Notifications = append(response.Notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return entity.NotificationsResponse{}, err
+	}
+
+	if len(response.Notifications) > filter.Limit {
+		response.Notifications = response.Notifications[:filter.Limit]
+		last := response.Notifications[len(response.Notifications)-1]
+		response.NextCursor = utils.EncodeCursor(entity.Cursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		})
+	}
+
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL AND %s`, visibleTo("actor_id", "$1"))
+	if err := n.db.QueryRow(ctx, countQuery, filter.UserID).Scan(&response.UnreadCount); err != nil {
+		return entity.NotificationsResponse{}, err
+	}
+
+	return response, nil
+}
+
+func (n *notificationRepo) MarkRead(ctx context.Context, request entity.ReadNotificationsRequest) error {
+	query := `
+	UPDATE
+		notifications
+	SET
+		read_at = NOW()
+	WHERE
+	    user_id = $1
+		AND read_at IS NULL
+		AND (COALESCE(cardinality($2::UUID[]), 0) = 0 OR id = ANY($2::UUID[]))
+	`
+
+	_, err := n.db.Exec(ctx, query, request.UserID, request.IDs)
+	return err
+}
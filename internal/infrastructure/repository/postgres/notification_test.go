@@ -0,0 +1,145 @@
+package postgres_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func notify(t *testing.T, notifications repo.NotificationStorageI, userID, actorID, kind string, tweetID *string) {
+	t.Helper()
+
+	_, err := notifications.Create(context.Background(), entity.Notification{
+		ID:      uuid.NewString(),
+		UserID:  userID,
+		ActorID: actorID,
+		Type:    kind,
+		TweetID: tweetID,
+	})
+	require.NoError(t, err)
+}
+
+func TestNotificationOfRepeatedLikeIsSavedOnce(t *testing.T) {
+	db := testDB(t)
+	notifications := postgresql.NewNotificationRepo(db)
+	ctx := context.Background()
+
+	author, fan := newUser(t, db), newUser(t, db)
+	tweetID := newTweet(t, db, author)
+
+	notify(t, notifications, author, fan, entity.NotificationTypeLike, &tweetID)
+	notify(t, notifications, author, fan, entity.NotificationTypeLike, &tweetID)
+	notify(t, notifications, author, fan, entity.NotificationTypeRetweet, &tweetID)
+	notify(t, notifications, author, fan, entity.NotificationTypeFollow, nil)
+	notify(t, notifications, author, fan, entity.NotificationTypeFollow, nil)
+
+	response, err := notifications.List(ctx, entity.NotificationFilter{UserID: author, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, response.Notifications, 4)
+	assert.Equal(t, 4, response.UnreadCount)
+}
+
+func TestConcurrentRepeatedNotificationIsSavedOnce(t *testing.T) {
+	db := testDB(t)
+	notifications := postgresql.NewNotificationRepo(db)
+
+	author, fan := newUser(t, db), newUser(t, db)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			notify(t, notifications, author, fan, entity.NotificationTypeFollow, nil)
+		}()
+	}
+	wg.Wait()
+
+	response, err := notifications.List(context.Background(), entity.NotificationFilter{UserID: author, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, response.Notifications, 1)
+}
+
+func TestActionsNotifyWithTheirMutation(t *testing.T) {
+	db := testDB(t)
+	notifications := postgresql.NewNotificationRepo(db)
+	likes, follows := postgresql.NewLikeRepo(db), postgresql.NewFollowRepo(db)
+	ctx := context.Background()
+
+	author, fan := newUser(t, db), newUser(t, db)
+	tweetID := newTweet(t, db, author)
+
+	_, err := follows.Follow(ctx, entity.FollowAction{UserID: fan, FollowingID: author})
+	require.NoError(t, err)
+	_, err = likes.Like(ctx, entity.LikeAction{UserID: fan, TweetID: tweetID})
+	require.NoError(t, err)
+
+	// liking one's own tweet notifies no one
+	_, err = likes.Like(ctx, entity.LikeAction{UserID: author, TweetID: tweetID})
+	require.NoError(t, err)
+
+	response, err := notifications.List(ctx, entity.NotificationFilter{UserID: author, Limit: 10})
+	require.NoError(t, err)
+
+	var kinds []string
+	for _, notification := range response.Notifications {
+		assert.Equal(t, fan, notification.ActorID)
+		kinds = append(kinds, notification.Type)
+	}
+	assert.ElementsMatch(t, []string{entity.NotificationTypeFollow, entity.NotificationTypeLike}, kinds)
+}
+
+func TestNotificationFromBlockedActorIsDropped(t *testing.T) {
+	db := testDB(t)
+	notifications := postgresql.NewNotificationRepo(db)
+
+	author, blocked := newUser(t, db), newUser(t, db)
+	tweetID := newTweet(t, db, author)
+	block(t, db, author, blocked)
+
+	notify(t, notifications, author, blocked, entity.NotificationTypeLike, &tweetID)
+
+	response, err := notifications.List(context.Background(), entity.NotificationFilter{UserID: author, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, response.Notifications)
+}
+
+func TestMarkReadWithoutIDsMarksAll(t *testing.T) {
+	db := testDB(t)
+	notifications := postgresql.NewNotificationRepo(db)
+	ctx := context.Background()
+
+	author := newUser(t, db)
+	first, second := newUser(t, db), newUser(t, db)
+	notify(t, notifications, author, first, entity.NotificationTypeFollow, nil)
+	notify(t, notifications, author, second, entity.NotificationTypeFollow, nil)
+
+	response, err := notifications.List(ctx, entity.NotificationFilter{UserID: author, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, response.Notifications, 2)
+
+	require.NoError(t, notifications.MarkRead(ctx, entity.ReadNotificationsRequest{
+		UserID: author,
+		IDs:    []string{response.Notifications[0].ID},
+	}))
+
+	response, err = notifications.List(ctx, entity.NotificationFilter{UserID: author, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.UnreadCount)
+
+	require.NoError(t, notifications.MarkRead(ctx, entity.ReadNotificationsRequest{UserID: author}))
+
+	response, err = notifications.List(ctx, entity.NotificationFilter{UserID: author, Limit: 10})
+	require.NoError(t, err)
+	assert.Zero(t, response.UnreadCount)
+	for _, notification := range response.Notifications {
+		assert.True(t, notification.Read)
+	}
+}
@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// outboxRelayLock is the advisory lock held by the one relay publishing at a time,
+	// a single publisher is what keeps the events of an aggregate in order
+	outboxRelayLock = 7_236_001
+	// outboxMaxBackoffSeconds caps the delay between two attempts of a failing event
+	outboxMaxBackoffSeconds = 300
+	// outboxLeaseSeconds is how long a claimed event is left to its relay, an event not
+	// marked by then is claimed again
+	outboxLeaseSeconds = 60
+)
+
+// writeOutbox records an event in the transaction of the mutation it describes,
+// the event is published by the relay once the transaction committed
+func writeOutbox(ctx context.Context, tx pgx.Tx, aggregateType, aggregateID, eventType, actorID, subjectID string, payload interface{}) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	event := entity.Event{
+		ID:        uuid.NewString(),
+		Version:   entity.EventVersion,
+		Type:      eventType,
+		ActorID:   actorID,
+		SubjectID: subjectID,
+		Timestamp: time.Now().UTC(),
+		Payload:   raw,
+	}
+
+	query := `
+	INSERT INTO outbox (
+	    id,
+	    aggregate_type,
+	    aggregate_id,
+	    event_type,
+	    event
+	) VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err = tx.Exec(ctx, query, event.ID, aggregateType, aggregateID, event.Type, event)
+	return err
+}
+
+type outboxRepo struct {
+	db *postgres.PostgresDB
+}
+
+func NewOutboxRepo(db *postgres.PostgresDB) repo.OutboxStorageI {
+	return &outboxRepo{
+		db: db,
+	}
+}
+
+// Claim leases up to limit due events to the caller, oldest first. A leased event holds
+// back the later events of its aggregate until it is marked or its lease ran out, so the
+// events of an aggregate stay in order while they are published outside a transaction.
+// It returns nothing while another relay is claiming
+func (o *outboxRepo) Claim(ctx context.Context, limit int) ([]entity.OutboxMessage, error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxRelayLock).Scan(&locked); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	if !locked {
+		return nil, tx.Rollback(ctx)
+	}
+
+	query := `
+	UPDATE
+		outbox
+	SET
+		next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+	WHERE
+	    seq IN (
+			SELECT o.seq FROM outbox AS o
+			WHERE o.published_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM outbox AS b
+					WHERE b.aggregate_id = o.aggregate_id AND b.published_at IS NULL
+						AND b.seq <= o.seq AND b.next_attempt_at > NOW()
+				)
+			ORDER BY o.seq
+			LIMIT $1
+		)
+	RETURNING
+		seq,
+		aggregate_id,
+		attempts,
+		event
+	`
+
+	rows, err := tx.Query(ctx, query, limit, outboxLeaseSeconds)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	var messages []entity.OutboxMessage
+	for rows.Next() {
+		var message entity.OutboxMessage
+		if err := rows.Scan(&message.Seq, &message.AggregateID, &message.Attempts, &message.Event); err != nil {
+			rows.Close()
+			if err := tx.Rollback(ctx); err != nil {
+				return nil, err
+			}
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	// RETURNING does not keep the order of the subquery
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Seq < messages[j].Seq
+	})
+
+	return messages, tx.Commit(ctx)
+}
+
+// MarkPublished records that the claimed events went out
+func (o *outboxRepo) MarkPublished(ctx context.Context, seqs []int64) error {
+	if len(seqs) == 0 {
+		return nil
+	}
+
+	query := `UPDATE outbox SET published_at = NOW(), last_error = NULL WHERE seq = ANY($1)`
+
+	_, err := o.db.Exec(ctx, query, seqs)
+	return err
+}
+
+// MarkFailed records a failed attempt, the event is retried with an exponential backoff
+func (o *outboxRepo) MarkFailed(ctx context.Context, seq int64, reason string) error {
+	query := `
+	UPDATE
+		outbox
+	SET
+		attempts = attempts + 1,
+		last_error = $2,
+		next_attempt_at = NOW() + LEAST(POWER(2, attempts), $3) * INTERVAL '1 second'
+	WHERE
+	    seq = $1
+	`
+
+	_, err := o.db.Exec(ctx, query, seq, reason, outboxMaxBackoffSeconds)
+	return err
+}
+
+// Processed reports whether group already handled the event of an idempotency key
+func (o *outboxRepo) Processed(ctx context.Context, group, key string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM processed_events WHERE consumer_group = $1 AND idempotency_key = $2)`
+
+	var processed bool
+	err := o.db.QueryRow(ctx, query, group, key).Scan(&processed)
+	return processed, err
+}
+
+// MarkProcessed stores the idempotency key of an event handled by group and reports
+// whether it was new, an event whose key is known was delivered before
+func (o *outboxRepo) MarkProcessed(ctx context.Context, group, key string) (bool, error) {
+	query := `
+	INSERT INTO processed_events (
+	    consumer_group,
+	    idempotency_key
+	) VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`
+
+	result, err := o.db.Exec(ctx, query, group, key)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// Prune deletes the events published and the keys processed before the given time
+func (o *outboxRepo) Prune(ctx context.Context, before time.Time) (int64, error) {
+	events, err := o.db.Exec(ctx, `DELETE FROM outbox WHERE published_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	keys, err := o.db.Exec(ctx, `DELETE FROM processed_events WHERE processed_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return events.RowsAffected() + keys.RowsAffected(), nil
+}
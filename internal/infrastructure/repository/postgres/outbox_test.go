@@ -0,0 +1,138 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEvent saves a due outbox event of aggregateID and returns its id
+func newEvent(t *testing.T, db *postgres.PostgresDB, aggregateID string) string {
+	t.Helper()
+
+	id := uuid.NewString()
+	exec(t, db, `INSERT INTO outbox (id, aggregate_type, aggregate_id, event_type, event) VALUES ($1, 'tweet', $2, 'tweet.created', jsonb_build_object('id', $1::TEXT))`, id, aggregateID)
+
+	return id
+}
+
+// claimedOf lists the ids of the claimed events of aggregateID, other tests may have
+// left events of their own in the outbox
+func claimedOf(messages []entity.OutboxMessage, aggregateID string) ([]string, []int64) {
+	var (
+		ids  []string
+		seqs []int64
+	)
+	for _, message := range messages {
+		if message.AggregateID == aggregateID {
+			ids = append(ids, message.Event.ID)
+			seqs = append(seqs, message.Seq)
+		}
+	}
+
+	return ids, seqs
+}
+
+func TestOutboxClaimLeasesEventsInOrder(t *testing.T) {
+	db := testDB(t)
+	outbox := postgresql.NewOutboxRepo(db)
+	ctx := context.Background()
+
+	aggregateID := uuid.NewString()
+	first, second := newEvent(t, db, aggregateID), newEvent(t, db, aggregateID)
+
+	messages, err := outbox.Claim(ctx, 1000)
+	require.NoError(t, err)
+	ids, seqs := claimedOf(messages, aggregateID)
+	assert.Equal(t, []string{first, second}, ids)
+
+	// leased events are not claimed twice
+	messages, err = outbox.Claim(ctx, 1000)
+	require.NoError(t, err)
+	ids, _ = claimedOf(messages, aggregateID)
+	assert.Empty(t, ids)
+
+	require.NoError(t, outbox.MarkPublished(ctx, seqs))
+
+	var unpublished int
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM outbox WHERE aggregate_id = $1 AND published_at IS NULL`, aggregateID).Scan(&unpublished))
+	assert.Zero(t, unpublished)
+}
+
+func TestOutboxFailedEventHoldsBackItsAggregate(t *testing.T) {
+	db := testDB(t)
+	outbox := postgresql.NewOutboxRepo(db)
+	ctx := context.Background()
+
+	aggregateID := uuid.NewString()
+	newEvent(t, db, aggregateID)
+	messages, err := outbox.Claim(ctx, 1000)
+	require.NoError(t, err)
+	_, seqs := claimedOf(messages, aggregateID)
+	require.Len(t, seqs, 1)
+
+	require.NoError(t, outbox.MarkFailed(ctx, seqs[0], "broker unavailable"))
+	newEvent(t, db, aggregateID)
+
+	messages, err = outbox.Claim(ctx, 1000)
+	require.NoError(t, err)
+	ids, _ := claimedOf(messages, aggregateID)
+	assert.Empty(t, ids)
+}
+
+func TestOutboxMarkProcessedOncePerGroup(t *testing.T) {
+	db := testDB(t)
+	outbox := postgresql.NewOutboxRepo(db)
+	ctx := context.Background()
+
+	key := uuid.NewString()
+	processed, err := outbox.Processed(ctx, "test", key)
+	require.NoError(t, err)
+	assert.False(t, processed)
+
+	for _, want := range []bool{true, false} {
+		first, err := outbox.MarkProcessed(ctx, "test", key)
+		require.NoError(t, err)
+		assert.Equal(t, want, first)
+	}
+
+	processed, err = outbox.Processed(ctx, "test", key)
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	first, err := outbox.MarkProcessed(ctx, "other", key)
+	require.NoError(t, err)
+	assert.True(t, first)
+}
+
+func TestOutboxPruneKeepsUnpublishedEvents(t *testing.T) {
+	db := testDB(t)
+	outbox := postgresql.NewOutboxRepo(db)
+	ctx := context.Background()
+
+	published, pending := uuid.NewString(), uuid.NewString()
+	newEvent(t, db, published)
+	newEvent(t, db, pending)
+	exec(t, db, `UPDATE outbox SET published_at = NOW() - INTERVAL '2 days' WHERE aggregate_id = $1`, published)
+
+	_, err := outbox.Prune(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+
+	var left []string
+	rows, err := db.Query(ctx, `SELECT aggregate_id::TEXT FROM outbox WHERE aggregate_id = ANY($1)`, []string{published, pending})
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		left = append(left, id)
+	}
+	assert.Equal(t, []string{pending}, left)
+}
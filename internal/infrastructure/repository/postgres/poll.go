@@ -0,0 +1,314 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// savePoll attaches a poll to a tweet created in tx, the counts of a new poll are hidden from its author
+// like from everyone else who did not vote
+func savePoll(ctx context.Context, tx pgx.Tx, tweetID string, request entity.PollRequest) (*entity.Poll, error) {
+	poll := entity.Poll{}
+
+	pollQuery := `INSERT INTO polls (tweet_id, ends_at) VALUES ($1, NOW() + make_interval(mins => $2)) RETURNING ends_at`
+
+	if err := tx.QueryRow(ctx, pollQuery, tweetID, request.DurationMinutes).Scan(&poll.EndsAt); err != nil {
+		return nil, err
+	}
+
+	optionQuery := `INSERT INTO poll_options (id, tweet_id, position, label) VALUES ($1, $2, $3, $4)`
+
+	for i, label := range request.Options {
+		option := entity.PollOption{
+			ID:       uuid.NewString(),
+			Position: i + 1,
+			Label:    label,
+		}
+
+		if _, err := tx.Exec(ctx, optionQuery, option.ID, tweetID, option.Position, option.Label); err != nil {
+			return nil, err
+		}
+
+		poll.Options = append(poll.Options, option)
+	}
+
+	return &poll, nil
+}
+
+// pollsByTweetIDs reads the polls of the tweets among ids as seen by viewerID, the counts
+// are only filled in once the viewer voted or the poll closed
+func pollsByTweetIDs(ctx context.Context, db *postgres.PostgresDB, ids []string, viewerID string) (map[string]*entity.Poll, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		o.tweet_id,
+		p.ends_at,
+		p.closed_at IS NOT NULL OR p.ends_at <= NOW(),
+		v.option_id,
+		o.id,
+		o.position,
+		o.label,
+		o.vote_count
+	FROM
+	    poll_options AS o
+	INNER JOIN
+	    polls AS p ON p.tweet_id = o.tweet_id
+	LEFT JOIN
+	    poll_votes AS v ON v.tweet_id = o.tweet_id AND v.user_id = %s
+	WHERE
+	    o.tweet_id = ANY($1::UUID[])
+	ORDER BY
+	    o.tweet_id, o.position
+	`, optionalViewer("$2"))
+
+	rows, err := db.Query(ctx, query, ids, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	polls := make(map[string]*entity.Poll)
+	for rows.Next() {
+		var (
+			tweetID string
+			count   int
+			option  entity.PollOption
+			poll    entity.Poll
+		)
+		err := rows.Scan(
+			&tweetID,
+			&poll.EndsAt,
+			&poll.Closed,
+			&poll.VotedOptionID,
+			&option.ID,
+			&option.Position,
+			&option.Label,
+			&count,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := polls[tweetID]; !ok {
+			polls[tweetID] = &poll
+		}
+		current := polls[tweetID]
+
+		if current.Closed || current.VotedOptionID != nil {
+			option.VoteCount = &count
+
+			if current.TotalVotes == nil {
+				current.TotalVotes = new(int)
+			}
+			*current.TotalVotes += count
+		}
+
+		current.Options = append(current.Options, option)
+	}
+
+	return polls, rows.Err()
+}
+
+// attachPolls embeds the polls of the tweets and their originals as seen by viewerID
+func attachPolls(ctx context.Context, db *postgres.PostgresDB, viewerID string, tweets []entity.GetTweetResponse) error {
+	var ids []string
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.ID)
+		if tweet.Original != nil {
+			ids = append(ids, tweet.Original.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	polls, err := pollsByTweetIDs(ctx, db, ids, viewerID)
+	if err != nil {
+		return err
+	}
+
+	for i := range tweets {
+		tweets[i].Poll = polls[tweets[i].ID]
+		if tweets[i].Original != nil {
+			tweets[i].Original.Poll = polls[tweets[i].Original.ID]
+		}
+	}
+
+	return nil
+}
+
+type pollRepo struct {
+	db *postgres.PostgresDB
+}
+
+func NewPollRepo(db *postgres.PostgresDB) repo.PollStorageI {
+	return &pollRepo{
+		db: db,
+	}
+}
+
+// Vote records the single vote of a user in the poll of a tweet they can see and returns
+// the poll with its counts. Unknown tweets and options give sql.ErrNoRows
+func (p *pollRepo) Vote(ctx context.Context, vote entity.PollVote) (entity.Poll, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return entity.Poll{}, err
+	}
+
+	pollQuery := fmt.Sprintf(`
+	SELECT
+		p.closed_at IS NOT NULL OR p.ends_at <= NOW(),
+		EXISTS (SELECT 1 FROM poll_options WHERE id = $3 AND tweet_id = p.tweet_id)
+	FROM
+	    polls AS p
+	INNER JOIN
+	    tweets AS t ON t.id = p.tweet_id
+	WHERE
+	    p.tweet_id = $1
+		AND t.deleted_at IS NULL
+		AND %s
+		AND %s
+	`, notBlocked("t.user_id", "$2::UUID"), canSee("t.user_id", "$2::UUID"))
+
+	var closed, known bool
+	err = tx.QueryRow(ctx, pollQuery, vote.TweetID, vote.UserID, vote.OptionID).Scan(&closed, &known)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !known) {
+		err = sql.ErrNoRows
+	}
+	if err == nil && closed {
+		err = errorspkg.ErrorPollClosed
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Poll{}, err
+		}
+		return entity.Poll{}, err
+	}
+
+	voteQuery := `INSERT INTO poll_votes (tweet_id, user_id, option_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+
+	tag, err := tx.Exec(ctx, voteQuery, vote.TweetID, vote.UserID, vote.OptionID)
+	if err == nil && tag.RowsAffected() == 0 {
+		err = errorspkg.ErrorConflict
+	}
+	if err == nil {
+		_, err = tx.Exec(ctx, `UPDATE poll_options SET vote_count = vote_count + 1 WHERE id = $1`, vote.OptionID)
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.Poll{}, err
+		}
+		return entity.Poll{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return entity.Poll{}, err
+	}
+
+	polls, err := pollsByTweetIDs(ctx, p.db, []string{vote.TweetID}, vote.UserID)
+	if err != nil {
+		return entity.Poll{}, err
+	}
+
+	poll, ok := polls[vote.TweetID]
+	if !ok {
+		return entity.Poll{}, sql.ErrNoRows
+	}
+
+	return *poll, nil
+}
+
+// ClosePolls finalizes up to limit polls whose time ran out and notifies the author of each
+// tweet still around. Polls being closed by another closer are skipped, it returns the number closed
+func (p *pollRepo) ClosePolls(ctx context.Context, limit int) (int, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+	UPDATE
+		polls AS p
+	SET
+		closed_at = NOW()
+	FROM
+	    tweets AS t
+	WHERE
+	    t.id = p.tweet_id
+		AND p.tweet_id IN (
+			SELECT tweet_id FROM polls
+			WHERE closed_at IS NULL AND ends_at <= NOW()
+			ORDER BY ends_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+	RETURNING
+		p.tweet_id,
+		t.user_id,
+		t.deleted_at IS NULL
+	`
+
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return 0, err
+		}
+		return 0, err
+	}
+
+	var (
+		closed        int
+		notifications []entity.Notification
+	)
+	for rows.Next() {
+		var (
+			tweetID, userID string
+			live            bool
+		)
+		if err := rows.Scan(&tweetID, &userID, &live); err != nil {
+			rows.Close()
+			if err := tx.Rollback(ctx); err != nil {
+				return 0, err
+			}
+			return 0, err
+		}
+
+		closed++
+		if live {
+			notifications = append(notifications, entity.Notification{
+				ID:      uuid.NewString(),
+				UserID:  userID,
+				ActorID: userID,
+				Type:    entity.NotificationTypePollClosed,
+				TweetID: &tweetID,
+			})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return 0, err
+		}
+		return 0, err
+	}
+
+	for _, notification := range notifications {
+		if _, _, err := insertNotification(ctx, tx, notification); err != nil {
+			if err := tx.Rollback(ctx); err != nil {
+				return 0, err
+			}
+			return 0, err
+		}
+	}
+
+	return closed, tx.Commit(ctx)
+}
@@ -0,0 +1,107 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPoll saves a tweet of userID with a two option poll and returns it
+func newPoll(t *testing.T, tweets repo.TweetStorageI, userID string) entity.CreateTweetResponse {
+	t.Helper()
+
+	content := "which one"
+	tweet, err := tweets.CreateTweet(context.Background(), entity.CreateTweetRequest{
+		ID:      uuid.NewString(),
+		UserID:  userID,
+		Kind:    entity.TweetKindTweet,
+		Content: &content,
+		Poll:    &entity.PollRequest{Options: []string{"yes", "no"}, DurationMinutes: 60},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, tweet.Poll)
+
+	return tweet
+}
+
+func TestPollCountsShowAfterVoting(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	polls := postgresql.NewPollRepo(db)
+	ctx := context.Background()
+
+	author, voter := newUser(t, db), newUser(t, db)
+	tweet := newPoll(t, tweets, author)
+	yes := tweet.Poll.Options[0].ID
+
+	before, err := tweets.GetTweet(ctx, tweet.ID, voter)
+	require.NoError(t, err)
+	require.NotNil(t, before.Poll)
+	assert.Nil(t, before.Poll.TotalVotes)
+
+	poll, err := polls.Vote(ctx, entity.PollVote{TweetID: tweet.ID, UserID: voter, OptionID: yes})
+	require.NoError(t, err)
+	require.NotNil(t, poll.TotalVotes)
+	assert.Equal(t, 1, *poll.TotalVotes)
+	assert.Equal(t, yes, *poll.VotedOptionID)
+
+	_, err = polls.Vote(ctx, entity.PollVote{TweetID: tweet.ID, UserID: voter, OptionID: tweet.Poll.Options[1].ID})
+	assert.ErrorIs(t, err, errorspkg.ErrorConflict)
+
+	_, err = polls.Vote(ctx, entity.PollVote{TweetID: tweet.ID, UserID: author, OptionID: uuid.NewString()})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestProtectedPollTakesNoVotesFromStrangers(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	polls := postgresql.NewPollRepo(db)
+
+	author, stranger := newProtectedUser(t, db), newUser(t, db)
+	tweet := newPoll(t, tweets, author)
+
+	_, err := polls.Vote(context.Background(), entity.PollVote{TweetID: tweet.ID, UserID: stranger, OptionID: tweet.Poll.Options[0].ID})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestClosePollsFinalizesExpiredPollsOnce(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	polls := postgresql.NewPollRepo(db)
+	notifications := postgresql.NewNotificationRepo(db)
+	ctx := context.Background()
+
+	author, voter := newUser(t, db), newUser(t, db)
+	tweet := newPoll(t, tweets, author)
+	exec(t, db, `UPDATE polls SET ends_at = NOW() - INTERVAL '1 minute' WHERE tweet_id = $1`, tweet.ID)
+
+	_, err := polls.Vote(ctx, entity.PollVote{TweetID: tweet.ID, UserID: voter, OptionID: tweet.Poll.Options[0].ID})
+	assert.ErrorIs(t, err, errorspkg.ErrorPollClosed)
+
+	// other tests may have left expired polls behind, closing runs until none is left
+	for {
+		closed, err := polls.ClosePolls(ctx, 100)
+		require.NoError(t, err)
+		if closed == 0 {
+			break
+		}
+	}
+
+	closed, err := tweets.GetTweet(ctx, tweet.ID, voter)
+	require.NoError(t, err)
+	assert.True(t, closed.Poll.Closed)
+	require.NotNil(t, closed.Poll.TotalVotes)
+
+	response, err := notifications.List(ctx, entity.NotificationFilter{UserID: author, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, response.Notifications, 1)
+	assert.Equal(t, entity.NotificationTypePollClosed, response.Notifications[0].Type)
+}
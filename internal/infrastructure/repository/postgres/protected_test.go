@@ -0,0 +1,157 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtectedTweetsNeedApprovedFollow(t *testing.T) {
+	db := testDB(t)
+	follows := postgresql.NewFollowRepo(db)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	author, reader := newProtectedUser(t, db), newUser(t, db)
+	tweetID := newTweet(t, db, author)
+
+	response, err := follows.Follow(ctx, entity.FollowAction{UserID: reader, FollowingID: author})
+	require.NoError(t, err)
+	assert.True(t, response.Requested)
+
+	_, err = tweets.GetTweet(ctx, tweetID, reader)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	found, err := tweets.GetTweetsByIDs(ctx, []string{tweetID}, reader)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	require.NoError(t, follows.AnswerFollowRequest(ctx, entity.FollowRequestAction{UserID: author, RequesterID: reader}, true))
+
+	_, err = tweets.GetTweet(ctx, tweetID, reader)
+	assert.NoError(t, err)
+}
+
+func TestProtectedTweetsCanNotBeRetweetedOrQuoted(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	author, follower, stranger := newProtectedUser(t, db), newUser(t, db), newUser(t, db)
+	follow(t, db, follower, author)
+	tweetID := newTweet(t, db, author)
+
+	_, err := retweet(t, tweets, stranger, tweetID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	content := "quoting"
+	_, err = tweets.CreateTweet(ctx, entity.CreateTweetRequest{
+		ID:              uuid.NewString(),
+		UserID:          stranger,
+		Kind:            entity.TweetKindQuote,
+		OriginalTweetID: &tweetID,
+		Content:         &content,
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// a follower's retweet does not open the tweet to the followers of the follower
+	repost, err := retweet(t, tweets, follower, tweetID)
+	require.NoError(t, err)
+
+	_, err = retweet(t, tweets, stranger, repost.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	shown, err := tweets.GetTweet(ctx, repost.ID, stranger)
+	require.NoError(t, err)
+	assert.Nil(t, shown.Original)
+}
+
+func TestProtectedTweetsCanOnlyBeAnsweredByFollowers(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	author, follower, stranger := newProtectedUser(t, db), newUser(t, db), newUser(t, db)
+	follow(t, db, follower, author)
+	tweetID := newTweet(t, db, author)
+
+	reply := func(userID string) error {
+		content := "answering"
+		_, err := tweets.CreateTweet(ctx, entity.CreateTweetRequest{
+			ID:            uuid.NewString(),
+			UserID:        userID,
+			Kind:          entity.TweetKindReply,
+			ParentTweetID: &tweetID,
+			Content:       &content,
+		})
+		return err
+	}
+
+	assert.ErrorIs(t, reply(stranger), sql.ErrNoRows)
+	require.NoError(t, reply(follower))
+	require.NoError(t, reply(author))
+
+	parent, err := tweets.GetTweet(ctx, tweetID, author)
+	require.NoError(t, err)
+	assert.Equal(t, 2, parent.ReplyCount)
+}
+
+func TestThreadHidesProtectedAndBlockedTweets(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	viewer, public, protected, blocker := newUser(t, db), newUser(t, db), newProtectedUser(t, db), newUser(t, db)
+	block(t, db, blocker, viewer)
+
+	root := newTweet(t, db, protected)
+	middle := newReply(t, db, public, root)
+	focal := newReply(t, db, public, middle)
+
+	shown := newReply(t, db, public, focal)
+	newReply(t, db, protected, focal)
+	newReply(t, db, blocker, focal)
+	nested := newReply(t, db, public, shown)
+	newReply(t, db, protected, shown)
+	newReply(t, db, blocker, shown)
+
+	thread, err := tweets.Thread(ctx, entity.ThreadFilter{TweetID: focal, ViewerID: viewer, Depth: 2, Limit: 10})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{middle}, nodeIDs(thread.Ancestors))
+	assert.Equal(t, []string{shown}, nodeIDs(thread.Tweet.Replies))
+	assert.Equal(t, []string{nested}, nodeIDs(thread.Tweet.Replies[0].Replies))
+
+	_, err = tweets.Thread(ctx, entity.ThreadFilter{TweetID: newTweet(t, db, blocker), ViewerID: viewer, Depth: 1, Limit: 10})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGoingPublicAcceptsPendingFollowRequests(t *testing.T) {
+	db := testDB(t)
+	follows := postgresql.NewFollowRepo(db)
+	users := postgresql.NewUserRepo(db)
+	ctx := context.Background()
+
+	author, requester := newProtectedUser(t, db), newUser(t, db)
+
+	_, err := follows.Follow(ctx, entity.FollowAction{UserID: requester, FollowingID: author})
+	require.NoError(t, err)
+
+	name := username(t, db, author)
+	require.NoError(t, users.Update(ctx, entity.UpdateUserRequest{ID: author, Name: "Test User", Username: name, IsProtected: false}))
+
+	requests, err := follows.FollowRequests(ctx, author)
+	require.NoError(t, err)
+	assert.Zero(t, requests.Count)
+
+	followers, err := follows.GetFollowers(ctx, author)
+	require.NoError(t, err)
+	require.Len(t, followers.Users, 1)
+	assert.Equal(t, requester, followers.Users[0].ID)
+}
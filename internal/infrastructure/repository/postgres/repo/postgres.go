@@ -2,6 +2,8 @@ package repo
 
 import (
 	"context"
+	"time"
+
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
 )
 
@@ -11,7 +13,7 @@ type UserStorageI interface {
 	Create(ctx context.Context, user entity.CreateUserRequest) (entity.CreateUserResponse, error)
 	Update(ctx context.Context, user entity.UpdateUserRequest) error
 	UpdatePasswd(ctx context.Context, id string, passwd string) error
-	UploadImage(ctx context.Context, id string, url string) error
+	UploadImage(ctx context.Context, id string, picture entity.MediaURLs) error
 	Delete(ctx context.Context, id string) error
 	Get(ctx context.Context, field map[string]interface{}) (entity.GetUserResponse, error)
 	List(ctx context.Context, filter entity.Filter) (entity.ListUser, error)
@@ -21,21 +23,118 @@ type TweetStorageI interface {
 	CreateTweet(ctx context.Context, tweet entity.CreateTweetRequest) (entity.CreateTweetResponse, error)
 	UpdateTweet(ctx context.Context, tweet entity.UpdateTweetRequest) (entity.UpdateTweetResponse, error)
 	DeleteTweet(ctx context.Context, id string) error
-	GetTweet(ctx context.Context, id string) (entity.GetTweetResponse, error)
-	ListTweets(ctx context.Context, filter entity.Filter) (entity.ListTweetsResponse, error)
-	UserTweets(ctx context.Context, userID string) (entity.ListTweetsResponse, error)
+	Retweet(ctx context.Context, retweet entity.RetweetAction) (entity.CreateTweetResponse, error)
+	UndoRetweet(ctx context.Context, retweet entity.RetweetAction) error
+	GetTweet(ctx context.Context, id, viewerID string) (entity.GetTweetResponse, error)
+	TweetHistory(ctx context.Context, id, viewerID string) (entity.TweetHistoryResponse, error)
+	ListTweets(ctx context.Context, filter entity.Filter, viewerID string) (entity.ListTweetsResponse, error)
+	UserTweets(ctx context.Context, userID, viewerID string) (entity.ListTweetsResponse, error)
+	HomeTimeline(ctx context.Context, filter entity.TimelineFilter) (entity.TimelineResponse, error)
+	Thread(ctx context.Context, filter entity.ThreadFilter) (entity.ThreadResponse, error)
+	HashtagTweets(ctx context.Context, filter entity.HashtagFilter) (entity.TimelineResponse, error)
+	MentionTweets(ctx context.Context, filter entity.TimelineFilter) (entity.TimelineResponse, error)
+	GetTweetsByIDs(ctx context.Context, ids []string, viewerID string) ([]entity.GetTweetResponse, error)
+	TimelineEntries(ctx context.Context, filter entity.TimelineEntriesFilter) ([]entity.TimelineEntry, error)
 }
 
 type SearchStorageI interface {
-	Search(ctx context.Context, data string) (entity.SearchResponse, error)
+	Search(ctx context.Context, data, viewerID string) (entity.SearchResponse, error)
 }
 
 type LikeStorageI interface {
 	Like(ctx context.Context, like entity.LikeAction) (bool, error)
 }
 
+type BookmarkStorageI interface {
+	Bookmark(ctx context.Context, bookmark entity.BookmarkAction) (bool, error)
+	RemoveBookmark(ctx context.Context, userID, tweetID string) error
+	Bookmarks(ctx context.Context, filter entity.BookmarkFilter) (entity.TimelineResponse, error)
+	CreateFolder(ctx context.Context, request entity.CreateBookmarkFolderRequest) (entity.BookmarkFolder, error)
+	Folders(ctx context.Context, userID string) (entity.BookmarkFoldersResponse, error)
+	DeleteFolder(ctx context.Context, userID, folderID string) error
+}
+
+type MediaStorageI interface {
+	CreateMedia(ctx context.Context, media entity.Media) (entity.Media, error)
+	ViewableMedia(ctx context.Context, id, viewerID string) (entity.Media, error)
+	PendingMedia(ctx context.Context, userID, id string) (entity.Media, error)
+	CompleteMedia(ctx context.Context, media entity.Media) (entity.Media, error)
+	DeletePendingMedia(ctx context.Context, userID, id string) error
+	StalePendingMedia(ctx context.Context, before time.Time, limit int) ([]entity.Media, error)
+}
+
+type DraftStorageI interface {
+	CreateDraft(ctx context.Context, request entity.DraftRequest) (entity.Draft, error)
+	UpdateDraft(ctx context.Context, request entity.DraftRequest) (entity.Draft, error)
+	DeleteDraft(ctx context.Context, userID, id string) error
+	Drafts(ctx context.Context, userID string, scheduled bool) (entity.DraftsResponse, error)
+	PublishDraft(ctx context.Context, userID, id string, prepare func(*entity.CreateTweetRequest)) (entity.CreateTweetResponse, error)
+	PublishDue(ctx context.Context, limit int, prepare func(*entity.CreateTweetRequest)) ([]entity.CreateTweetResponse, error)
+}
+
+type PollStorageI interface {
+	Vote(ctx context.Context, vote entity.PollVote) (entity.Poll, error)
+	ClosePolls(ctx context.Context, limit int) (int, error)
+}
+
+type ListStorageI interface {
+	CreateList(ctx context.Context, request entity.CreateListRequest) (entity.List, error)
+	GetList(ctx context.Context, id, viewerID string) (entity.List, error)
+	Lists(ctx context.Context, userID string) (entity.ListsResponse, error)
+	UpdateList(ctx context.Context, request entity.UpdateListRequest) (entity.List, error)
+	DeleteList(ctx context.Context, id, ownerID string) error
+	AddMember(ctx context.Context, request entity.ListMemberRequest) error
+	RemoveMember(ctx context.Context, request entity.ListMemberRequest) error
+	Members(ctx context.Context, listID, viewerID string) (entity.ListUser, error)
+	Subscribe(ctx context.Context, listID, userID string) (bool, error)
+	Timeline(ctx context.Context, filter entity.ListTimelineFilter) (entity.TimelineResponse, error)
+}
+
 type FollowStorageI interface {
-	Follow(ctx context.Context, follow entity.FollowAction) (bool, error)
+	Follow(ctx context.Context, follow entity.FollowAction) (entity.FollowResponse, error)
+	FollowRequests(ctx context.Context, id string) (entity.ListUser, error)
+	AnswerFollowRequest(ctx context.Context, request entity.FollowRequestAction, approve bool) error
 	GetFollowings(ctx context.Context, id string) (entity.ListUser, error)
 	GetFollowers(ctx context.Context, id string) (entity.ListUser, error)
+	FollowerIDs(ctx context.Context, id string, limit int) ([]string, error)
+	FollowingIDs(ctx context.Context, id string) ([]string, error)
+	HeavyFollowings(ctx context.Context, id string, threshold int) ([]string, error)
+}
+
+type BlockStorageI interface {
+	Block(ctx context.Context, block entity.BlockAction) (bool, error)
+	Mute(ctx context.Context, mute entity.MuteAction) (bool, error)
+	Blocked(ctx context.Context, id string) (entity.ListUser, error)
+	Muted(ctx context.Context, id string) (entity.ListUser, error)
+}
+
+type NotificationStorageI interface {
+	Create(ctx context.Context, notification entity.Notification) (entity.Notification, error)
+	List(ctx context.Context, filter entity.NotificationFilter) (entity.NotificationsResponse, error)
+	MarkRead(ctx context.Context, request entity.ReadNotificationsRequest) error
+}
+
+type OutboxStorageI interface {
+	Claim(ctx context.Context, limit int) ([]entity.OutboxMessage, error)
+	MarkPublished(ctx context.Context, seqs []int64) error
+	MarkFailed(ctx context.Context, seq int64, reason string) error
+	Processed(ctx context.Context, group, key string) (bool, error)
+	MarkProcessed(ctx context.Context, group, key string) (bool, error)
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+type MessageStorageI interface {
+	StartConversation(ctx context.Context, request entity.StartConversationRequest) (entity.Conversation, bool, error)
+	GetConversation(ctx context.Context, id, userID string) (entity.Conversation, error)
+	ListConversations(ctx context.Context, filter entity.ConversationFilter) (entity.ConversationsResponse, error)
+	SendMessage(ctx context.Context, request entity.SendMessageRequest) (entity.Message, error)
+	ListMessages(ctx context.Context, filter entity.MessageFilter) (entity.MessagesResponse, error)
+	MarkRead(ctx context.Context, conversationID, userID string) (entity.ReadReceipt, error)
+	Mute(ctx context.Context, request entity.MuteConversationRequest) error
+	CreateGroup(ctx context.Context, request entity.CreateGroupRequest) (entity.Conversation, error)
+	UpdateGroup(ctx context.Context, request entity.UpdateGroupRequest) (entity.Conversation, error)
+	AddMembers(ctx context.Context, request entity.GroupMembersRequest) (entity.Conversation, error)
+	RemoveMember(ctx context.Context, request entity.GroupMemberRequest) error
+	SetMemberRole(ctx context.Context, request entity.GroupMemberRequest) error
+	LeaveGroup(ctx context.Context, conversationID, userID string) error
 }
@@ -0,0 +1,105 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func retweet(t *testing.T, tweets repo.TweetStorageI, userID, tweetID string) (entity.CreateTweetResponse, error) {
+	t.Helper()
+
+	return tweets.Retweet(context.Background(), entity.RetweetAction{ID: uuid.NewString(), UserID: userID, TweetID: tweetID})
+}
+
+func TestRetweetsAndQuotesPointAtTheOriginal(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	author := newUser(t, db)
+	first := newUser(t, db)
+	second := newUser(t, db)
+	original := newTweet(t, db, author)
+
+	repost, err := retweet(t, tweets, first, original)
+	require.NoError(t, err)
+	assert.Equal(t, original, *repost.OriginalTweetID)
+
+	// a retweet of a retweet reposts the original
+	again, err := retweet(t, tweets, second, repost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, original, *again.OriginalTweetID)
+
+	_, err = retweet(t, tweets, first, original)
+	assert.ErrorIs(t, err, errorspkg.ErrorConflict)
+
+	content := "quoting"
+	quote, err := tweets.CreateTweet(ctx, entity.CreateTweetRequest{
+		ID:              uuid.NewString(),
+		UserID:          second,
+		Kind:            entity.TweetKindQuote,
+		OriginalTweetID: &repost.ID,
+		Content:         &content,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, original, *quote.OriginalTweetID)
+
+	tweet, err := tweets.GetTweet(ctx, original, author)
+	require.NoError(t, err)
+	assert.Equal(t, 2, tweet.RetweetCount)
+	assert.Equal(t, 1, tweet.QuoteCount)
+
+	quoted, err := tweets.GetTweet(ctx, quote.ID, author)
+	require.NoError(t, err)
+	require.NotNil(t, quoted.Original)
+	assert.Equal(t, original, quoted.Original.ID)
+}
+
+func TestUndoRetweetOfDeletedTweet(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	author := newUser(t, db)
+	user := newUser(t, db)
+	original := newTweet(t, db, author)
+
+	_, err := retweet(t, tweets, user, original)
+	require.NoError(t, err)
+
+	require.NoError(t, tweets.DeleteTweet(ctx, original))
+
+	assert.NoError(t, tweets.UndoRetweet(ctx, entity.RetweetAction{UserID: user, TweetID: original}))
+	assert.ErrorIs(t, tweets.UndoRetweet(ctx, entity.RetweetAction{UserID: user, TweetID: original}), sql.ErrNoRows)
+}
+
+func TestOnlyTweetsAndQuotesAreEditable(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	author := newUser(t, db)
+	tweet := newTweet(t, db, author)
+	reply := newReply(t, db, author, tweet)
+
+	repost, err := retweet(t, tweets, author, tweet)
+	require.NoError(t, err)
+
+	for _, id := range []string{reply, repost.ID} {
+		_, err := tweets.UpdateTweet(ctx, entity.UpdateTweetRequest{ID: id, Content: "edited"})
+		assert.ErrorIs(t, err, errorspkg.ErrorConflict)
+	}
+
+	updated, err := tweets.UpdateTweet(ctx, entity.UpdateTweetRequest{ID: tweet, Content: "edited"})
+	require.NoError(t, err)
+	assert.Equal(t, "edited", *updated.Content)
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
 	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
-	"github.com/lib/pq"
 )
 
 type searchRepo struct {
@@ -20,8 +19,9 @@ func NewSearchRepo(db *postgres.PostgresDB) repo.SearchStorageI {
 	}
 }
 
-// Search method for searching users or tweets with text
-func (s *searchRepo) Search(ctx context.Context, data string) (entity.SearchResponse, error) {
+// Search method for searching users or tweets with text, users blocked or muted by the viewer are left out
+// and tweets of protected accounts only show to their approved followers
+func (s *searchRepo) Search(ctx context.Context, data, viewerID string) (entity.SearchResponse, error) {
 	var response entity.SearchResponse
 
 	searchUsers := fmt.Sprintf(`
@@ -38,10 +38,11 @@ func (s *searchRepo) Search(ctx context.Context, data string) (entity.SearchResp
 	WHERE
 	    deleted_at IS NULL
 		AND role = 'user'
-		AND name ILIKE '%s'
-	`, "%"+data+"%")
+		AND name ILIKE $1
+		AND %s
+	`, visibleTo("id", optionalViewer("$2")))
 
-	userRows, err := s.db.Query(ctx, searchUsers)
+	userRows, err := s.db.Query(ctx, searchUsers, "%"+data+"%", viewerID)
 	if err != nil {
 		return entity.SearchResponse{}, err
 	}
@@ -67,44 +68,25 @@ func (s *searchRepo) Search(ctx context.Context, data string) (entity.SearchResp
 	}
 
 	searchTweets := fmt.Sprintf(`
-	SELECT
-		id,
-		user_id,
-		parent_tweet_id,
-		content,
-		COALESCE((SELECT array_agg(file_url) FROM files WHERE tweet_id = id AND deleted_at IS NULL), '{}')
+	SELECT %s
 	FROM
-		tweets
+		tweets AS t
 	WHERE
-		deleted_at IS NULL AND content ILIKE '%s'
-	`, "%"+data+"%")
+		t.deleted_at IS NULL AND t.content ILIKE $1 AND %s AND %s
+	`, tweetColumns, visibleTo("t.user_id", optionalViewer("$2")), canSee("t.user_id", optionalViewer("$2")))
 
-	tweetRows, err := s.db.Query(ctx, searchTweets)
+	tweetRows, err := s.db.Query(ctx, searchTweets, "%"+data+"%", viewerID)
 	if err != nil {
 		return entity.SearchResponse{}, err
 	}
-	defer tweetRows.Close()
-
-	for tweetRows.Next() {
-		var (
-			urls  []string
-			tweet entity.GetTweetResponse
-		)
 
-		err := tweetRows.Scan(
-			&tweet.ID,
-			&tweet.UserID,
-			&tweet.ParentTweetID,
-			&tweet.Content,
-			pq.Array(&urls),
-		)
-		if err != nil {
-			return entity.SearchResponse{}, err
-		}
-
-		tweet.URLs = urls
+	response.Tweets, err = scanTweets(tweetRows)
+	if err != nil {
+		return entity.SearchResponse{}, err
+	}
 
-		response.Tweets = append(response.Tweets, tweet)
+	if err := hydrateTweets(ctx, s.db, viewerID, response.Tweets); err != nil {
+		return entity.SearchResponse{}, err
 	}
 
 	return response, nil
@@ -0,0 +1,63 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nodeIDs lists the ids of thread nodes in their order
+func nodeIDs(nodes []entity.ThreadNode) []string {
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+
+	return ids
+}
+
+func TestThreadLoadsAncestorsAndPagesReplies(t *testing.T) {
+	db := testDB(t)
+	tweets := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	user := newUser(t, db)
+	root := newTweet(t, db, user)
+	middle := newReply(t, db, user, root)
+	focal := newReply(t, db, user, middle)
+
+	first := newReply(t, db, user, focal)
+	second := newReply(t, db, user, focal)
+	third := newReply(t, db, user, focal)
+	nested := newReply(t, db, user, first)
+	deeper := newReply(t, db, user, nested)
+
+	thread, err := tweets.Thread(ctx, entity.ThreadFilter{TweetID: focal, ViewerID: user, Depth: 2, Limit: 2})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{root, middle}, nodeIDs(thread.Ancestors))
+	assert.Equal(t, focal, thread.Tweet.ID)
+	assert.Equal(t, []string{first, second}, nodeIDs(thread.Tweet.Replies))
+
+	// replies are nested down to the depth asked for
+	assert.Equal(t, []string{nested}, nodeIDs(thread.Tweet.Replies[0].Replies))
+	assert.Empty(t, thread.Tweet.Replies[0].Replies[0].Replies)
+
+	cursor, err := utils.DecodeCursor(thread.NextCursor)
+	require.NoError(t, err)
+	require.NotNil(t, cursor)
+
+	thread, err = tweets.Thread(ctx, entity.ThreadFilter{TweetID: focal, ViewerID: user, Depth: 3, Cursor: cursor, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{third}, nodeIDs(thread.Tweet.Replies))
+	assert.Empty(t, thread.NextCursor)
+
+	thread, err = tweets.Thread(ctx, entity.ThreadFilter{TweetID: focal, ViewerID: user, Depth: 3, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{deeper}, nodeIDs(thread.Tweet.Replies[0].Replies[0].Replies))
+}
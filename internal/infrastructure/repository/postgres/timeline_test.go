@@ -0,0 +1,41 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	postgresql "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHomeTimelinePagesOwnAndFollowedTweets(t *testing.T) {
+	db := testDB(t)
+	repo := postgresql.NewTweetRepo(db)
+	ctx := context.Background()
+
+	viewer := newUser(t, db)
+	followed := newUser(t, db)
+	stranger := newUser(t, db)
+	follow(t, db, viewer, followed)
+
+	first := newTweet(t, db, followed)
+	newTweet(t, db, stranger)
+	own := newTweet(t, db, viewer)
+	latest := newTweet(t, db, followed)
+
+	page, err := repo.HomeTimeline(ctx, entity.TimelineFilter{UserID: viewer, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{latest, own}, tweetIDs(page.Tweets))
+	require.NotEmpty(t, page.NextCursor)
+
+	cursor, err := utils.DecodeCursor(page.NextCursor)
+	require.NoError(t, err)
+
+	page, err = repo.HomeTimeline(ctx, entity.TimelineFilter{UserID: viewer, Cursor: cursor, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{first}, tweetIDs(page.Tweets))
+	assert.Empty(t, page.NextCursor)
+}
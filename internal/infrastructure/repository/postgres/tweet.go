@@ -3,13 +3,446 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
 	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
 	"github.com/dostonshernazarov/mini-twitter/internal/pkg/postgres"
-	"github.com/lib/pq"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
 )
 
+// tweetMediaColumn gathers the rendition urls of the media of the tweet aliased as t, files
+// attached before media were recorded only have their original
+const tweetMediaColumn = `
+		COALESCE((
+			SELECT json_agg(COALESCE(m.urls, jsonb_build_object('original', f.file_url)) ORDER BY f.position)
+			FROM files AS f LEFT JOIN media AS m ON m.id = f.id
+			WHERE f.tweet_id = t.id AND f.deleted_at IS NULL
+		), '[]')`
+
+// tweetColumns is the select list shared by every tweet read, the table must be aliased as t
+const tweetColumns = `
+		t.id,
+		t.user_id,
+		t.kind,
+		t.parent_tweet_id,
+		t.original_tweet_id,
+		t.content,` + tweetMediaColumn + `,
+		t.like_count,
+		t.reply_count,
+		t.retweet_count,
+		t.quote_count,
+		t.edit_count,
+		t.updated_at,
+		t.created_at`
+
+const (
+	// maxThreadAncestors bounds the walk up a reply chain
+	maxThreadAncestors = 100
+	// threadBranchLimit is the number of replies loaded under each nested reply
+	threadBranchLimit = 5
+)
+
+// scanTweet reads one row selected with tweetColumns
+func scanTweet(row pgx.Row) (entity.GetTweetResponse, error) {
+	var tweet entity.GetTweetResponse
+	err := row.Scan(
+		&tweet.ID,
+		&tweet.UserID,
+		&tweet.Kind,
+		&tweet.ParentTweetID,
+		&tweet.OriginalTweetID,
+		&tweet.Content,
+		&tweet.URLs,
+		&tweet.LikeCount,
+		&tweet.ReplyCount,
+		&tweet.RetweetCount,
+		&tweet.QuoteCount,
+		&tweet.EditCount,
+		&tweet.EditedAt,
+		&tweet.CreatedAt,
+	)
+	if err != nil {
+		return entity.GetTweetResponse{}, err
+	}
+
+	return tweet, nil
+}
+
+// scanTweets reads all rows selected with tweetColumns
+func scanTweets(rows pgx.Rows) ([]entity.GetTweetResponse, error) {
+	defer rows.Close()
+
+	var tweets []entity.GetTweetResponse
+	for rows.Next() {
+		tweet, err := scanTweet(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tweets = append(tweets, tweet)
+	}
+
+	return tweets, rows.Err()
+}
+
+// scanThreadNodes reads all rows selected with tweetColumns as thread nodes without replies
+func scanThreadNodes(rows pgx.Rows) ([]entity.ThreadNode, error) {
+	tweets, err := scanTweets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]entity.ThreadNode, 0, len(tweets))
+	for _, tweet := range tweets {
+		nodes = append(nodes, entity.ThreadNode{GetTweetResponse: tweet})
+	}
+
+	return nodes, nil
+}
+
+// attachOriginals embeds the original tweet into retweets and quotes, an original hidden
+// from viewerID is left out and the retweet or quote comes without it
+func attachOriginals(ctx context.Context, db *postgres.PostgresDB, viewerID string, tweets []entity.GetTweetResponse) error {
+	var ids []string
+	for _, tweet := range tweets {
+		if tweet.OriginalTweetID != nil {
+			ids = append(ids, *tweet.OriginalTweetID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	originals, err := getTweetsByIDs(ctx, db, ids, viewerID)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*entity.GetTweetResponse, len(originals))
+	for i := range originals {
+		byID[originals[i].ID] = &originals[i]
+	}
+
+	for i := range tweets {
+		if tweets[i].OriginalTweetID != nil {
+			tweets[i].Original = byID[*tweets[i].OriginalTweetID]
+		}
+	}
+
+	return nil
+}
+
+// attachViewerState sets the flags that depend on who reads the tweets, including embedded originals
+func attachViewerState(ctx context.Context, db *postgres.PostgresDB, viewerID string, tweets []entity.GetTweetResponse) error {
+	if viewerID == "" || len(tweets) == 0 {
+		return nil
+	}
+
+	var ids []string
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.ID)
+		if tweet.Original != nil {
+			ids = append(ids, tweet.Original.ID)
+		}
+	}
+
+	liked, err := viewerTweetIDs(ctx, db, `SELECT tweet_id FROM likes WHERE user_id = $1 AND tweet_id = ANY($2::UUID[])`, viewerID, ids)
+	if err != nil {
+		return err
+	}
+
+	bookmarked, err := viewerTweetIDs(ctx, db, `SELECT tweet_id FROM bookmarks WHERE user_id = $1 AND tweet_id = ANY($2::UUID[])`, viewerID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range tweets {
+		tweets[i].LikedByMe = liked[tweets[i].ID]
+		tweets[i].BookmarkedByMe = bookmarked[tweets[i].ID]
+		if tweets[i].Original != nil {
+			tweets[i].Original.LikedByMe = liked[tweets[i].Original.ID]
+			tweets[i].Original.BookmarkedByMe = bookmarked[tweets[i].Original.ID]
+		}
+	}
+
+	return nil
+}
+
+// viewerTweetIDs runs a query selecting tweet ids for the viewer $1 among the ids $2 and returns them as a set
+func viewerTweetIDs(ctx context.Context, db *postgres.PostgresDB, query, viewerID string, ids []string) (map[string]bool, error) {
+	rows, err := db.Query(ctx, query, viewerID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	set := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		set[id] = true
+	}
+
+	return set, rows.Err()
+}
+
+// hydrateTweets fills what the row of a tweet does not hold, one query per kind for the whole page
+func hydrateTweets(ctx context.Context, db *postgres.PostgresDB, viewerID string, tweets []entity.GetTweetResponse) error {
+	if err := attachOriginals(ctx, db, viewerID, tweets); err != nil {
+		return err
+	}
+
+	if err := attachPolls(ctx, db, viewerID, tweets); err != nil {
+		return err
+	}
+
+	return attachViewerState(ctx, db, viewerID, tweets)
+}
+
+// getTweetsByIDs reads the tweets among ids in the order of ids, authors blocked or muted
+// by viewerID and protected authors viewerID does not follow are left out
+func getTweetsByIDs(ctx context.Context, db *postgres.PostgresDB, ids []string, viewerID string) ([]entity.GetTweetResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    tweets AS t
+	WHERE
+	    t.id = ANY($1::UUID[]) AND t.deleted_at IS NULL AND %s AND %s
+	ORDER BY
+	    array_position($1::UUID[], t.id)
+	`, tweetColumns, visibleTo("t.user_id", optionalViewer("$2")), canSee("t.user_id", optionalViewer("$2")))
+
+	rows, err := db.Query(ctx, query, ids, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTweets(rows)
+}
+
+// originalTweetID resolves the tweet a retweet or quote of userID points to, retweets of
+// retweets point to the original. Tweets userID can not see give sql.ErrNoRows
+func originalTweetID(ctx context.Context, tx pgx.Tx, id, userID string) (string, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		o.id
+	FROM
+	    tweets AS t
+	INNER JOIN
+	    tweets AS o ON o.id = CASE WHEN t.kind = 'retweet' THEN t.original_tweet_id ELSE t.id END
+	WHERE
+	    t.id = $1 AND t.deleted_at IS NULL AND o.deleted_at IS NULL
+		AND %s AND %s AND %s AND %s
+	`, notBlocked("t.user_id", "$2::UUID"), canSee("t.user_id", "$2::UUID"), notBlocked("o.user_id", "$2::UUID"), canSee("o.user_id", "$2::UUID"))
+
+	var originalID string
+	if err := tx.QueryRow(ctx, query, id, userID).Scan(&originalID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", sql.ErrNoRows
+		}
+		return "", err
+	}
+
+	return originalID, nil
+}
+
+// saveHashtags links a tweet to its hashtags, creating the hashtags seen for the first time
+func saveHashtags(ctx context.Context, tx pgx.Tx, tweetID string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(tags))
+	for range tags {
+		ids = append(ids, uuid.NewString())
+	}
+
+	insertHashtagsQuery := `
+	INSERT INTO hashtags (id, tag)
+	SELECT * FROM unnest($1::UUID[], $2::VARCHAR[])
+	ON CONFLICT (tag) DO NOTHING
+	`
+
+	if _, err := tx.Exec(ctx, insertHashtagsQuery, ids, tags); err != nil {
+		return err
+	}
+
+	linkQuery := `
+	INSERT INTO tweet_hashtags (tweet_id, hashtag_id)
+	SELECT $1, id FROM hashtags WHERE tag = ANY($2::VARCHAR[])
+	ON CONFLICT DO NOTHING
+	`
+
+	_, err := tx.Exec(ctx, linkQuery, tweetID, tags)
+	return err
+}
+
+// saveMentions links a tweet to the existing users it mentions and returns the ids
+// of the users mentioned for the first time, authors mentioning themselves and users
+// with a block between them and the author are skipped
+func saveMentions(ctx context.Context, tx pgx.Tx, tweetID, authorID string, usernames []string) ([]string, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf(`
+	INSERT INTO mentions (tweet_id, user_id)
+	SELECT $1, id FROM users
+	WHERE
+	    username = ANY($2::VARCHAR[]) AND id <> $3 AND deleted_at IS NULL AND %s
+	ON CONFLICT DO NOTHING
+	RETURNING user_id
+	`, notBlocked("id", "$3"))
+
+	rows, err := tx.Query(ctx, query, tweetID, usernames, authorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// insertTweet saves a new tweet with its media, poll, hashtags and mentions and records its
+// tweet.created event in tx, quoting and replying bump the counters of the tweets they point to
+func insertTweet(ctx context.Context, tx pgx.Tx, tweet entity.CreateTweetRequest) (entity.CreateTweetResponse, error) {
+	if tweet.Kind == entity.TweetKindQuote {
+		originalID, err := originalTweetID(ctx, tx, *tweet.OriginalTweetID, tweet.UserID)
+		if err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+
+		tweet.OriginalTweetID = &originalID
+
+		if _, err := tx.Exec(ctx, `UPDATE tweets SET quote_count = quote_count + 1 WHERE id = $1`, originalID); err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+	}
+
+	if tweet.ParentTweetID != nil {
+		// a tweet of a protected account the author does not follow is not there to answer
+		replyQuery := fmt.Sprintf(`
+		UPDATE
+			tweets
+		SET
+			reply_count = reply_count + 1
+		WHERE
+		    id = $1 AND deleted_at IS NULL AND %s
+		RETURNING
+			user_id
+		`, canSee("user_id", "$2::UUID"))
+
+		var parentUserID string
+		err := tx.QueryRow(ctx, replyQuery, *tweet.ParentTweetID, tweet.UserID).Scan(&parentUserID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = sql.ErrNoRows
+		}
+		if err == nil {
+			var blocked bool
+			blocked, err = blockedBetween(ctx, tx, tweet.UserID, parentUserID)
+			if err == nil && blocked {
+				err = errorspkg.ErrorBlocked
+			}
+		}
+		if err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+	}
+
+	insertTweetQuery := `
+	INSERT INTO tweets (
+	    id,
+	    user_id,
+	    kind,
+	    parent_tweet_id,
+	    original_tweet_id,
+	    content
+	) VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING
+		id,
+		user_id,
+		kind,
+		parent_tweet_id,
+		original_tweet_id,
+		content,
+		created_at
+	`
+
+	var response entity.CreateTweetResponse
+
+	err := tx.QueryRow(
+		ctx,
+		insertTweetQuery,
+		tweet.ID,
+		tweet.UserID,
+		tweet.Kind,
+		tweet.ParentTweetID,
+		tweet.OriginalTweetID,
+		tweet.Content,
+	).Scan(
+		&response.ID,
+		&response.UserID,
+		&response.Kind,
+		&response.ParentTweetID,
+		&response.OriginalTweetID,
+		&response.Content,
+		&response.CreatedAt,
+	)
+	if err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	response.URLs, err = attachMedia(ctx, tx, response.ID, response.UserID, tweet.MediaIDs)
+	if err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	if tweet.Poll != nil {
+		response.Poll, err = savePoll(ctx, tx, response.ID, *tweet.Poll)
+		if err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+	}
+
+	if err := saveHashtags(ctx, tx, response.ID, tweet.Hashtags); err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	response.Hashtags = tweet.Hashtags
+
+	response.MentionedIDs, err = saveMentions(ctx, tx, response.ID, response.UserID, tweet.Mentions)
+	if err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	err = writeOutbox(ctx, tx, entity.AggregateTweet, response.ID, entity.EventTypeTweetCreated, response.UserID, response.ID, response)
+	if err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	return response, nil
+}
+
 type tweetRepo struct {
 	db *postgres.PostgresDB
 }
@@ -26,251 +459,902 @@ func (t *tweetRepo) CreateTweet(ctx context.Context, tweet entity.CreateTweetReq
 		return entity.CreateTweetResponse{}, err
 	}
 
-	insertTweetQuery := `
+	response, err := insertTweet(ctx, tx, tweet)
+	if err == nil {
+		err = notifyTweet(ctx, tx, response)
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+		return entity.CreateTweetResponse{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	return response, nil
+}
+
+// Retweet reposts a tweet, a retweet of a retweet reposts the original and its author is notified
+func (t *tweetRepo) Retweet(ctx context.Context, retweet entity.RetweetAction) (entity.CreateTweetResponse, error) {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	originalID, err := originalTweetID(ctx, tx, retweet.TweetID, retweet.UserID)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+		return entity.CreateTweetResponse{}, err
+	}
+
+	insertQuery := `
 	INSERT INTO tweets (
+	    id,
 	    user_id,
-	    parent_tweet_id,
-	    content
-	) VALUES ($1, $2, $3)
+	    kind,
+	    original_tweet_id
+	) VALUES ($1, $2, 'retweet', $3)
+	ON CONFLICT (user_id, original_tweet_id) WHERE kind = 'retweet' AND deleted_at IS NULL DO NOTHING
 	RETURNING
 		id,
 		user_id,
+		kind,
 		parent_tweet_id,
-		content
+		original_tweet_id,
+		content,
+		created_at
+	`
+
+	var response entity.CreateTweetResponse
+
+	err = tx.QueryRow(ctx, insertQuery, retweet.ID, retweet.UserID, originalID).Scan(
+		&response.ID,
+		&response.UserID,
+		&response.Kind,
+		&response.ParentTweetID,
+		&response.OriginalTweetID,
+		&response.Content,
+		&response.CreatedAt,
+	)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.CreateTweetResponse{}, errorspkg.ErrorConflict
+		}
+		return entity.CreateTweetResponse{}, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE tweets SET retweet_count = retweet_count + 1 WHERE id = $1`, originalID); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+		return entity.CreateTweetResponse{}, err
+	}
+
+	err = writeOutbox(ctx, tx, entity.AggregateTweet, response.ID, entity.EventTypeTweetCreated, response.UserID, response.ID, response)
+	if err == nil {
+		var authorID string
+		err = tx.QueryRow(ctx, `SELECT user_id FROM tweets WHERE id = $1`, originalID).Scan(&authorID)
+		if err == nil {
+			err = notify(ctx, tx, authorID, response.UserID, entity.NotificationTypeRetweet, &originalID)
+		}
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+		return entity.CreateTweetResponse{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.CreateTweetResponse{}, err
+		}
+		return entity.CreateTweetResponse{}, err
+	}
+
+	return response, nil
+}
+
+// UndoRetweet removes the user's retweet of a tweet, also when the tweet was deleted since
+func (t *tweetRepo) UndoRetweet(ctx context.Context, retweet entity.RetweetAction) error {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	deleteQuery := `
+	UPDATE
+		tweets
+	SET
+		deleted_at = NOW()
+	WHERE
+	    user_id = $1
+		AND kind = 'retweet'
+		AND deleted_at IS NULL
+		AND original_tweet_id = (SELECT CASE WHEN kind = 'retweet' THEN original_tweet_id ELSE id END FROM tweets WHERE id = $2)
+	RETURNING
+		id,
+		original_tweet_id
 	`
 
-	var response entity.CreateTweetResponse
+	var retweetID, originalID string
+	if err := tx.QueryRow(ctx, deleteQuery, retweet.UserID, retweet.TweetID).Scan(&retweetID, &originalID); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE tweets SET retweet_count = retweet_count - 1 WHERE id = $1`, originalID); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	deleted := entity.DeletedTweet{ID: retweetID, UserID: retweet.UserID, Kind: entity.TweetKindRetweet}
+	err = writeOutbox(ctx, tx, entity.AggregateTweet, retweetID, entity.EventTypeTweetDeleted, retweet.UserID, retweetID, deleted)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (t *tweetRepo) UpdateTweet(ctx context.Context, tweet entity.UpdateTweetRequest) (entity.UpdateTweetResponse, error) {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return entity.UpdateTweetResponse{}, err
+	}
+
+	// the replaced content is kept as a revision, an edit made since the caller read the
+	// tweet changed edit_count and gives a conflict instead of being overwritten
+	revisionQuery := `
+	INSERT INTO tweet_revisions (
+		tweet_id,
+		revision,
+		content,
+		created_at
+	)
+	SELECT
+		id,
+		edit_count,
+		content,
+		COALESCE(updated_at, created_at)
+	FROM
+	    tweets
+	WHERE
+	    id = $1 AND deleted_at IS NULL AND parent_tweet_id IS NULL AND kind <> 'retweet' AND edit_count = $2
+	FOR UPDATE
+	`
+
+	tag, err := tx.Exec(ctx, revisionQuery, tweet.ID, tweet.EditCount)
+	if err == nil && tag.RowsAffected() == 0 {
+		err = errorspkg.ErrorConflict
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.UpdateTweetResponse{}, err
+		}
+		return entity.UpdateTweetResponse{}, err
+	}
+
+	query := `
+	UPDATE
+		tweets AS t
+	SET
+		content = $1,
+		edit_count = t.edit_count + 1,
+		updated_at = NOW()
+	WHERE
+	    t.id = $2
+	RETURNING
+		t.id,
+	    t.user_id,
+		t.parent_tweet_id,
+		t.content,` + tweetMediaColumn + `,
+		t.edit_count,
+		t.updated_at
+	`
+
+	var response entity.UpdateTweetResponse
+	err = tx.QueryRow(ctx, query, tweet.Content, tweet.ID).Scan(
+		&response.ID,
+		&response.UserID,
+		&response.ParentTweetID,
+		&response.Content,
+		&response.URLs,
+		&response.EditCount,
+		&response.EditedAt,
+	)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.UpdateTweetResponse{}, err
+		}
+		return entity.UpdateTweetResponse{}, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM tweet_hashtags WHERE tweet_id = $1`, tweet.ID); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.UpdateTweetResponse{}, err
+		}
+		return entity.UpdateTweetResponse{}, err
+	}
+
+	if err := saveHashtags(ctx, tx, tweet.ID, tweet.Hashtags); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.UpdateTweetResponse{}, err
+		}
+		return entity.UpdateTweetResponse{}, err
+	}
+
+	// mentions removed by the edit are dropped, the ones kept are not reported again
+	deleteMentionsQuery := `
+	DELETE FROM mentions
+	WHERE
+	    tweet_id = $1
+		AND user_id NOT IN (SELECT id FROM users WHERE username = ANY($2::VARCHAR[]))
+	`
+
+	if _, err := tx.Exec(ctx, deleteMentionsQuery, tweet.ID, tweet.Mentions); err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.UpdateTweetResponse{}, err
+		}
+		return entity.UpdateTweetResponse{}, err
+	}
+
+	response.MentionedIDs, err = saveMentions(ctx, tx, tweet.ID, response.UserID, tweet.Mentions)
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.UpdateTweetResponse{}, err
+		}
+		return entity.UpdateTweetResponse{}, err
+	}
+
+	err = writeOutbox(ctx, tx, entity.AggregateTweet, response.ID, entity.EventTypeTweetUpdated, response.UserID, response.ID, response)
+	if err == nil {
+		err = notifyMentions(ctx, tx, response.UserID, response.ID, response.MentionedIDs)
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return entity.UpdateTweetResponse{}, err
+		}
+		return entity.UpdateTweetResponse{}, err
+	}
+
+	return response, tx.Commit(ctx)
+}
+
+func (t *tweetRepo) DeleteTweet(ctx context.Context, id string) error {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE tweets SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING user_id, kind, parent_tweet_id, original_tweet_id`
 
-	err = tx.QueryRow(
-		ctx,
-		insertTweetQuery,
-		tweet.UserID,
-		tweet.ParentTweetID,
-		tweet.Content,
-	).Scan(
-		&response.ID,
-		&response.UserID,
-		&response.ParentTweetID,
-		&response.Content,
+	var (
+		userID     string
+		kind       string
+		parentID   *string
+		originalID *string
 	)
-	if err != nil {
+	if err := tx.QueryRow(ctx, query, id).Scan(&userID, &kind, &parentID, &originalID); err != nil {
 		if err := tx.Rollback(ctx); err != nil {
-			return entity.CreateTweetResponse{}, err
+			return err
 		}
-		return entity.CreateTweetResponse{}, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return err
 	}
 
-	for _, url := range tweet.URLs {
-		insertFileURLQuery := `INSERT INTO files (tweet_id, file_url) VALUES ($1, $2) RETURNING file_url`
+	var (
+		counterQuery string
+		targetID     *string
+	)
+	switch kind {
+	case entity.TweetKindReply:
+		counterQuery = `UPDATE tweets SET reply_count = reply_count - 1 WHERE id = $1`
+		targetID = parentID
+	case entity.TweetKindRetweet:
+		counterQuery = `UPDATE tweets SET retweet_count = retweet_count - 1 WHERE id = $1`
+		targetID = originalID
+	case entity.TweetKindQuote:
+		counterQuery = `UPDATE tweets SET quote_count = quote_count - 1 WHERE id = $1`
+		targetID = originalID
+	}
 
-		var savedURL string
-		if err := tx.QueryRow(ctx, insertFileURLQuery, response.ID, url).Scan(&savedURL); err != nil {
+	if counterQuery != "" {
+		if _, err := tx.Exec(ctx, counterQuery, targetID); err != nil {
 			if err := tx.Rollback(ctx); err != nil {
-				return entity.CreateTweetResponse{}, err
+				return err
 			}
-			return entity.CreateTweetResponse{}, err
+			return err
 		}
-
-		response.URLs = append(response.URLs, savedURL)
 	}
 
-	if err := tx.Commit(ctx); err != nil {
+	deleted := entity.DeletedTweet{ID: id, UserID: userID, Kind: kind}
+	if err := writeOutbox(ctx, tx, entity.AggregateTweet, id, entity.EventTypeTweetDeleted, userID, id, deleted); err != nil {
 		if err := tx.Rollback(ctx); err != nil {
-			return entity.CreateTweetResponse{}, err
+			return err
 		}
-		return entity.CreateTweetResponse{}, err
+		return err
 	}
 
-	return response, nil
+	return tx.Commit(ctx)
 }
 
-func (t *tweetRepo) UpdateTweet(ctx context.Context, tweet entity.UpdateTweetRequest) (entity.UpdateTweetResponse, error) {
-	query := `
-	UPDATE
-		tweets AS t
-	SET
-		content = $1
+func (t *tweetRepo) GetTweet(ctx context.Context, id, viewerID string) (entity.GetTweetResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    tweets AS t
 	WHERE
-	    t.id = $2 AND t.deleted_at IS NULL AND t.parent_tweet_id IS NULL
-	RETURNING
-		t.id,
-	    t.user_id,
-		t.parent_tweet_id,
-		t.content,
-		COALESCE((SELECT array_agg(file_url) FROM files WHERE tweet_id = t.id AND t.deleted_at IS NULL), '{}')
-	`
+	    t.id = $1 AND t.deleted_at IS NULL AND %s AND %s
+	`, tweetColumns, notBlocked("t.user_id", optionalViewer("$2")), canSee("t.user_id", optionalViewer("$2")))
 
-	var (
-		urls     []string
-		response entity.UpdateTweetResponse
-	)
-	err := t.db.QueryRow(ctx, query, tweet.Content, tweet.ID).Scan(
-		&response.ID,
-		&response.UserID,
-		&response.ParentTweetID,
-		&response.Content,
-		pq.Array(&urls),
-	)
+	response, err := scanTweet(t.db.QueryRow(ctx, query, id, viewerID))
 	if err != nil {
-		return entity.UpdateTweetResponse{}, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.GetTweetResponse{}, sql.ErrNoRows
+		}
+		return entity.GetTweetResponse{}, err
 	}
 
-	response.URLs = urls
+	tweets := []entity.GetTweetResponse{response}
+	if err := hydrateTweets(ctx, t.db, viewerID, tweets); err != nil {
+		return entity.GetTweetResponse{}, err
+	}
 
-	return response, nil
+	return tweets[0], nil
 }
 
-func (t *tweetRepo) DeleteTweet(ctx context.Context, id string) error {
-	query := `UPDATE tweets SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
-
-	result, err := t.db.Exec(ctx, query, id)
+// TweetHistory returns the current content of a tweet the viewer can see followed by the
+// contents it replaced, the latest first
+func (t *tweetRepo) TweetHistory(ctx context.Context, id, viewerID string) (entity.TweetHistoryResponse, error) {
+	tweet, err := t.GetTweet(ctx, id, viewerID)
 	if err != nil {
-		return err
+		return entity.TweetHistoryResponse{}, err
 	}
 
-	rows := result.RowsAffected()
-
-	if rows == 0 {
-		return sql.ErrNoRows
+	response := entity.TweetHistoryResponse{
+		TweetID: tweet.ID,
+		Revisions: []entity.TweetRevision{{
+			Revision:  tweet.EditCount,
+			Content:   tweet.Content,
+			CreatedAt: tweet.CreatedAt,
+		}},
+	}
+	if tweet.EditedAt != nil {
+		response.Revisions[0].CreatedAt = *tweet.EditedAt
 	}
 
-	return nil
-}
-
-func (t *tweetRepo) GetTweet(ctx context.Context, id string) (entity.GetTweetResponse, error) {
 	query := `
 	SELECT
-		id,
-		user_id,
-		parent_tweet_id,
+		revision,
 		content,
-		COALESCE((SELECT array_agg(file_url) FROM files WHERE tweet_id = $1 AND deleted_at IS NULL), '{}')
+		created_at
 	FROM
-	    tweets
+	    tweet_revisions
 	WHERE
-	    id = $1 AND deleted_at IS NULL
+	    tweet_id = $1
+	ORDER BY
+	    revision DESC
 	`
 
-	var (
-		urls     []string
-		response entity.GetTweetResponse
-	)
-	err := t.db.QueryRow(ctx, query, id).Scan(
-		&response.ID,
-		&response.UserID,
-		&response.ParentTweetID,
-		&response.Content,
-		pq.Array(&urls),
-	)
+	rows, err := t.db.Query(ctx, query, id)
 	if err != nil {
-		return entity.GetTweetResponse{}, err
+		return entity.TweetHistoryResponse{}, err
 	}
+	defer rows.Close()
 
-	response.URLs = urls
+	for rows.Next() {
+		var revision entity.TweetRevision
+		if err := rows.Scan(&revision.Revision, &revision.Content, &revision.CreatedAt); err != nil {
+			return entity.TweetHistoryResponse{}, err
+		}
 
-	return response, nil
+		response.Revisions = append(response.Revisions, revision)
+	}
+
+	return response, rows.Err()
 }
 
-func (t *tweetRepo) ListTweets(ctx context.Context, filter entity.Filter) (entity.ListTweetsResponse, error) {
-	query := `
-	SELECT
-		t.id,
-		t.user_id,
-		t.parent_tweet_id,
-		t.content,
-		COALESCE((SELECT array_agg(file_url) FROM files WHERE tweet_id = t.id AND deleted_at IS NULL), '{}')
+func (t *tweetRepo) ListTweets(ctx context.Context, filter entity.Filter, viewerID string) (entity.ListTweetsResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
 	FROM
 	    tweets AS t
 	WHERE
-	    t.deleted_at IS NULL
+	    t.deleted_at IS NULL AND %s AND %s
 	LIMIT $1 OFFSET $2
-	`
+	`, tweetColumns, visibleTo("t.user_id", optionalViewer("$3")), canSee("t.user_id", optionalViewer("$3")))
 
 	var response entity.ListTweetsResponse
 	offset := filter.Limit * (filter.Page - 1)
 
-	rows, err := t.db.Query(ctx, query, filter.Limit, offset)
+	rows, err := t.db.Query(ctx, query, filter.Limit, offset, viewerID)
 	if err != nil {
 		return entity.ListTweetsResponse{}, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var (
-			urls  []string
-			tweet entity.GetTweetResponse
-		)
-		err := rows.Scan(
-			&tweet.ID,
-			&tweet.UserID,
-			&tweet.ParentTweetID,
-			&tweet.Content,
-			pq.Array(&urls),
-		)
-		if err != nil {
-			return entity.ListTweetsResponse{}, err
-		}
+	response.Tweets, err = scanTweets(rows)
+	if err != nil {
+		return entity.ListTweetsResponse{}, err
+	}
+
+	if err := hydrateTweets(ctx, t.db, viewerID, response.Tweets); err != nil {
+		return entity.ListTweetsResponse{}, err
+	}
+
+	countQuery := fmt.Sprintf(
+		`SELECT COUNT(*) FROM tweets WHERE deleted_at IS NULL AND %s AND %s`,
+		visibleTo("user_id", optionalViewer("$1")),
+		canSee("user_id", optionalViewer("$1")),
+	)
+	if err := t.db.QueryRow(ctx, countQuery, viewerID).Scan(&response.Count); err != nil {
+		return entity.ListTweetsResponse{}, err
+	}
+
+	return response, nil
+}
+
+func (t *tweetRepo) UserTweets(ctx context.Context, usrID, viewerID string) (entity.ListTweetsResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    tweets AS t
+	WHERE
+	    t.deleted_at IS NULL AND t.user_id = $1 AND %s AND %s
+	`, tweetColumns, notBlocked("t.user_id", optionalViewer("$2")), canSee("t.user_id", optionalViewer("$2")))
+
+	var response entity.ListTweetsResponse
+
+	rows, err := t.db.Query(ctx, query, usrID, viewerID)
+	if err != nil {
+		return entity.ListTweetsResponse{}, err
+	}
 
-		tweet.URLs = urls
+	response.Tweets, err = scanTweets(rows)
+	if err != nil {
+		return entity.ListTweetsResponse{}, err
+	}
 
-		response.Tweets = append(response.Tweets, tweet)
+	if err := hydrateTweets(ctx, t.db, viewerID, response.Tweets); err != nil {
+		return entity.ListTweetsResponse{}, err
 	}
 
-	countQuery := `SELECT COUNT(*) FROM tweets WHERE deleted_at IS NULL`
-	if err := t.db.QueryRow(ctx, countQuery).Scan(&response.Count); err != nil {
+	countQuery := fmt.Sprintf(
+		`SELECT COUNT(*) FROM tweets WHERE deleted_at IS NULL and user_id = $1 AND %s AND %s`,
+		notBlocked("user_id", optionalViewer("$2")),
+		canSee("user_id", optionalViewer("$2")),
+	)
+	if err := t.db.QueryRow(ctx, countQuery, usrID, viewerID).Scan(&response.Count); err != nil {
 		return entity.ListTweetsResponse{}, err
 	}
 
 	return response, nil
 }
 
-func (t *tweetRepo) UserTweets(ctx context.Context, usrID string) (entity.ListTweetsResponse, error) {
+// HomeTimeline returns tweets of the user and of everyone the user follows, newest first
+func (t *tweetRepo) HomeTimeline(ctx context.Context, filter entity.TimelineFilter) (entity.TimelineResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    tweets AS t
+	WHERE
+	    t.deleted_at IS NULL
+		AND (t.user_id = $1 OR t.user_id IN (SELECT following_id FROM follows WHERE user_id = $1))
+		AND ($2::TIMESTAMP IS NULL OR (t.created_at, t.id) < ($2::TIMESTAMP, $3::UUID))
+		AND %s
+	ORDER BY
+	    t.created_at DESC, t.id DESC
+	LIMIT $4
+	`, tweetColumns, visibleTo("t.user_id", "$1"))
+
+	var (
+		cursorTime *time.Time
+		cursorID   *string
+	)
+	if filter.Cursor != nil {
+		cursorTime = &filter.Cursor.CreatedAt
+		cursorID = &filter.Cursor.ID
+	}
+
+	// one extra row tells whether there is a next page
+	rows, err := t.db.Query(ctx, query, filter.UserID, cursorTime, cursorID, filter.Limit+1)
+	if err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	tweets, err := scanTweets(rows)
+	if err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	if err := hydrateTweets(ctx, t.db, filter.UserID, tweets); err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	return utils.NewTimelineResponse(tweets, filter.Limit), nil
+}
+
+// GetTweetsByIDs returns the existing tweets among ids in the order of ids, leaving out
+// the ones whose authors are blocked or muted by viewerID
+func (t *tweetRepo) GetTweetsByIDs(ctx context.Context, ids []string, viewerID string) ([]entity.GetTweetResponse, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	tweets, err := getTweetsByIDs(ctx, t.db, ids, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := hydrateTweets(ctx, t.db, viewerID, tweets); err != nil {
+		return nil, err
+	}
+
+	return tweets, nil
+}
+
+// TimelineEntries returns the newest tweet ids of the given authors, used to build materialized timelines
+func (t *tweetRepo) TimelineEntries(ctx context.Context, filter entity.TimelineEntriesFilter) ([]entity.TimelineEntry, error) {
 	query := `
 	SELECT
 		t.id,
 		t.user_id,
-		t.parent_tweet_id,
-		t.content,
-		COALESCE((SELECT array_agg(file_url) FROM files WHERE tweet_id = t.id AND deleted_at IS NULL), '{}')
+		t.created_at
 	FROM
 	    tweets AS t
 	WHERE
-	    t.deleted_at IS NULL AND t.user_id = $1
+	    t.deleted_at IS NULL
+		AND t.user_id = ANY($1::UUID[])
+		AND ($2::TIMESTAMP IS NULL OR (t.created_at, t.id) < ($2::TIMESTAMP, $3::UUID))
+	ORDER BY
+	    t.created_at DESC, t.id DESC
+	LIMIT $4
 	`
 
-	var response entity.ListTweetsResponse
+	var (
+		cursorTime *time.Time
+		cursorID   *string
+	)
+	if filter.Cursor != nil {
+		cursorTime = &filter.Cursor.CreatedAt
+		cursorID = &filter.Cursor.ID
+	}
 
-	rows, err := t.db.Query(ctx, query, usrID)
+	rows, err := t.db.Query(ctx, query, filter.UserIDs, cursorTime, cursorID, filter.Limit)
 	if err != nil {
-		return entity.ListTweetsResponse{}, err
+		return nil, err
 	}
 	defer rows.Close()
 
+	var entries []entity.TimelineEntry
 	for rows.Next() {
-		var (
-			tweet entity.GetTweetResponse
-			urls  []string
-		)
-		err := rows.Scan(
-			&tweet.ID,
-			&tweet.UserID,
-			&tweet.ParentTweetID,
-			&tweet.Content,
-			pq.Array(&urls),
-		)
+		var entry entity.TimelineEntry
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Thread returns a tweet with its ancestors and a page of its reply tree, direct
+// replies are paged oldest first and nested replies are loaded down to filter.Depth
+func (t *tweetRepo) Thread(ctx context.Context, filter entity.ThreadFilter) (entity.ThreadResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    tweets AS t
+	WHERE
+	    t.id = $1 AND t.deleted_at IS NULL AND %s AND %s
+	`, tweetColumns, notBlocked("t.user_id", optionalViewer("$2")), canSee("t.user_id", optionalViewer("$2")))
+
+	tweet, err := scanTweet(t.db.QueryRow(ctx, query, filter.TweetID, filter.ViewerID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.ThreadResponse{}, sql.ErrNoRows
+		}
+		return entity.ThreadResponse{}, err
+	}
+
+	var response entity.ThreadResponse
+	focal := entity.ThreadNode{GetTweetResponse: tweet}
+
+	if focal.ParentTweetID != nil {
+		response.Ancestors, err = t.threadAncestors(ctx, *focal.ParentTweetID, filter.ViewerID)
 		if err != nil {
-			return entity.ListTweetsResponse{}, err
+			return entity.ThreadResponse{}, err
 		}
+	}
 
-		tweet.URLs = urls
+	replies, err := t.threadReplies(ctx, filter)
+	if err != nil {
+		return entity.ThreadResponse{}, err
+	}
 
-		response.Tweets = append(response.Tweets, tweet)
+	if len(replies) > filter.Limit {
+		replies = replies[:filter.Limit]
+		last := replies[len(replies)-1]
+		response.NextCursor = utils.EncodeCursor(entity.Cursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		})
 	}
 
-	countQuery := `SELECT COUNT(*) FROM tweets WHERE deleted_at IS NULL and user_id = $1`
-	if err := t.db.QueryRow(ctx, countQuery, usrID).Scan(&response.Count); err != nil {
-		return entity.ListTweetsResponse{}, err
+	var descendants []entity.ThreadNode
+	if filter.Depth > 1 && len(replies) > 0 {
+		ids := make([]string, 0, len(replies))
+		for _, reply := range replies {
+			ids = append(ids, reply.ID)
+		}
+
+		descendants, err = t.threadDescendants(ctx, ids, filter.Depth, filter.ViewerID)
+		if err != nil {
+			return entity.ThreadResponse{}, err
+		}
+	}
+
+	// originals of quotes are embedded in one batch for the whole thread
+	nodes := make([]entity.ThreadNode, 0, len(response.Ancestors)+1+len(replies)+len(descendants))
+	nodes = append(nodes, response.Ancestors...)
+	nodes = append(nodes, focal)
+	nodes = append(nodes, replies...)
+	nodes = append(nodes, descendants...)
+
+	tweets := make([]entity.GetTweetResponse, len(nodes))
+	for i := range nodes {
+		tweets[i] = nodes[i].GetTweetResponse
+	}
+
+	if err := hydrateTweets(ctx, t.db, filter.ViewerID, tweets); err != nil {
+		return entity.ThreadResponse{}, err
+	}
+
+	for i := range nodes {
+		nodes[i].GetTweetResponse = tweets[i]
+	}
+
+	ancestors := len(response.Ancestors)
+	response.Ancestors = nodes[:ancestors]
+	focal = nodes[ancestors]
+	replies = nodes[ancestors+1 : ancestors+1+len(replies)]
+	descendants = nodes[ancestors+1+len(replies):]
+
+	children := make(map[string][]entity.ThreadNode)
+	for _, node := range descendants {
+		children[*node.ParentTweetID] = append(children[*node.ParentTweetID], node)
 	}
 
+	focal.Replies = buildReplyTree(replies, children)
+	response.Tweet = focal
+
 	return response, nil
 }
+
+// threadAncestors walks up the reply chain from parentID, the root comes first. Ancestors
+// blocked from viewerID or protected from them are left out of the chain
+func (t *tweetRepo) threadAncestors(ctx context.Context, parentID, viewerID string) ([]entity.ThreadNode, error) {
+	query := fmt.Sprintf(`
+	WITH RECURSIVE ancestors AS (
+		SELECT $1::UUID AS id, 1 AS depth
+		UNION ALL
+		SELECT p.parent_tweet_id, a.depth + 1
+		FROM
+		    ancestors AS a
+		    JOIN tweets AS p ON p.id = a.id
+		WHERE
+		    p.parent_tweet_id IS NOT NULL AND a.depth < $2
+	)
+	SELECT %s
+	FROM
+	    ancestors AS a
+	    JOIN tweets AS t ON t.id = a.id
+	WHERE
+	    t.deleted_at IS NULL AND %s AND %s
+	ORDER BY
+	    a.depth DESC
+	`, tweetColumns, notBlocked("t.user_id", optionalViewer("$3")), canSee("t.user_id", optionalViewer("$3")))
+
+	rows, err := t.db.Query(ctx, query, parentID, maxThreadAncestors, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanThreadNodes(rows)
+}
+
+// threadReplies reads one extra direct reply to tell whether there is a next page
+func (t *tweetRepo) threadReplies(ctx context.Context, filter entity.ThreadFilter) ([]entity.ThreadNode, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    tweets AS t
+	WHERE
+	    t.parent_tweet_id = $1
+		AND t.deleted_at IS NULL
+		AND ($2::TIMESTAMP IS NULL OR (t.created_at, t.id) > ($2::TIMESTAMP, $3::UUID))
+		AND %s
+		AND %s
+	ORDER BY
+	    t.created_at, t.id
+	LIMIT $4
+	`, tweetColumns, visibleTo("t.user_id", optionalViewer("$5")), canSee("t.user_id", optionalViewer("$5")))
+
+	var (
+		cursorTime *time.Time
+		cursorID   *string
+	)
+	if filter.Cursor != nil {
+		cursorTime = &filter.Cursor.CreatedAt
+		cursorID = &filter.Cursor.ID
+	}
+
+	rows, err := t.db.Query(ctx, query, filter.TweetID, cursorTime, cursorID, filter.Limit+1, filter.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanThreadNodes(rows)
+}
+
+// threadDescendants loads the first replies under each of ids, level by level down to depth,
+// replies of users blocked or muted by viewerID, or protected from them, are skipped along
+// with everything under them
+func (t *tweetRepo) threadDescendants(ctx context.Context, ids []string, depth int, viewerID string) ([]entity.ThreadNode, error) {
+	query := fmt.Sprintf(`
+	WITH RECURSIVE descendants AS (
+		SELECT c.id, 2 AS depth
+		FROM
+		    unnest($1::UUID[]) AS p(id)
+		    CROSS JOIN LATERAL (
+		        SELECT r.id FROM tweets AS r
+		        WHERE r.parent_tweet_id = p.id AND r.deleted_at IS NULL AND %[2]s AND %[3]s
+		        ORDER BY r.created_at, r.id
+		        LIMIT $3
+		    ) AS c
+		UNION ALL
+		SELECT c.id, d.depth + 1
+		FROM
+		    descendants AS d
+		    CROSS JOIN LATERAL (
+		        SELECT r.id FROM tweets AS r
+		        WHERE r.parent_tweet_id = d.id AND r.deleted_at IS NULL AND %[2]s AND %[3]s
+		        ORDER BY r.created_at, r.id
+		        LIMIT $3
+		    ) AS c
+		WHERE
+		    d.depth < $2
+	)
+	SELECT %[1]s
+	FROM
+	    descendants AS d
+	    JOIN tweets AS t ON t.id = d.id
+	ORDER BY
+	    t.created_at, t.id
+	`, tweetColumns, visibleTo("r.user_id", optionalViewer("$4")), canSee("r.user_id", optionalViewer("$4")))
+
+	rows, err := t.db.Query(ctx, query, ids, depth, threadBranchLimit, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanThreadNodes(rows)
+}
+
+// buildReplyTree attaches the loaded children to every reply recursively
+func buildReplyTree(replies []entity.ThreadNode, children map[string][]entity.ThreadNode) []entity.ThreadNode {
+	for i := range replies {
+		replies[i].Replies = buildReplyTree(children[replies[i].ID], children)
+	}
+
+	return replies
+}
+
+// HashtagTweets returns the tweets tagged with a hashtag, newest first
+func (t *tweetRepo) HashtagTweets(ctx context.Context, filter entity.HashtagFilter) (entity.TimelineResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    tweets AS t
+	    JOIN tweet_hashtags AS th ON th.tweet_id = t.id
+	    JOIN hashtags AS h ON h.id = th.hashtag_id
+	WHERE
+	    h.tag = $1
+		AND t.deleted_at IS NULL
+		AND ($2::TIMESTAMP IS NULL OR (t.created_at, t.id) < ($2::TIMESTAMP, $3::UUID))
+		AND %s
+		AND %s
+	ORDER BY
+	    t.created_at DESC, t.id DESC
+	LIMIT $4
+	`, tweetColumns, visibleTo("t.user_id", optionalViewer("$5")), canSee("t.user_id", optionalViewer("$5")))
+
+	var (
+		cursorTime *time.Time
+		cursorID   *string
+	)
+	if filter.Cursor != nil {
+		cursorTime = &filter.Cursor.CreatedAt
+		cursorID = &filter.Cursor.ID
+	}
+
+	rows, err := t.db.Query(ctx, query, filter.Tag, cursorTime, cursorID, filter.Limit+1, filter.ViewerID)
+	if err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	tweets, err := scanTweets(rows)
+	if err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	if err := hydrateTweets(ctx, t.db, filter.ViewerID, tweets); err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	return utils.NewTimelineResponse(tweets, filter.Limit), nil
+}
+
+// MentionTweets returns the tweets mentioning filter.UserID, newest first
+func (t *tweetRepo) MentionTweets(ctx context.Context, filter entity.TimelineFilter) (entity.TimelineResponse, error) {
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM
+	    tweets AS t
+	    JOIN mentions AS m ON m.tweet_id = t.id
+	WHERE
+	    m.user_id = $1
+		AND t.deleted_at IS NULL
+		AND ($2::TIMESTAMP IS NULL OR (t.created_at, t.id) < ($2::TIMESTAMP, $3::UUID))
+		AND %s
+	ORDER BY
+	    t.created_at DESC, t.id DESC
+	LIMIT $4
+	`, tweetColumns, visibleTo("t.user_id", "$1"))
+
+	var (
+		cursorTime *time.Time
+		cursorID   *string
+	)
+	if filter.Cursor != nil {
+		cursorTime = &filter.Cursor.CreatedAt
+		cursorID = &filter.Cursor.ID
+	}
+
+	rows, err := t.db.Query(ctx, query, filter.UserID, cursorTime, cursorID, filter.Limit+1)
+	if err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	tweets, err := scanTweets(rows)
+	if err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	if err := hydrateTweets(ctx, t.db, filter.UserID, tweets); err != nil {
+		return entity.TimelineResponse{}, err
+	}
+
+	return utils.NewTimelineResponse(tweets, filter.Limit), nil
+}
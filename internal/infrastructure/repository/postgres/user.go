@@ -100,23 +100,25 @@ func (u *userRepo) Create(ctx context.Context, user entity.CreateUserRequest) (e
 	}
 
 	return entity.CreateUserResponse{
-		ID:             user.ID,
-		Name:           user.Name,
-		Username:       user.Username,
-		Email:          user.Email,
-		Bio:            new(string),
-		Role:           user.Role,
-		ProfilePicture: new(string),
+		ID:       user.ID,
+		Name:     user.Name,
+		Username: user.Username,
+		Email:    user.Email,
+		Bio:      new(string),
+		Role:     user.Role,
 	}, nil
 
 }
 
+// Update saves the profile of a user, an account made public accepts the follow requests
+// still waiting for an answer in the same transaction
 func (u *userRepo) Update(ctx context.Context, user entity.UpdateUserRequest) error {
 
 	clauses := map[string]interface{}{
-		"name":     user.Name,
-		"bio":      user.Bio,
-		"username": user.Username,
+		"name":         user.Name,
+		"bio":          user.Bio,
+		"username":     user.Username,
+		"is_protected": user.IsProtected,
 	}
 
 	queryBuilder := u.db.Sq.Builder.Update(u.tableName)
@@ -129,16 +131,26 @@ func (u *userRepo) Update(ctx context.Context, user entity.UpdateUserRequest) er
 		return err
 	}
 
-	result, err := u.db.Exec(ctx, query, args...)
+	tx, err := u.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 
-	if result.RowsAffected() == 0 {
-		return pgx.ErrNoRows
+	result, err := tx.Exec(ctx, query, args...)
+	if err == nil && result.RowsAffected() == 0 {
+		err = pgx.ErrNoRows
+	}
+	if err == nil && !user.IsProtected {
+		err = acceptFollowRequests(ctx, tx, user.ID)
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return err
+		}
+		return err
 	}
 
-	return nil
+	return tx.Commit(ctx)
 }
 
 func (u *userRepo) UpdatePasswd(ctx context.Context, id string, passwd string) error {
@@ -168,9 +180,9 @@ func (u *userRepo) UpdatePasswd(ctx context.Context, id string, passwd string) e
 	return nil
 }
 
-func (u *userRepo) UploadImage(ctx context.Context, id string, url string) error {
+func (u *userRepo) UploadImage(ctx context.Context, id string, picture entity.MediaURLs) error {
 	clauses := map[string]interface{}{
-		"profile_picture": url,
+		"profile_picture": picture,
 	}
 
 	queryBuilder := u.db.Sq.Builder.Update(u.tableName)
@@ -220,9 +232,8 @@ func (u *userRepo) Delete(ctx context.Context, id string) error {
 
 func (u *userRepo) Get(ctx context.Context, field map[string]interface{}) (entity.GetUserResponse, error) {
 	var (
-		result   entity.GetUserResponse
-		NullBio  sql.NullString
-		NulPhoto sql.NullString
+		result  entity.GetUserResponse
+		NullBio sql.NullString
 	)
 	queryBuilder := u.db.Sq.Builder.Select(
 		"id, " +
@@ -233,6 +244,7 @@ func (u *userRepo) Get(ctx context.Context, field map[string]interface{}) (entit
 			"role, " +
 			"password, " +
 			"profile_picture, " +
+			"is_protected, " +
 			"(SELECT COUNT(*) FROM follows WHERE user_id = id AND deleted_at IS NULL), " +
 			"(SELECT COUNT(*) FROM follows WHERE following_id = id AND deleted_at is null)")
 
@@ -259,7 +271,8 @@ func (u *userRepo) Get(ctx context.Context, field map[string]interface{}) (entit
 		&NullBio,
 		&result.Role,
 		&result.Password,
-		&NulPhoto,
+		&result.ProfilePicture,
+		&result.IsProtected,
 		&result.FollowingCount,
 		&result.FollowersCount,
 	)
@@ -268,10 +281,6 @@ func (u *userRepo) Get(ctx context.Context, field map[string]interface{}) (entit
 		return entity.GetUserResponse{}, err
 	}
 
-	if NulPhoto.Valid {
-		result.ProfilePicture = &NulPhoto.String
-	}
-
 	if NullBio.Valid {
 		result.Bio = &NullBio.String
 	}
@@ -313,9 +322,8 @@ func (u *userRepo) List(ctx context.Context, filter entity.Filter) (entity.ListU
 
 	for rows.Next() {
 		var (
-			user     entity.GetUserResponse
-			NullBio  sql.NullString
-			NulPhoto sql.NullString
+			user    entity.GetUserResponse
+			NullBio sql.NullString
 		)
 
 		err := rows.Scan(
@@ -325,7 +333,7 @@ func (u *userRepo) List(ctx context.Context, filter entity.Filter) (entity.ListU
 			&user.Email,
 			&NullBio,
 			&user.Role,
-			&NulPhoto,
+			&user.ProfilePicture,
 			&user.FollowingCount,
 			&user.FollowersCount,
 		)
@@ -334,10 +342,6 @@ func (u *userRepo) List(ctx context.Context, filter entity.Filter) (entity.ListU
 			return entity.ListUser{}, err
 		}
 
-		if NulPhoto.Valid {
-			user.ProfilePicture = &NulPhoto.String
-		}
-
 		if NullBio.Valid {
 			user.Bio = &NullBio.String
 		}
@@ -5,10 +5,29 @@ import (
 	"time"
 )
 
+// ZMember is a member of a sorted set with its score
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
 type KV interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 	Get(ctx context.Context, key string) (interface{}, error)
 	Del(ctx context.Context, key string) error
+
+	// ZReplace atomically replaces the sorted set at key with members
+	ZReplace(ctx context.Context, key string, members []ZMember, expiration time.Duration) error
+	// ZAddCapped adds member to every existing sorted set of keys and trims each
+	// of them to the capacity highest scores, missing keys are left missing
+	ZAddCapped(ctx context.Context, keys []string, member ZMember, capacity int64) error
+	// ZRevRangeByScore returns up to count members with score <= max, highest first
+	ZRevRangeByScore(ctx context.Context, key string, max string, count int64) ([]ZMember, error)
+	// ZIncr increments the score of every member of the sorted set at key by one and sets its expiration
+	ZIncr(ctx context.Context, key string, members []string, expiration time.Duration) error
+	// ZUnionRevRange stores the union of the sorted sets of keys at dest and returns
+	// up to count members of it, highest score first
+	ZUnionRevRange(ctx context.Context, dest string, keys []string, count int64, expiration time.Duration) ([]ZMember, error)
 }
 
 var inst KV
@@ -47,3 +47,105 @@ func (r *RedisStorage) Get(ctx context.Context, key string) (interface{}, error)
 func (r *RedisStorage) Del(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
+
+func (r *RedisStorage) ZReplace(ctx context.Context, key string, members []ZMember, expiration time.Duration) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		if len(members) > 0 {
+			pipe.ZAdd(ctx, key, toZ(members)...)
+			pipe.Expire(ctx, key, expiration)
+		}
+		return nil
+	})
+	return err
+}
+
+// zAddCappedScript adds ARGV[2] with score ARGV[1] to every existing key and
+// keeps only the ARGV[3] highest scored members of each
+var zAddCappedScript = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+	if redis.call('EXISTS', key) == 1 then
+		redis.call('ZADD', key, ARGV[1], ARGV[2])
+		redis.call('ZREMRANGEBYRANK', key, 0, -(tonumber(ARGV[3]) + 1))
+	end
+end
+return 0
+`)
+
+func (r *RedisStorage) ZAddCapped(ctx context.Context, keys []string, member ZMember, capacity int64) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return zAddCappedScript.Run(ctx, r.client, keys, member.Score, member.Member, capacity).Err()
+}
+
+func (r *RedisStorage) ZRevRangeByScore(ctx context.Context, key string, max string, count int64) ([]ZMember, error) {
+	result, err := r.client.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   max,
+		Count: count,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]ZMember, 0, len(result))
+	for _, z := range result {
+		members = append(members, ZMember{
+			Member: z.Member.(string),
+			Score:  z.Score,
+		})
+	}
+
+	return members, nil
+}
+
+func (r *RedisStorage) ZIncr(ctx context.Context, key string, members []string, expiration time.Duration) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, member := range members {
+			pipe.ZIncrBy(ctx, key, 1, member)
+		}
+		pipe.Expire(ctx, key, expiration)
+		return nil
+	})
+	return err
+}
+
+func (r *RedisStorage) ZUnionRevRange(ctx context.Context, dest string, keys []string, count int64, expiration time.Duration) ([]ZMember, error) {
+	var result *redis.ZSliceCmd
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZUnionStore(ctx, dest, &redis.ZStore{Keys: keys})
+		pipe.Expire(ctx, dest, expiration)
+		result = pipe.ZRevRangeWithScores(ctx, dest, 0, count-1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]ZMember, 0, len(result.Val()))
+	for _, z := range result.Val() {
+		members = append(members, ZMember{
+			Member: z.Member.(string),
+			Score:  z.Score,
+		})
+	}
+
+	return members, nil
+}
+
+func toZ(members []ZMember) []redis.Z {
+	zs := make([]redis.Z, 0, len(members))
+	for _, m := range members {
+		zs = append(zs, redis.Z{
+			Score:  m.Score,
+			Member: m.Member,
+		})
+	}
+	return zs
+}
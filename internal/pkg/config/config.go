@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+
+	"github.com/spf13/cast"
 )
 
 type Config struct {
@@ -20,17 +22,61 @@ type Config struct {
 		TimeOut string
 	}
 
+	EventBus struct {
+		Driver string // kafka, memory
+	}
+
 	Kafka struct {
 		Brokers string
 		GroupID string
 		Topic   string
 	}
 
+	Tweet struct {
+		EditWindow string // tweets can be edited for this long after posting
+		MaxEdits   int    // edits allowed per tweet
+	}
+
+	Timeline struct {
+		FanoutThreshold int    // authors with more followers are merged into timelines on read
+		Capacity        int    // tweets kept per materialized timeline
+		TTL             string // materialized timelines are rebuilt after this duration
+	}
+
+	Outbox struct {
+		PollInterval string // pause between two polls of a drained outbox
+		BatchSize    int    // events claimed per relay batch
+		Retention    string // published events and processed keys are deleted after this duration
+	}
+
+	Poll struct {
+		CloseInterval  string // pause between two looks for expired polls
+		CloseBatchSize int    // polls closed per transaction
+	}
+
+	Scheduler struct {
+		Interval  string // pause between two looks for due scheduled tweets
+		BatchSize int    // scheduled tweets published per look at most, before looking again right away
+	}
+
+	Media struct {
+		Driver         string // s3, local
+		LocalDir       string // directory the local driver keeps files in
+		URL            string // address clients reach the /media path of the app at, media URLs start with it
+		MaxImageSize   int64  // largest image upload in bytes
+		MaxGIFSize     int64  // largest gif upload in bytes
+		MaxVideoSize   int64  // largest video upload in bytes
+		UploadURLTTL   string // how long the presigned URL of a direct upload can be used
+		SweepInterval  string // pause between two looks for direct uploads never completed
+		SweepBatchSize int    // uncompleted direct uploads dropped per look at most, before looking again right away
+	}
+
 	AWSS3 struct {
 		AWSAccessKeyID     string
 		AWSSecretAccessKey string
 		BucketName         string
 		Region             string
+		Endpoint           string // endpoint of an S3 compatible store, empty for AWS itself
 	}
 	GinMode string // debug, test, release
 
@@ -89,10 +135,48 @@ func Load() *Config {
 	cfg.AWSS3.AWSSecretAccessKey = getEnv("AWS_SECRET_ACCESS_KEY", "your_secret_access_key")
 	cfg.AWSS3.BucketName = getEnv("AWS_BUCKET_NAME", "your_aws_s3_bucket")
 	cfg.AWSS3.Region = getEnv("AWS_REGION", "your_region")
+	cfg.AWSS3.Endpoint = getEnv("AWS_ENDPOINT", "")
+
+	// media configuration, local keeps uploads on disk without a bucket
+	cfg.Media.Driver = getEnv("MEDIA_DRIVER", "s3")
+	cfg.Media.LocalDir = getEnv("MEDIA_LOCAL_DIR", "./media")
+	cfg.Media.URL = getEnv("MEDIA_URL", "/media")
+	cfg.Media.MaxImageSize = cast.ToInt64(getEnv("MEDIA_MAX_IMAGE_SIZE", "5242880"))
+	cfg.Media.MaxGIFSize = cast.ToInt64(getEnv("MEDIA_MAX_GIF_SIZE", "15728640"))
+	cfg.Media.MaxVideoSize = cast.ToInt64(getEnv("MEDIA_MAX_VIDEO_SIZE", "536870912"))
+	cfg.Media.UploadURLTTL = getEnv("MEDIA_UPLOAD_URL_TTL", "15m")
+	cfg.Media.SweepInterval = getEnv("MEDIA_SWEEP_INTERVAL", "10m")
+	cfg.Media.SweepBatchSize = cast.ToInt(getEnv("MEDIA_SWEEP_BATCH_SIZE", "100"))
+
+	// event bus configuration, memory runs without a broker
+	cfg.EventBus.Driver = getEnv("EVENT_BUS_DRIVER", "kafka")
 
 	// kafka configuration
 	cfg.Kafka.Brokers = getEnv("KAFKA_BROKER", "kafka_broker")
 	cfg.Kafka.Topic = getEnv("KAFKA_TOPIC", "kafka_topic_name")
+	cfg.Kafka.GroupID = getEnv("KAFKA_GROUP_ID", "mini-twitter")
+
+	// tweet edit configuration
+	cfg.Tweet.EditWindow = getEnv("TWEET_EDIT_WINDOW", "1h")
+	cfg.Tweet.MaxEdits = cast.ToInt(getEnv("TWEET_MAX_EDITS", "5"))
+
+	// timeline cache configuration
+	cfg.Timeline.FanoutThreshold = cast.ToInt(getEnv("TIMELINE_FANOUT_THRESHOLD", "10000"))
+	cfg.Timeline.Capacity = cast.ToInt(getEnv("TIMELINE_CAPACITY", "800"))
+	cfg.Timeline.TTL = getEnv("TIMELINE_TTL", "72h")
+
+	// outbox relay configuration
+	cfg.Outbox.PollInterval = getEnv("OUTBOX_POLL_INTERVAL", "1s")
+	cfg.Outbox.BatchSize = cast.ToInt(getEnv("OUTBOX_BATCH_SIZE", "100"))
+	cfg.Outbox.Retention = getEnv("OUTBOX_RETENTION", "168h")
+
+	// poll closer configuration
+	cfg.Poll.CloseInterval = getEnv("POLL_CLOSE_INTERVAL", "30s")
+	cfg.Poll.CloseBatchSize = cast.ToInt(getEnv("POLL_CLOSE_BATCH_SIZE", "100"))
+
+	// tweet scheduler configuration
+	cfg.Scheduler.Interval = getEnv("SCHEDULER_INTERVAL", "1s")
+	cfg.Scheduler.BatchSize = cast.ToInt(getEnv("SCHEDULER_BATCH_SIZE", "100"))
 
 	// redis configuration
 	cfg.RedisHost = getEnv("REDIS_HOST", "redis_host")
@@ -0,0 +1,40 @@
+package eventbus
+
+import (
+	"context"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+)
+
+// KeyStore remembers the idempotency keys of the events a group handled
+type KeyStore interface {
+	// Processed reports whether group handled the event of key already
+	Processed(ctx context.Context, group, key string) (bool, error)
+	// MarkProcessed stores key for group and reports whether it was new
+	MarkProcessed(ctx context.Context, group, key string) (bool, error)
+}
+
+// Deduplicate wraps handlers so that group handles an event once however often it was
+// published, the event id is its idempotency key. The key is stored once the handler
+// succeeded, an event whose handler or key failed is handled again when redelivered
+func Deduplicate(keys KeyStore, group string, handlers Handlers) Handlers {
+	deduplicated := make(Handlers, len(handlers))
+	for eventType, handler := range handlers {
+		handler := handler
+		deduplicated[eventType] = func(ctx context.Context, event entity.Event) error {
+			processed, err := keys.Processed(ctx, group, event.ID)
+			if err != nil || processed {
+				return err
+			}
+
+			if err := handler(ctx, event); err != nil {
+				return err
+			}
+
+			_, err = keys.MarkProcessed(ctx, group, event.ID)
+			return err
+		}
+	}
+
+	return deduplicated
+}
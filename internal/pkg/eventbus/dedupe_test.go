@@ -0,0 +1,97 @@
+package eventbus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/eventbus"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryKeys struct {
+	keys map[string]bool
+	err  error
+}
+
+func (m *memoryKeys) Processed(ctx context.Context, group, key string) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+
+	return m.keys[group+"/"+key], nil
+}
+
+func (m *memoryKeys) MarkProcessed(ctx context.Context, group, key string) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+
+	if m.keys[group+"/"+key] {
+		return false, nil
+	}
+
+	m.keys[group+"/"+key] = true
+	return true, nil
+}
+
+func TestDeduplicateHandlesEventOncePerGroup(t *testing.T) {
+	keys := &memoryKeys{keys: make(map[string]bool)}
+
+	var handled []string
+	handlers := eventbus.Handlers{
+		entity.EventTypeTweetCreated: func(ctx context.Context, event entity.Event) error {
+			handled = append(handled, event.ID)
+			return nil
+		},
+	}
+
+	first := eventbus.Deduplicate(keys, "first", handlers)
+	second := eventbus.Deduplicate(keys, "second", handlers)
+	event := entity.Event{ID: "1", Type: entity.EventTypeTweetCreated}
+
+	for _, group := range []eventbus.Handlers{first, first, second} {
+		assert.NoError(t, group[entity.EventTypeTweetCreated](context.Background(), event))
+	}
+
+	assert.Equal(t, []string{"1", "1"}, handled)
+}
+
+func TestDeduplicateSkipsEventWhoseKeyWasNotStored(t *testing.T) {
+	keys := &memoryKeys{err: errors.New("database unavailable")}
+
+	handlers := eventbus.Deduplicate(keys, "group", eventbus.Handlers{
+		entity.EventTypeTweetCreated: func(ctx context.Context, event entity.Event) error {
+			t.Fatal("event handled without its key")
+			return nil
+		},
+	})
+
+	err := handlers[entity.EventTypeTweetCreated](context.Background(), entity.Event{ID: "1", Type: entity.EventTypeTweetCreated})
+	assert.Error(t, err)
+}
+
+func TestDeduplicateHandlesEventAgainAfterHandlerFailed(t *testing.T) {
+	keys := &memoryKeys{keys: make(map[string]bool)}
+
+	calls := 0
+	handlers := eventbus.Deduplicate(keys, "group", eventbus.Handlers{
+		entity.EventTypeTweetCreated: func(ctx context.Context, event entity.Event) error {
+			calls++
+			if calls == 1 {
+				return errors.New("websocket gone")
+			}
+			return nil
+		},
+	})
+
+	event := entity.Event{ID: "1", Type: entity.EventTypeTweetCreated}
+	assert.Error(t, handlers[entity.EventTypeTweetCreated](context.Background(), event))
+
+	// the redelivered event is handled, the one after it is skipped
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, handlers[entity.EventTypeTweetCreated](context.Background(), event))
+	}
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,41 @@
+package eventbus
+
+import (
+	"context"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+)
+
+// drivers selectable with config.EventBus.Driver
+const (
+	DriverKafka  = "kafka"
+	DriverMemory = "memory"
+)
+
+// Start tells a group that has not consumed anything yet where to begin
+type Start int
+
+const (
+	// StartOldest replays the events the bus still holds, for workers that must not miss any
+	StartOldest Start = iota
+	// StartNewest only receives the events published from now on, for groups that serve
+	// live connections
+	StartNewest
+)
+
+// Handler processes the events of one type, a failed event is logged and skipped
+type Handler func(ctx context.Context, event entity.Event) error
+
+// Handlers maps event types to their handlers, events of other types are skipped
+type Handlers map[string]Handler
+
+// Bus carries domain events between the parts of the app
+type Bus interface {
+	// Publish sends an event, events sharing a key are delivered in the order they were published
+	Publish(ctx context.Context, key string, event entity.Event) error
+	// Subscribe delivers the events to handlers until ctx is done. Subscribers of one
+	// group share the events, every group receives all of them. A group that has not
+	// consumed anything yet begins at start
+	Subscribe(ctx context.Context, group string, start Start, handlers Handlers) error
+	Close() error
+}
@@ -0,0 +1,8 @@
+package eventbus
+
+// Join creates the queue of group on a memory bus, the events published from then on
+// wait there for the subscriber of the group
+func Join(bus Bus, group string) error {
+	_, err := bus.(*memoryBus).join(group)
+	return err
+}
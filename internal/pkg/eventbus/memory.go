@@ -0,0 +1,183 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+)
+
+// memoryQueueSize is the number of events a group may lag behind before Publish waits for it
+const memoryQueueSize = 1024
+
+var ErrClosed = errors.New("event bus is closed")
+
+// memoryGroup is the queue of a group, left is closed once its last subscriber stopped
+type memoryGroup struct {
+	queue       chan entity.Event
+	left        chan struct{}
+	subscribers int
+}
+
+type memoryBus struct {
+	mu     sync.RWMutex
+	groups map[string]*memoryGroup
+	closed bool
+	done   chan struct{}
+}
+
+// NewMemoryBus returns a bus living in the process, meant for local development and
+// tests. Events published while no group subscribed are dropped
+func NewMemoryBus() Bus {
+	return &memoryBus{
+		groups: make(map[string]*memoryGroup),
+		done:   make(chan struct{}),
+	}
+}
+
+// Publish delivers the event to every group, waiting for the ones lagging behind until ctx is
+// done. Groups delivered to before giving up get the event again when the relay retries it,
+// their subscribers deduplicate it
+func (m *memoryBus) Publish(ctx context.Context, key string, event entity.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrClosed
+	}
+
+	groups := make([]*memoryGroup, 0, len(m.groups))
+	for _, group := range m.groups {
+		groups = append(groups, group)
+	}
+	m.mu.RUnlock()
+
+	for _, group := range groups {
+		select {
+		case group.queue <- event:
+		case <-group.left:
+		case <-m.done:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Subscribe ignores start, the bus holds no events from before a group joined. The group
+// is dropped when its last subscriber stops, so publishers do not wait for it
+func (m *memoryBus) Subscribe(ctx context.Context, group string, start Start, handlers Handlers) error {
+	joined, err := m.subscribe(group)
+	if err != nil {
+		return err
+	}
+	defer m.leave(group, joined)
+
+	handle := func(event entity.Event) {
+		handler, ok := handlers[event.Type]
+		if !ok {
+			return
+		}
+
+		if err := handler(ctx, event); err != nil {
+			log.Printf("Event %s of type %s failed: %v", event.ID, event.Type, err)
+		}
+	}
+
+	for {
+		select {
+		case event := <-joined.queue:
+			handle(event)
+		case <-m.done:
+			// the events published before Close are still handled
+			for {
+				select {
+				case event := <-joined.queue:
+					handle(event)
+				default:
+					return nil
+				}
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// join returns the group, creating its queue if it has none yet
+func (m *memoryBus) join(name string) (*memoryGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.group(name)
+}
+
+// subscribe joins the group as one of its subscribers
+func (m *memoryBus) subscribe(name string) (*memoryGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, err := m.group(name)
+	if err != nil {
+		return nil, err
+	}
+	group.subscribers++
+
+	return group, nil
+}
+
+// group returns the group of name, m.mu has to be held
+func (m *memoryBus) group(name string) (*memoryGroup, error) {
+	if m.closed {
+		return nil, ErrClosed
+	}
+
+	group, ok := m.groups[name]
+	if !ok {
+		group = &memoryGroup{
+			queue: make(chan entity.Event, memoryQueueSize),
+			left:  make(chan struct{}),
+		}
+		m.groups[name] = group
+	}
+
+	return group, nil
+}
+
+// leave drops the group once its last subscriber left
+func (m *memoryBus) leave(name string, group *memoryGroup) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group.subscribers--
+	if group.subscribers > 0 {
+		return
+	}
+
+	if m.groups[name] == group {
+		delete(m.groups, name)
+	}
+	close(group.left)
+}
+
+// Close stops the subscribers once they handled the events already published
+func (m *memoryBus) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+
+	m.closed = true
+	close(m.done)
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package eventbus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/eventbus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// subscribe starts a subscriber of group collecting the events of eventType, the group
+// is joined before it returns so no event published afterwards is dropped
+func subscribe(t *testing.T, ctx context.Context, bus eventbus.Bus, group, eventType string) <-chan entity.Event {
+	require.NoError(t, eventbus.Join(bus, group))
+
+	ctx, cancel := context.WithCancel(ctx)
+	received := make(chan entity.Event, 16)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		_ = bus.Subscribe(ctx, group, eventbus.StartOldest, eventbus.Handlers{
+			eventType: func(ctx context.Context, event entity.Event) error {
+				received <- event
+				return nil
+			},
+		})
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return received
+}
+
+func receive(t *testing.T, events <-chan entity.Event) entity.Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return entity.Event{}
+	}
+}
+
+func TestMemoryBusDeliversToEveryGroupInOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.NewMemoryBus()
+	defer bus.Close()
+
+	first := subscribe(t, ctx, bus, "first", entity.EventTypeTweetCreated)
+	second := subscribe(t, ctx, bus, "second", entity.EventTypeTweetCreated)
+
+	for _, id := range []string{"1", "2", "3"} {
+		require.NoError(t, bus.Publish(ctx, "tweet", entity.Event{ID: id, Type: entity.EventTypeTweetCreated}))
+	}
+
+	for _, events := range []<-chan entity.Event{first, second} {
+		for _, id := range []string{"1", "2", "3"} {
+			assert.Equal(t, id, receive(t, events).ID)
+		}
+	}
+}
+
+func TestMemoryBusSkipsUnhandledTypes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.NewMemoryBus()
+	defer bus.Close()
+
+	events := subscribe(t, ctx, bus, "likes", entity.EventTypeLikeCreated)
+
+	require.NoError(t, bus.Publish(ctx, "tweet", entity.Event{ID: "1", Type: entity.EventTypeTweetCreated}))
+	require.NoError(t, bus.Publish(ctx, "tweet", entity.Event{ID: "2", Type: entity.EventTypeLikeCreated}))
+
+	assert.Equal(t, "2", receive(t, events).ID)
+}
+
+func TestMemoryBusPublishesNothingOnceContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	bus := eventbus.NewMemoryBus()
+	defer bus.Close()
+
+	events := subscribe(t, context.Background(), bus, "tweets", entity.EventTypeTweetCreated)
+
+	cancel()
+	err := bus.Publish(ctx, "tweet", entity.Event{ID: "1", Type: entity.EventTypeTweetCreated})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	require.NoError(t, bus.Publish(context.Background(), "tweet", entity.Event{ID: "2", Type: entity.EventTypeTweetCreated}))
+	assert.Equal(t, "2", receive(t, events).ID)
+}
+
+func TestMemoryBusPublishGivesUpOnLaggingGroup(t *testing.T) {
+	bus := eventbus.NewMemoryBus()
+
+	// a group nobody reads from fills up
+	require.NoError(t, eventbus.Join(bus, "stalled"))
+	for i := 0; i < 1024; i++ {
+		require.NoError(t, bus.Publish(context.Background(), "tweet", entity.Event{ID: "1", Type: entity.EventTypeTweetCreated}))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := bus.Publish(ctx, "tweet", entity.Event{ID: "2", Type: entity.EventTypeTweetCreated})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	closed := make(chan error, 1)
+	go func() { closed <- bus.Close() }()
+
+	select {
+	case err := <-closed:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("close waited for a publisher")
+	}
+}
+
+func TestMemoryBusDropsGroupOfStoppedSubscriber(t *testing.T) {
+	bus := eventbus.NewMemoryBus()
+	defer bus.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	require.NoError(t, eventbus.Join(bus, "stopped"))
+	go func() {
+		defer close(done)
+		_ = bus.Subscribe(ctx, "stopped", eventbus.StartOldest, eventbus.Handlers{})
+	}()
+
+	cancel()
+	<-done
+
+	// more events than the queue of the group holds go out without waiting for it
+	publishCtx, stop := context.WithTimeout(context.Background(), time.Second)
+	defer stop()
+
+	for i := 0; i < 2048; i++ {
+		require.NoError(t, bus.Publish(publishCtx, "tweet", entity.Event{ID: "1", Type: entity.EventTypeTweetCreated}))
+	}
+}
+
+func TestMemoryBusRejectsPublishAfterClose(t *testing.T) {
+	bus := eventbus.NewMemoryBus()
+	require.NoError(t, bus.Close())
+
+	err := bus.Publish(context.Background(), "tweet", entity.Event{ID: "1"})
+	assert.ErrorIs(t, err, eventbus.ErrClosed)
+}
@@ -0,0 +1,262 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+)
+
+// names of the renditions of an image, the WebP renditions of a jpeg or png carry the webp suffix
+const (
+	RenditionOriginal  = "original"
+	RenditionMedium    = "medium"
+	RenditionThumbnail = "thumbnail"
+	WebPSuffix         = "_webp"
+)
+
+const (
+	// mediumSize is the longest side of the medium rendition
+	mediumSize = 1200
+	// thumbnailSize is the side of the square thumbnail
+	thumbnailSize = 150
+	// maxImagePixels keeps small files describing huge images from being decoded
+	maxImagePixels = 50_000_000
+	jpegQuality    = 85
+)
+
+var ErrImageTooLarge = errors.New("image is too large")
+
+// Rendition is an encoded version of an uploaded image
+type Rendition struct {
+	Name        string
+	ContentType string
+	Extension   string
+	Data        []byte
+}
+
+type imageFormat struct {
+	contentType string
+	extension   string
+	encode      func(w io.Writer, img image.Image) error
+}
+
+var (
+	jpegFormat = imageFormat{"image/jpeg", ".jpg", func(w io.Writer, img image.Image) error {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	}}
+	pngFormat = imageFormat{"image/png", ".png", func(w io.Writer, img image.Image) error {
+		return png.Encode(w, img)
+	}}
+	webpFormat = imageFormat{"image/webp", ".webp", func(w io.Writer, img image.Image) error {
+		return nativewebp.Encode(w, img, nil)
+	}}
+)
+
+// ProcessImage decodes a jpeg, png or WebP image, turns it upright by its EXIF orientation and
+// encodes the original, medium and thumbnail renditions of it in its own format and as WebP.
+// The renditions are encoded from the pixels alone, so EXIF, GPS and other metadata are dropped
+func ProcessImage(data []byte) ([]Rendition, error) {
+	config, name, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Width*config.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	formats := []imageFormat{webpFormat}
+	suffixes := []string{""}
+	switch name {
+	case "jpeg":
+		img = orient(img, jpegOrientation(data))
+		formats = []imageFormat{jpegFormat, webpFormat}
+		suffixes = []string{"", WebPSuffix}
+	case "png":
+		formats = []imageFormat{pngFormat, webpFormat}
+		suffixes = []string{"", WebPSuffix}
+	}
+
+	sizes := []struct {
+		name string
+		img  image.Image
+	}{
+		{RenditionOriginal, img},
+		{RenditionMedium, fit(img, mediumSize)},
+		{RenditionThumbnail, square(img, thumbnailSize)},
+	}
+
+	var renditions []Rendition
+	for _, size := range sizes {
+		for i, format := range formats {
+			var buf bytes.Buffer
+			if err := format.encode(&buf, size.img); err != nil {
+				return nil, err
+			}
+
+			renditions = append(renditions, Rendition{
+				Name:        size.name + suffixes[i],
+				ContentType: format.contentType,
+				Extension:   format.extension,
+				Data:        buf.Bytes(),
+			})
+		}
+	}
+
+	return renditions, nil
+}
+
+// fit scales img down to have no side longer than size, smaller images are kept as they are
+func fit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+
+	return dst
+}
+
+// square crops the middle square out of img and scales it to size, smaller squares are not scaled up
+func square(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+
+	crop := image.Rect(0, 0, side, side).Add(bounds.Min).Add(image.Pt((bounds.Dx()-side)/2, (bounds.Dy()-side)/2))
+	size = min(size, side)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+
+	return dst
+}
+
+// orient applies an EXIF orientation to img, the orientations from 5 to 8 swap its sides
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	w, h := bounds.Dx(), bounds.Dy()
+	dstWidth, dstHeight := w, h
+	if orientation >= 5 {
+		dstWidth, dstHeight = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation of a jpeg, 1 stands for upright or unknown
+func jpegOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// the metadata segments come before the start of scan
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF && data[i+1] != 0xDA; {
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : i+2+length]
+		if data[i+1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+
+		i += 2 + length
+	}
+
+	return 1
+}
+
+// exifOrientation finds the orientation tag in the first directory of an EXIF block
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+
+	return 1
+}
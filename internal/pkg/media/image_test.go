@@ -0,0 +1,136 @@
+package media_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sample draws a blue width x height picture with a red square in its top left corner
+func sample(width, height int) image.Image {
+	side := min(width, height) / 2
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < side && y < side {
+				img.Set(x, y, color.NRGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.NRGBA{0, 0, 255, 255})
+			}
+		}
+	}
+
+	return img
+}
+
+// jpegWithOrientation encodes img as a jpeg carrying an EXIF block with the orientation and a GPS marker
+func jpegWithOrientation(t *testing.T, img image.Image, orientation uint16) []byte {
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 100}))
+
+	// a little endian TIFF header followed by a directory holding the orientation alone
+	tiff := []byte("II*\x00\x08\x00\x00\x00\x01\x00")
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry[0:], 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:], 3)
+	binary.LittleEndian.PutUint32(entry[4:], 1)
+	binary.LittleEndian.PutUint16(entry[8:], orientation)
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0, 0, 0, 0)
+	tiff = append(tiff, []byte("GPSLatitude")...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	data := encoded.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func renditions(t *testing.T, data []byte) map[string]media.Rendition {
+	processed, err := media.ProcessImage(data)
+	require.NoError(t, err)
+
+	byName := make(map[string]media.Rendition)
+	for _, rendition := range processed {
+		byName[rendition.Name] = rendition
+	}
+
+	return byName
+}
+
+func TestProcessImageStripsMetadataAndTurnsUpright(t *testing.T) {
+	data := jpegWithOrientation(t, sample(40, 20), 6)
+	require.True(t, bytes.Contains(data, []byte("GPSLatitude")))
+
+	original := renditions(t, data)[media.RenditionOriginal]
+	assert.Equal(t, "image/jpeg", original.ContentType)
+	assert.False(t, bytes.Contains(original.Data, []byte("Exif")))
+	assert.False(t, bytes.Contains(original.Data, []byte("GPSLatitude")))
+
+	img, err := jpeg.Decode(bytes.NewReader(original.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(20, 40), img.Bounds().Size())
+
+	// turned clockwise, the top left corner ends up top right
+	r, _, b, _ := img.At(15, 4).RGBA()
+	assert.Greater(t, r, b)
+
+	r, _, b, _ = img.At(4, 4).RGBA()
+	assert.Less(t, r, b)
+}
+
+func TestProcessImageProducesSizesAndWebP(t *testing.T) {
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, sample(2400, 1600)))
+
+	byName := renditions(t, encoded.Bytes())
+	require.Len(t, byName, 6)
+
+	sizes := map[string]image.Point{
+		media.RenditionOriginal:  image.Pt(2400, 1600),
+		media.RenditionMedium:    image.Pt(1200, 800),
+		media.RenditionThumbnail: image.Pt(150, 150),
+	}
+	for name, size := range sizes {
+		rendition := byName[name]
+		assert.Equal(t, "image/png", rendition.ContentType)
+
+		config, format, err := image.DecodeConfig(bytes.NewReader(rendition.Data))
+		require.NoError(t, err)
+		assert.Equal(t, "png", format)
+		assert.Equal(t, size, image.Pt(config.Width, config.Height), name)
+
+		webp := byName[name+media.WebPSuffix]
+		assert.Equal(t, "image/webp", webp.ContentType)
+
+		config, format, err = image.DecodeConfig(bytes.NewReader(webp.Data))
+		require.NoError(t, err)
+		assert.Equal(t, "webp", format)
+		assert.Equal(t, size, image.Pt(config.Width, config.Height), name)
+	}
+}
+
+func TestProcessImageRejectsOversizedImages(t *testing.T) {
+	// a png header claiming a huge picture is turned down before decoding
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, sample(1, 1)))
+
+	data := encoded.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	_, err := media.ProcessImage(data)
+	assert.ErrorIs(t, err, media.ErrImageTooLarge)
+}
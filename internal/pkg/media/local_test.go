@@ -0,0 +1,65 @@
+package media_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorePutWritesFile(t *testing.T) {
+	dir := t.TempDir()
+	store := media.NewLocalStore(dir)
+
+	err := store.Put(context.Background(), "images/a.png", "image/png", strings.NewReader("content"), 7)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "images", "a.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+}
+
+func TestLocalStorePutKeepsSizeBytes(t *testing.T) {
+	dir := t.TempDir()
+	store := media.NewLocalStore(dir)
+
+	err := store.Put(context.Background(), "a.png", "image/png", strings.NewReader("content and more"), 7)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "a.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestLocalStoreOpen(t *testing.T) {
+	dir := t.TempDir()
+	store := media.NewLocalStore(dir)
+
+	_, _, err := store.Open(context.Background(), "images/a.png")
+	assert.ErrorIs(t, err, media.ErrObjectNotFound)
+
+	err = store.Put(context.Background(), "images/a.png", "image/png", strings.NewReader("content"), 7)
+	require.NoError(t, err)
+
+	file, object, err := store.Open(context.Background(), "images/a.png")
+	require.NoError(t, err)
+	defer file.Close()
+	assert.Equal(t, int64(7), object.Size)
+
+	_, err = file.Seek(3, io.SeekStart)
+	require.NoError(t, err)
+
+	rest, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, "tent", string(rest))
+}
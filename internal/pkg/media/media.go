@@ -0,0 +1,47 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// drivers selectable with config.Media.Driver
+const (
+	DriverS3    = "s3"
+	DriverLocal = "local"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+// Store keeps the bytes of uploaded media under keys chosen by the caller. Objects are private,
+// the app serves them to the viewers allowed to see them
+type Store interface {
+	// Put saves size bytes of body under key, an existing object with that key is replaced
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+	// Open returns the object under key for reading from any offset, a missing object gives ErrObjectNotFound
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, Object, error)
+}
+
+// Object describes a stored object, ETag changes whenever the object is replaced
+type Object struct {
+	Size int64
+	ETag string
+}
+
+// DirectStore is a Store clients can upload to themselves through presigned URLs, so the
+// bytes of an upload never pass through the app. A missing object gives ErrObjectNotFound
+type DirectStore interface {
+	Store
+	// PresignPut returns a URL size bytes of contentType can be PUT under key with until expires passes
+	PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (string, error)
+	// Stat describes the object under key
+	Stat(ctx context.Context, key string) (Object, error)
+	// Read returns the first n bytes of the object under key
+	Read(ctx context.Context, key string, n int64) ([]byte, error)
+	// Copy copies the object under from to the key to, as long as it is still the object described
+	Copy(ctx context.Context, from string, object Object, to, contentType string) error
+	// Delete removes the object under key, a missing object is no error
+	Delete(ctx context.Context, key string) error
+}
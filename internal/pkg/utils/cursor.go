@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor makes an opaque page cursor from the last item of a page
+func EncodeCursor(cursor entity.Cursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor made by EncodeCursor, an empty string means the first page
+func DecodeCursor(cursor string) (*entity.Cursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &entity.Cursor{
+		CreatedAt: createdAt,
+		ID:        parts[1],
+	}, nil
+}
+
+// NewTimelineResponse cuts a page fetched with limit+1 rows and sets the next cursor
+func NewTimelineResponse(tweets []entity.GetTweetResponse, limit int) entity.TimelineResponse {
+	var response entity.TimelineResponse
+
+	if len(tweets) > limit {
+		tweets = tweets[:limit]
+		last := tweets[len(tweets)-1]
+		response.NextCursor = EncodeCursor(entity.Cursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		})
+	}
+
+	response.Tweets = tweets
+
+	return response
+}
@@ -0,0 +1,55 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := entity.Cursor{
+		CreatedAt: time.Date(2024, 5, 1, 10, 30, 0, 123456000, time.UTC),
+		ID:        uuid.NewString(),
+	}
+
+	decoded, err := utils.DecodeCursor(utils.EncodeCursor(cursor))
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, cursor.ID, decoded.ID)
+}
+
+func TestDecodeCursor(t *testing.T) {
+	first, err := utils.DecodeCursor("")
+	assert.NoError(t, err)
+	assert.Nil(t, first)
+
+	for _, cursor := range []string{"%%%", "bm8tc2VwYXJhdG9y", "bm90LWEtdGltZXxpZA"} {
+		_, err := utils.DecodeCursor(cursor)
+		assert.ErrorIs(t, err, utils.ErrInvalidCursor, cursor)
+	}
+}
+
+func TestNewTimelineResponseCutsTheExtraRow(t *testing.T) {
+	now := time.Now()
+	tweets := []entity.GetTweetResponse{
+		{ID: "3", CreatedAt: now},
+		{ID: "2", CreatedAt: now.Add(-time.Minute)},
+		{ID: "1", CreatedAt: now.Add(-2 * time.Minute)},
+	}
+
+	page := utils.NewTimelineResponse(tweets, 2)
+	assert.Len(t, page.Tweets, 2)
+
+	cursor, err := utils.DecodeCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, "2", cursor.ID)
+
+	last := utils.NewTimelineResponse(tweets[2:], 2)
+	assert.Len(t, last.Tweets, 1)
+	assert.Empty(t, last.NextCursor)
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// hashtagPattern matches a # that does not continue a word, the tag itself is capped at 100 runes
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/])#([\p{L}\p{N}_]{1,100})`)
+
+// ExtractHashtags returns the distinct lower cased hashtags of a tweet in order of appearance,
+// tags made only of digits are ignored
+func ExtractHashtags(content string) []string {
+	var (
+		tags []string
+		seen = make(map[string]bool)
+	)
+
+	for _, match := range hashtagPattern.FindAllStringSubmatch(content, -1) {
+		tag := strings.ToLower(match[1])
+		if seen[tag] || strings.IndexFunc(tag, unicode.IsLetter) < 0 {
+			continue
+		}
+
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
@@ -0,0 +1,23 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	cases := map[string][]string{
+		"#Go and #go again":                    {"go"},
+		"learning #golang, #Postgres!":         {"golang", "postgres"},
+		"#2024 is a number but #go2024 is not": {"go2024"},
+		"no#tag, a&#39; or a/#path":            nil,
+		"#тег works in any script":             {"тег"},
+		"under_#score and (#paren)":            {"paren"},
+	}
+
+	for content, want := range cases {
+		assert.Equal(t, want, utils.ExtractHashtags(content), content)
+	}
+}
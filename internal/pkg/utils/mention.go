@@ -0,0 +1,25 @@
+package utils
+
+import "regexp"
+
+// mentionPattern matches an @ that does not continue a word or an email address
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@([A-Za-z0-9_]{1,20})`)
+
+// ExtractMentions returns the distinct usernames mentioned in a tweet in order of appearance
+func ExtractMentions(content string) []string {
+	var (
+		usernames []string
+		seen      = make(map[string]bool)
+	)
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if seen[match[1]] {
+			continue
+		}
+
+		seen[match[1]] = true
+		usernames = append(usernames, match[1])
+	}
+
+	return usernames
+}
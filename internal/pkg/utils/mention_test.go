@@ -0,0 +1,22 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractMentions(t *testing.T) {
+	cases := map[string][]string{
+		"@alice and @bob, @alice again": {"alice", "bob"},
+		"mail me at bob@example.com":    nil,
+		"(@carol) said hi to.@dave":     {"carol"},
+		"@a_very_long_username_over_20": {"a_very_long_username"},
+		"@@eve is not a mention either": nil,
+	}
+
+	for content, want := range cases {
+		assert.Equal(t, want, utils.ExtractMentions(content), content)
+	}
+}
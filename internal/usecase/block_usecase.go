@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+)
+
+type blockService struct {
+	ctxTimeout time.Duration
+	repo       repo.BlockStorageI
+	timeline   *TimelineCache
+}
+
+func NewBlockService(timeout time.Duration, repository repo.BlockStorageI, timeline *TimelineCache) Block {
+	return &blockService{
+		ctxTimeout: timeout,
+		repo:       repository,
+		timeline:   timeline,
+	}
+}
+
+func (b *blockService) Block(ctx context.Context, block entity.BlockAction) (bool, error) {
+	blocked, err := b.repo.Block(ctx, block)
+	if err != nil {
+		return false, err
+	}
+
+	// blocking drops the follows of both users, their timelines lose each other's tweets
+	if blocked {
+		for _, id := range []string{block.UserID, block.BlockedID} {
+			if err := b.timeline.Refresh(ctx, id); err != nil {
+				log.Println(err.Error())
+			}
+		}
+	}
+
+	return blocked, nil
+}
+
+func (b *blockService) Mute(ctx context.Context, mute entity.MuteAction) (bool, error) {
+	return b.repo.Mute(ctx, mute)
+}
+
+func (b *blockService) Blocked(ctx context.Context, id string) (entity.ListUser, error) {
+	return b.repo.Blocked(ctx, id)
+}
+
+func (b *blockService) Muted(ctx context.Context, id string) (entity.ListUser, error) {
+	return b.repo.Muted(ctx, id)
+}
@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+)
+
+type bookmarkService struct {
+	ctxTimeout time.Duration
+	repo       repo.BookmarkStorageI
+}
+
+func NewBookmarkService(timeout time.Duration, repository repo.BookmarkStorageI) Bookmark {
+	return &bookmarkService{
+		ctxTimeout: timeout,
+		repo:       repository,
+	}
+}
+
+func (b *bookmarkService) Bookmark(ctx context.Context, bookmark entity.BookmarkAction) (bool, error) {
+	return b.repo.Bookmark(ctx, bookmark)
+}
+
+func (b *bookmarkService) RemoveBookmark(ctx context.Context, userID, tweetID string) error {
+	return b.repo.RemoveBookmark(ctx, userID, tweetID)
+}
+
+func (b *bookmarkService) Bookmarks(ctx context.Context, filter entity.BookmarkFilter) (entity.TimelineResponse, error) {
+	return b.repo.Bookmarks(ctx, filter)
+}
+
+func (b *bookmarkService) CreateFolder(ctx context.Context, request entity.CreateBookmarkFolderRequest) (entity.BookmarkFolder, error) {
+	return b.repo.CreateFolder(ctx, request)
+}
+
+func (b *bookmarkService) Folders(ctx context.Context, userID string) (entity.BookmarkFoldersResponse, error) {
+	return b.repo.Folders(ctx, userID)
+}
+
+func (b *bookmarkService) DeleteFolder(ctx context.Context, userID, folderID string) error {
+	return b.repo.DeleteFolder(ctx, userID, folderID)
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+)
+
+type draftService struct {
+	ctxTimeout time.Duration
+	repo       repo.DraftStorageI
+	timeline   *TimelineCache
+	trends     Trend
+}
+
+func NewDraftService(timeout time.Duration, repository repo.DraftStorageI, timeline *TimelineCache, trends Trend) Draft {
+	return &draftService{
+		ctxTimeout: timeout,
+		repo:       repository,
+		timeline:   timeline,
+		trends:     trends,
+	}
+}
+
+func (d *draftService) CreateDraft(ctx context.Context, request entity.DraftRequest) (entity.Draft, error) {
+	return d.repo.CreateDraft(ctx, request)
+}
+
+func (d *draftService) UpdateDraft(ctx context.Context, request entity.DraftRequest) (entity.Draft, error) {
+	return d.repo.UpdateDraft(ctx, request)
+}
+
+func (d *draftService) DeleteDraft(ctx context.Context, userID, id string) error {
+	return d.repo.DeleteDraft(ctx, userID, id)
+}
+
+func (d *draftService) Drafts(ctx context.Context, userID string, scheduled bool) (entity.DraftsResponse, error) {
+	return d.repo.Drafts(ctx, userID, scheduled)
+}
+
+func (d *draftService) PublishDraft(ctx context.Context, userID, id string) (entity.CreateTweetResponse, error) {
+	response, err := d.repo.PublishDraft(ctx, userID, id, prepareTweet)
+	if err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	d.afterPublish(ctx, response)
+
+	return response, nil
+}
+
+// PublishDue publishes the scheduled tweets whose time came and returns how many went out
+func (d *draftService) PublishDue(ctx context.Context, limit int) (int, error) {
+	published, err := d.repo.PublishDue(ctx, limit, prepareTweet)
+
+	// the tweets published before a failure are out and still have to reach the timelines
+	for _, response := range published {
+		d.afterPublish(ctx, response)
+	}
+
+	return len(published), err
+}
+
+// afterPublish fans a published draft out like a tweet posted right away
+func (d *draftService) afterPublish(ctx context.Context, response entity.CreateTweetResponse) {
+	pushToTimelines(ctx, d.timeline, response)
+
+	// trends are best effort, a lost count does not fail the tweet
+	if err := d.trends.Record(ctx, response.Hashtags, response.CreatedAt); err != nil {
+		log.Println(err.Error())
+	}
+}
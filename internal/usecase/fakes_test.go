@@ -0,0 +1,382 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	cache "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/redis"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/media"
+	"github.com/redis/go-redis/v9"
+)
+
+// memoryKV keeps what redis would in maps, expirations are ignored
+type memoryKV struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+	sets   map[string]map[string]float64
+}
+
+func newMemoryKV() *memoryKV {
+	return &memoryKV{
+		values: make(map[string]interface{}),
+		sets:   make(map[string]map[string]float64),
+	}
+}
+
+func (m *memoryKV) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values[key] = value
+	return nil
+}
+
+func (m *memoryKV) Get(ctx context.Context, key string) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, ok := m.values[key]
+	if !ok {
+		return nil, redis.Nil
+	}
+	return value, nil
+}
+
+func (m *memoryKV) Del(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.values, key)
+	delete(m.sets, key)
+	return nil
+}
+
+func (m *memoryKV) ZReplace(ctx context.Context, key string, members []cache.ZMember, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sets, key)
+	if len(members) > 0 {
+		m.sets[key] = make(map[string]float64)
+		for _, member := range members {
+			m.sets[key][member.Member] = member.Score
+		}
+	}
+	return nil
+}
+
+func (m *memoryKV) ZAddCapped(ctx context.Context, keys []string, member cache.ZMember, capacity int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		set, ok := m.sets[key]
+		if !ok {
+			continue
+		}
+
+		set[member.Member] = member.Score
+		if sorted := m.sorted(key); int64(len(sorted)) > capacity {
+			for _, dropped := range sorted[capacity:] {
+				delete(set, dropped.Member)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *memoryKV) ZRevRangeByScore(ctx context.Context, key string, max string, count int64) ([]cache.ZMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limit, err := strconv.ParseFloat(max, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []cache.ZMember
+	for _, member := range m.sorted(key) {
+		if member.Score <= limit && int64(len(members)) < count {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (m *memoryKV) ZIncr(ctx context.Context, key string, members []string, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sets[key] == nil {
+		m.sets[key] = make(map[string]float64)
+	}
+	for _, member := range members {
+		m.sets[key][member]++
+	}
+	return nil
+}
+
+func (m *memoryKV) ZUnionRevRange(ctx context.Context, dest string, keys []string, count int64, expiration time.Duration) ([]cache.ZMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	union := make(map[string]float64)
+	for _, key := range keys {
+		for member, score := range m.sets[key] {
+			union[member] += score
+		}
+	}
+	m.sets[dest] = union
+
+	sorted := m.sorted(dest)
+	if int64(len(sorted)) > count {
+		sorted = sorted[:count]
+	}
+	return sorted, nil
+}
+
+// sorted returns the members of the set at key, highest score first
+func (m *memoryKV) sorted(key string) []cache.ZMember {
+	members := make([]cache.ZMember, 0, len(m.sets[key]))
+	for member, score := range m.sets[key] {
+		members = append(members, cache.ZMember{Member: member, Score: score})
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score == members[j].Score {
+			return members[i].Member > members[j].Member
+		}
+		return members[i].Score > members[j].Score
+	})
+	return members
+}
+
+// zmembers lists the members of the set at key, highest score first
+func (m *memoryKV) zmembers(key string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var members []string
+	for _, member := range m.sorted(key) {
+		members = append(members, member.Member)
+	}
+	return members
+}
+
+// memoryStore keeps objects in a map, clients can upload to it directly like to a bucket
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		objects: make(map[string][]byte),
+	}
+}
+
+// readSeekNopCloser is a bytes.Reader with nothing to close
+type readSeekNopCloser struct {
+	*bytes.Reader
+}
+
+func (readSeekNopCloser) Close() error {
+	return nil
+}
+
+func (m *memoryStore) Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error {
+	data, err := io.ReadAll(io.LimitReader(body, size))
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.objects[key] = data
+	return nil
+}
+
+func (m *memoryStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, media.Object, error) {
+	object, err := m.Stat(ctx, key)
+	if err != nil {
+		return nil, media.Object{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return readSeekNopCloser{bytes.NewReader(m.objects[key])}, object, nil
+}
+
+func (m *memoryStore) PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (string, error) {
+	return "https://bucket.example/" + key, nil
+}
+
+func (m *memoryStore) Stat(ctx context.Context, key string) (media.Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.objects[key]
+	if !ok {
+		return media.Object{}, media.ErrObjectNotFound
+	}
+	return media.Object{Size: int64(len(data)), ETag: strconv.FormatUint(uint64(crc32.ChecksumIEEE(data)), 16)}, nil
+}
+
+func (m *memoryStore) Read(ctx context.Context, key string, n int64) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, media.ErrObjectNotFound
+	}
+	return bytes.Clone(data[:min(n, int64(len(data)))]), nil
+}
+
+func (m *memoryStore) Copy(ctx context.Context, from string, object media.Object, to, contentType string) error {
+	current, err := m.Stat(ctx, from)
+	if err != nil {
+		return err
+	}
+	if current != object {
+		return errors.New("object changed")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.objects[to] = bytes.Clone(m.objects[from])
+	return nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.objects, key)
+	return nil
+}
+
+// keys lists the keys of the objects stored, in order
+func (m *memoryStore) keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]string, 0, len(m.objects))
+	for key := range m.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// memoryMedia keeps media by id, a pending media is only found by its uploader
+type memoryMedia struct {
+	repo.MediaStorageI
+	mu    sync.Mutex
+	media map[string]entity.Media
+}
+
+func newMemoryMedia() *memoryMedia {
+	return &memoryMedia{
+		media: make(map[string]entity.Media),
+	}
+}
+
+func (m *memoryMedia) CreateMedia(ctx context.Context, file entity.Media) (entity.Media, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if file.CreatedAt.IsZero() {
+		file.CreatedAt = time.Now()
+	}
+	m.media[file.ID] = file
+	return file, nil
+}
+
+func (m *memoryMedia) PendingMedia(ctx context.Context, userID, id string) (entity.Media, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, ok := m.media[id]
+	if !ok || file.UserID != userID || file.Status != entity.MediaStatusPending {
+		return entity.Media{}, sql.ErrNoRows
+	}
+	return file, nil
+}
+
+func (m *memoryMedia) CompleteMedia(ctx context.Context, file entity.Media) (entity.Media, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file.Status = entity.MediaStatusReady
+	m.media[file.ID] = file
+	return file, nil
+}
+
+func (m *memoryMedia) DeletePendingMedia(ctx context.Context, userID, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if file, ok := m.media[id]; ok && file.UserID == userID && file.Status == entity.MediaStatusPending {
+		delete(m.media, id)
+	}
+	return nil
+}
+
+func (m *memoryMedia) StalePendingMedia(ctx context.Context, before time.Time, limit int) ([]entity.Media, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var stale []entity.Media
+	for _, file := range m.media {
+		if file.Status == entity.MediaStatusPending && file.CreatedAt.Before(before) {
+			stale = append(stale, file)
+		}
+	}
+
+	sort.Slice(stale, func(i, j int) bool { return stale[i].CreatedAt.Before(stale[j].CreatedAt) })
+	if len(stale) > limit {
+		stale = stale[:limit]
+	}
+	return stale, nil
+}
+
+// stored returns the media saved under id
+func (m *memoryMedia) stored(id string) (entity.Media, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, ok := m.media[id]
+	return file, ok
+}
+
+// backdate moves the creation of the media saved under id by ago into the past
+func (m *memoryMedia) backdate(id string, ago time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file := m.media[id]
+	file.CreatedAt = file.CreatedAt.Add(-ago)
+	m.media[id] = file
+}
+
+// count tells how many media are saved
+func (m *memoryMedia) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.media)
+}
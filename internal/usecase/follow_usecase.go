@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
@@ -11,17 +12,52 @@ import (
 type followService struct {
 	ctxTimeout time.Duration
 	repo       repo.FollowStorageI
+	timeline   *TimelineCache
 }
 
-func NewFollowService(timeout time.Duration, repository repo.FollowStorageI) Follow {
+func NewFollowService(timeout time.Duration, repository repo.FollowStorageI, timeline *TimelineCache) Follow {
 	return &followService{
 		ctxTimeout: timeout,
 		repo:       repository,
+		timeline:   timeline,
 	}
 }
 
-func (f *followService) Follow(ctx context.Context, follow entity.FollowAction) (bool, error) {
-	return f.repo.Follow(ctx, follow)
+func (f *followService) Follow(ctx context.Context, follow entity.FollowAction) (entity.FollowResponse, error) {
+	response, err := f.repo.Follow(ctx, follow)
+	if err != nil {
+		return entity.FollowResponse{}, err
+	}
+
+	// the follower's timeline gains or loses the tweets of the followed user,
+	// a pending follow request changes nothing yet
+	if !response.Requested {
+		if err := f.timeline.Refresh(ctx, follow.UserID); err != nil {
+			log.Println(err.Error())
+		}
+	}
+
+	return response, nil
+}
+
+func (f *followService) FollowRequests(ctx context.Context, id string) (entity.ListUser, error) {
+	return f.repo.FollowRequests(ctx, id)
+}
+
+func (f *followService) ApproveFollowRequest(ctx context.Context, request entity.FollowRequestAction) error {
+	if err := f.repo.AnswerFollowRequest(ctx, request, true); err != nil {
+		return err
+	}
+
+	if err := f.timeline.Refresh(ctx, request.RequesterID); err != nil {
+		log.Println(err.Error())
+	}
+
+	return nil
+}
+
+func (f *followService) RejectFollowRequest(ctx context.Context, request entity.FollowRequestAction) error {
+	return f.repo.AnswerFollowRequest(ctx, request, false)
 }
 
 func (f *followService) GetFollowings(ctx context.Context, id string) (entity.ListUser, error) {
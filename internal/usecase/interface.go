@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
 )
@@ -12,7 +13,7 @@ type User interface {
 	Create(ctx context.Context, user entity.CreateUserRequest) (entity.CreateUserResponse, error)
 	Update(ctx context.Context, user entity.UpdateUserRequest) error
 	UpdatePasswd(ctx context.Context, id string, passwd string) error
-	UploadImage(ctx context.Context, id string, url string) error
+	UploadImage(ctx context.Context, id string, picture entity.MediaURLs) error
 	Delete(ctx context.Context, id string) error
 	Get(ctx context.Context, field map[string]interface{}) (entity.GetUserResponse, error)
 	List(ctx context.Context, filter entity.Filter) (entity.ListUser, error)
@@ -22,21 +23,107 @@ type Twit interface {
 	CreateTweet(ctx context.Context, tweet entity.CreateTweetRequest) (entity.CreateTweetResponse, error)
 	UpdateTweet(ctx context.Context, tweet entity.UpdateTweetRequest) (entity.UpdateTweetResponse, error)
 	DeleteTweet(ctx context.Context, id string) error
-	GetTweet(ctx context.Context, id string) (entity.GetTweetResponse, error)
-	ListTweets(ctx context.Context, filter entity.Filter) (entity.ListTweetsResponse, error)
-	UserTweets(ctx context.Context, usrID string) (entity.ListTweetsResponse, error)
+	Retweet(ctx context.Context, retweet entity.RetweetAction) (entity.CreateTweetResponse, error)
+	UndoRetweet(ctx context.Context, retweet entity.RetweetAction) error
+	GetTweet(ctx context.Context, id, viewerID string) (entity.GetTweetResponse, error)
+	TweetHistory(ctx context.Context, id, viewerID string) (entity.TweetHistoryResponse, error)
+	ListTweets(ctx context.Context, filter entity.Filter, viewerID string) (entity.ListTweetsResponse, error)
+	UserTweets(ctx context.Context, usrID, viewerID string) (entity.ListTweetsResponse, error)
+	HomeTimeline(ctx context.Context, filter entity.TimelineFilter) (entity.TimelineResponse, error)
+	Thread(ctx context.Context, filter entity.ThreadFilter) (entity.ThreadResponse, error)
+	HashtagTweets(ctx context.Context, filter entity.HashtagFilter) (entity.TimelineResponse, error)
+	MentionTweets(ctx context.Context, filter entity.TimelineFilter) (entity.TimelineResponse, error)
+}
+
+type Trend interface {
+	Record(ctx context.Context, tags []string, at time.Time) error
+	Trends(ctx context.Context, window string, limit int) (entity.TrendsResponse, error)
 }
 
 type Search interface {
-	Search(ctx context.Context, data string) (entity.SearchResponse, error)
+	Search(ctx context.Context, data, viewerID string) (entity.SearchResponse, error)
 }
 
 type Like interface {
 	Like(ctx context.Context, like entity.LikeAction) (bool, error)
 }
 
+type Bookmark interface {
+	Bookmark(ctx context.Context, bookmark entity.BookmarkAction) (bool, error)
+	RemoveBookmark(ctx context.Context, userID, tweetID string) error
+	Bookmarks(ctx context.Context, filter entity.BookmarkFilter) (entity.TimelineResponse, error)
+	CreateFolder(ctx context.Context, request entity.CreateBookmarkFolderRequest) (entity.BookmarkFolder, error)
+	Folders(ctx context.Context, userID string) (entity.BookmarkFoldersResponse, error)
+	DeleteFolder(ctx context.Context, userID, folderID string) error
+}
+
+type Media interface {
+	Upload(ctx context.Context, upload entity.MediaUpload) (entity.Media, error)
+	StartDirectUpload(ctx context.Context, request entity.DirectUploadRequest) (entity.DirectUploadResponse, error)
+	CompleteDirectUpload(ctx context.Context, userID, id string) (entity.Media, error)
+	SweepPendingUploads(ctx context.Context, limit int) (int, error)
+	Open(ctx context.Context, id, file, viewerID string) (entity.MediaFile, error)
+}
+
+type Draft interface {
+	CreateDraft(ctx context.Context, request entity.DraftRequest) (entity.Draft, error)
+	UpdateDraft(ctx context.Context, request entity.DraftRequest) (entity.Draft, error)
+	DeleteDraft(ctx context.Context, userID, id string) error
+	Drafts(ctx context.Context, userID string, scheduled bool) (entity.DraftsResponse, error)
+	PublishDraft(ctx context.Context, userID, id string) (entity.CreateTweetResponse, error)
+	PublishDue(ctx context.Context, limit int) (int, error)
+}
+
+type Poll interface {
+	Vote(ctx context.Context, vote entity.PollVote) (entity.Poll, error)
+}
+
+type List interface {
+	CreateList(ctx context.Context, request entity.CreateListRequest) (entity.List, error)
+	GetList(ctx context.Context, id, viewerID string) (entity.List, error)
+	Lists(ctx context.Context, userID string) (entity.ListsResponse, error)
+	UpdateList(ctx context.Context, request entity.UpdateListRequest) (entity.List, error)
+	DeleteList(ctx context.Context, id, ownerID string) error
+	AddMember(ctx context.Context, request entity.ListMemberRequest) error
+	RemoveMember(ctx context.Context, request entity.ListMemberRequest) error
+	Members(ctx context.Context, listID, viewerID string) (entity.ListUser, error)
+	Subscribe(ctx context.Context, listID, userID string) (bool, error)
+	Timeline(ctx context.Context, filter entity.ListTimelineFilter) (entity.TimelineResponse, error)
+}
+
 type Follow interface {
-	Follow(ctx context.Context, follow entity.FollowAction) (bool, error)
+	Follow(ctx context.Context, follow entity.FollowAction) (entity.FollowResponse, error)
+	FollowRequests(ctx context.Context, id string) (entity.ListUser, error)
+	ApproveFollowRequest(ctx context.Context, request entity.FollowRequestAction) error
+	RejectFollowRequest(ctx context.Context, request entity.FollowRequestAction) error
 	GetFollowings(ctx context.Context, id string) (entity.ListUser, error)
 	GetFollowers(ctx context.Context, id string) (entity.ListUser, error)
 }
+
+type Block interface {
+	Block(ctx context.Context, block entity.BlockAction) (bool, error)
+	Mute(ctx context.Context, mute entity.MuteAction) (bool, error)
+	Blocked(ctx context.Context, id string) (entity.ListUser, error)
+	Muted(ctx context.Context, id string) (entity.ListUser, error)
+}
+
+type Notification interface {
+	List(ctx context.Context, filter entity.NotificationFilter) (entity.NotificationsResponse, error)
+	MarkRead(ctx context.Context, request entity.ReadNotificationsRequest) error
+}
+
+type Message interface {
+	StartConversation(ctx context.Context, request entity.StartConversationRequest) (entity.Conversation, bool, error)
+	GetConversation(ctx context.Context, id, userID string) (entity.Conversation, error)
+	ListConversations(ctx context.Context, filter entity.ConversationFilter) (entity.ConversationsResponse, error)
+	SendMessage(ctx context.Context, request entity.SendMessageRequest) (entity.Message, error)
+	ListMessages(ctx context.Context, filter entity.MessageFilter) (entity.MessagesResponse, error)
+	MarkRead(ctx context.Context, conversationID, userID string) (entity.ReadReceipt, error)
+	Mute(ctx context.Context, request entity.MuteConversationRequest) error
+	CreateGroup(ctx context.Context, request entity.CreateGroupRequest) (entity.Conversation, error)
+	UpdateGroup(ctx context.Context, request entity.UpdateGroupRequest) (entity.Conversation, error)
+	AddMembers(ctx context.Context, request entity.GroupMembersRequest) (entity.Conversation, error)
+	RemoveMember(ctx context.Context, request entity.GroupMemberRequest) error
+	SetMemberRole(ctx context.Context, request entity.GroupMemberRequest) error
+	LeaveGroup(ctx context.Context, conversationID, userID string) error
+}
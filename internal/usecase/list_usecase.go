@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+)
+
+type listService struct {
+	ctxTimeout time.Duration
+	repo       repo.ListStorageI
+}
+
+func NewListService(timeout time.Duration, repository repo.ListStorageI) List {
+	return &listService{
+		ctxTimeout: timeout,
+		repo:       repository,
+	}
+}
+
+func (l *listService) CreateList(ctx context.Context, request entity.CreateListRequest) (entity.List, error) {
+	return l.repo.CreateList(ctx, request)
+}
+
+func (l *listService) GetList(ctx context.Context, id, viewerID string) (entity.List, error) {
+	return l.repo.GetList(ctx, id, viewerID)
+}
+
+func (l *listService) Lists(ctx context.Context, userID string) (entity.ListsResponse, error) {
+	return l.repo.Lists(ctx, userID)
+}
+
+func (l *listService) UpdateList(ctx context.Context, request entity.UpdateListRequest) (entity.List, error) {
+	return l.repo.UpdateList(ctx, request)
+}
+
+func (l *listService) DeleteList(ctx context.Context, id, ownerID string) error {
+	return l.repo.DeleteList(ctx, id, ownerID)
+}
+
+func (l *listService) AddMember(ctx context.Context, request entity.ListMemberRequest) error {
+	return l.repo.AddMember(ctx, request)
+}
+
+func (l *listService) RemoveMember(ctx context.Context, request entity.ListMemberRequest) error {
+	return l.repo.RemoveMember(ctx, request)
+}
+
+func (l *listService) Members(ctx context.Context, listID, viewerID string) (entity.ListUser, error) {
+	return l.repo.Members(ctx, listID, viewerID)
+}
+
+func (l *listService) Subscribe(ctx context.Context, listID, userID string) (bool, error) {
+	return l.repo.Subscribe(ctx, listID, userID)
+}
+
+func (l *listService) Timeline(ctx context.Context, filter entity.ListTimelineFilter) (entity.TimelineResponse, error) {
+	return l.repo.Timeline(ctx, filter)
+}
@@ -0,0 +1,400 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/media"
+	"github.com/google/uuid"
+)
+
+// sniffLength is the number of leading bytes the content type of an upload is told from
+const sniffLength = 512
+
+type mediaType struct {
+	kind      string
+	extension string
+}
+
+// mediaTypes are the content types accepted for uploads
+var mediaTypes = map[string]mediaType{
+	"image/jpeg": {entity.MediaKindImage, ".jpg"},
+	"image/png":  {entity.MediaKindImage, ".png"},
+	"image/webp": {entity.MediaKindImage, ".webp"},
+	"image/gif":  {entity.MediaKindGIF, ".gif"},
+	"video/mp4":  {entity.MediaKindVideo, ".mp4"},
+	"video/webm": {entity.MediaKindVideo, ".webm"},
+}
+
+type mediaService struct {
+	ctxTimeout time.Duration
+	repo       repo.MediaStorageI
+	store      media.Store
+	maxSizes   map[string]int64
+	uploadTTL  time.Duration
+	baseURL    string
+}
+
+// NewMediaService returns the media service, maxSizes holds the largest upload in bytes per media
+// kind, uploadTTL is how long the presigned URL of a direct upload can be used and baseURL is
+// the address the app serves media under
+func NewMediaService(timeout time.Duration, repository repo.MediaStorageI, store media.Store, maxSizes map[string]int64, uploadTTL time.Duration, baseURL string) Media {
+	return &mediaService{
+		ctxTimeout: timeout,
+		repo:       repository,
+		store:      store,
+		maxSizes:   maxSizes,
+		uploadTTL:  uploadTTL,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Upload tells the type of a file from its content rather than its name or headers, checks
+// it against the kinds the caller accepts and the size limit of its kind, then stores it.
+// Images are stored as their renditions, other media byte for byte
+func (m *mediaService) Upload(ctx context.Context, upload entity.MediaUpload) (entity.Media, error) {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(upload.Body, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return entity.Media{}, err
+	}
+
+	contentType := http.DetectContentType(head[:n])
+
+	kind, ok := mediaTypes[contentType]
+	if !ok || !slices.Contains(upload.Kinds, kind.kind) {
+		return entity.Media{}, errorspkg.ErrorMediaType
+	}
+
+	if upload.Size > m.maxSizes[kind.kind] {
+		return entity.Media{}, errorspkg.ErrorMediaSize
+	}
+
+	if _, err := upload.Body.Seek(0, io.SeekStart); err != nil {
+		return entity.Media{}, err
+	}
+
+	file := entity.Media{
+		ID:             uuid.NewString(),
+		UserID:         upload.UserID,
+		Kind:           kind.kind,
+		ContentType:    contentType,
+		Size:           upload.Size,
+		Status:         entity.MediaStatusReady,
+		Purpose:        upload.Purpose,
+		ConversationID: upload.ConversationID,
+	}
+
+	if file.Kind != entity.MediaKindImage {
+		file.Key = file.ID + "/" + media.RenditionOriginal + kind.extension
+		file.URLs.Original = m.url(file.Key)
+
+		if err := m.store.Put(ctx, file.Key, file.ContentType, upload.Body, file.Size); err != nil {
+			return entity.Media{}, err
+		}
+
+		return m.repo.CreateMedia(ctx, file)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(upload.Body, upload.Size))
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	if err := m.storeImage(ctx, &file, data); err != nil {
+		return entity.Media{}, err
+	}
+
+	return m.repo.CreateMedia(ctx, file)
+}
+
+// StartDirectUpload records a pending media for a file the client announced and presigns a PUT
+// of exactly its content type and size to a key of its own, so its bytes never pass through the app
+func (m *mediaService) StartDirectUpload(ctx context.Context, request entity.DirectUploadRequest) (entity.DirectUploadResponse, error) {
+	direct, ok := m.store.(media.DirectStore)
+	if !ok {
+		return entity.DirectUploadResponse{}, errorspkg.ErrorDirectUpload
+	}
+
+	kind, ok := mediaTypes[request.ContentType]
+	if !ok {
+		return entity.DirectUploadResponse{}, errorspkg.ErrorMediaType
+	}
+
+	if request.Size > m.maxSizes[kind.kind] {
+		return entity.DirectUploadResponse{}, errorspkg.ErrorMediaSize
+	}
+
+	file := entity.Media{
+		ID:          uuid.NewString(),
+		UserID:      request.UserID,
+		Kind:        kind.kind,
+		ContentType: request.ContentType,
+		Size:        request.Size,
+		Status:      entity.MediaStatusPending,
+		Purpose:     entity.MediaPurposeTweet,
+	}
+	file.Key = uploadKey(file.ID)
+
+	expiresAt := time.Now().Add(m.uploadTTL).UTC()
+
+	uploadURL, err := direct.PresignPut(ctx, file.Key, file.ContentType, file.Size, m.uploadTTL)
+	if err != nil {
+		return entity.DirectUploadResponse{}, err
+	}
+
+	if _, err := m.repo.CreateMedia(ctx, file); err != nil {
+		return entity.DirectUploadResponse{}, err
+	}
+
+	return entity.DirectUploadResponse{
+		MediaID:   file.ID,
+		UploadURL: uploadURL,
+		Method:    http.MethodPut,
+		Headers: map[string]string{
+			"Content-Type": file.ContentType,
+		},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// CompleteDirectUpload checks the file a client uploaded against the one it announced, by its
+// size and the content type told from its bytes, and makes its media ready to be attached.
+// Images are processed into renditions like any upload and other media are copied to a key of
+// their own, so the presigned URL can not change them afterwards. A file failing the checks is
+// dropped along with its media, a file not uploaded yet gives ErrorMediaMissing
+func (m *mediaService) CompleteDirectUpload(ctx context.Context, userID, id string) (entity.Media, error) {
+	direct, ok := m.store.(media.DirectStore)
+	if !ok {
+		return entity.Media{}, errorspkg.ErrorDirectUpload
+	}
+
+	file, err := m.repo.PendingMedia(ctx, userID, id)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	object, err := direct.Stat(ctx, file.Key)
+	if errors.Is(err, media.ErrObjectNotFound) {
+		return entity.Media{}, errorspkg.ErrorMediaMissing
+	}
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	upload := file.Key
+
+	checkErr := m.storeDirectUpload(ctx, direct, &file, object)
+	if errors.Is(checkErr, errorspkg.ErrorMediaType) || errors.Is(checkErr, errorspkg.ErrorMediaSize) || errors.Is(checkErr, errorspkg.ErrorMediaMismatch) {
+		if err := direct.Delete(ctx, upload); err != nil {
+			return entity.Media{}, err
+		}
+
+		if err := m.repo.DeletePendingMedia(ctx, userID, id); err != nil {
+			return entity.Media{}, err
+		}
+	}
+	if checkErr != nil {
+		return entity.Media{}, checkErr
+	}
+
+	if err := direct.Delete(ctx, upload); err != nil {
+		return entity.Media{}, err
+	}
+
+	return m.repo.CompleteMedia(ctx, file)
+}
+
+// SweepPendingUploads drops up to limit direct uploads that were never completed along with
+// whatever the client put in the bucket for them, and returns how many went. An upload is
+// given another uploadTTL after its URL expired to be completed. The object goes first, so
+// a media whose object could not be deleted is swept again next time
+func (m *mediaService) SweepPendingUploads(ctx context.Context, limit int) (int, error) {
+	direct, ok := m.store.(media.DirectStore)
+	if !ok {
+		return 0, nil
+	}
+
+	stale, err := m.repo.StalePendingMedia(ctx, time.Now().Add(-2*m.uploadTTL).UTC(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, file := range stale {
+		if err := direct.Delete(ctx, file.Key); err != nil {
+			return i, err
+		}
+
+		if err := m.repo.DeletePendingMedia(ctx, file.UserID, file.ID); err != nil {
+			return i, err
+		}
+	}
+
+	return len(stale), nil
+}
+
+// storeDirectUpload checks the uploaded object of a pending media and stores it under its final
+// keys, filling in the key, size and URLs of file
+func (m *mediaService) storeDirectUpload(ctx context.Context, direct media.DirectStore, file *entity.Media, object media.Object) error {
+	if object.Size != file.Size {
+		return errorspkg.ErrorMediaMismatch
+	}
+
+	head, err := direct.Read(ctx, file.Key, sniffLength)
+	if err != nil {
+		return err
+	}
+
+	contentType := http.DetectContentType(head)
+
+	kind, ok := mediaTypes[contentType]
+	if !ok {
+		return errorspkg.ErrorMediaType
+	}
+
+	if contentType != file.ContentType {
+		return errorspkg.ErrorMediaMismatch
+	}
+
+	if file.Kind != entity.MediaKindImage {
+		key := file.ID + "/" + media.RenditionOriginal + kind.extension
+
+		if err := direct.Copy(ctx, file.Key, object, key, file.ContentType); err != nil {
+			return err
+		}
+
+		file.Key = key
+		file.URLs.Original = m.url(key)
+
+		return nil
+	}
+
+	data, err := direct.Read(ctx, file.Key, object.Size)
+	if err != nil {
+		return err
+	}
+
+	return m.storeImage(ctx, file, data)
+}
+
+// storeImage stores the renditions of an image, filling in the key, size and URLs of file
+func (m *mediaService) storeImage(ctx context.Context, file *entity.Media, data []byte) error {
+	renditions, err := media.ProcessImage(data)
+	if errors.Is(err, media.ErrImageTooLarge) {
+		return errorspkg.ErrorMediaSize
+	}
+	if err != nil {
+		// a file looking like an image that does not decode is not one
+		return errorspkg.ErrorMediaType
+	}
+
+	for _, rendition := range renditions {
+		key := file.ID + "/" + rendition.Name + rendition.Extension
+
+		if err := m.store.Put(ctx, key, rendition.ContentType, bytes.NewReader(rendition.Data), int64(len(rendition.Data))); err != nil {
+			return err
+		}
+
+		setRendition(&file.URLs, rendition.Name, m.url(key))
+
+		if rendition.Name == media.RenditionOriginal {
+			file.Key = key
+			file.Size = int64(len(rendition.Data))
+		}
+	}
+
+	return nil
+}
+
+// Open returns a rendition of a media the viewer may see, file is its name under the media as in
+// its URLs. Media the viewer may not see give sql.ErrNoRows like missing ones, so they stay unknown
+func (m *mediaService) Open(ctx context.Context, id, file, viewerID string) (entity.MediaFile, error) {
+	found, err := m.repo.ViewableMedia(ctx, id, viewerID)
+	if err != nil {
+		return entity.MediaFile{}, err
+	}
+
+	key := found.ID + "/" + file
+	contentType, ok := contentTypeOf(file)
+	if !ok || !hasRendition(found.URLs, key) {
+		return entity.MediaFile{}, sql.ErrNoRows
+	}
+
+	body, object, err := m.store.Open(ctx, key)
+	if errors.Is(err, media.ErrObjectNotFound) {
+		return entity.MediaFile{}, sql.ErrNoRows
+	}
+	if err != nil {
+		return entity.MediaFile{}, err
+	}
+
+	return entity.MediaFile{
+		ContentType: contentType,
+		Size:        object.Size,
+		ETag:        object.ETag,
+		ModTime:     found.CreatedAt,
+		Body:        body,
+	}, nil
+}
+
+// url is the address the app serves the object under key from
+func (m *mediaService) url(key string) string {
+	return m.baseURL + "/" + key
+}
+
+// hasRendition tells whether the object under key is one of the renditions of a media, their
+// URLs end in their keys whatever address the media were served under when they were uploaded
+func hasRendition(urls entity.MediaURLs, key string) bool {
+	for _, rendition := range []string{urls.Original, urls.Medium, urls.Thumbnail, urls.OriginalWebP, urls.MediumWebP, urls.ThumbnailWebP} {
+		if strings.HasSuffix(rendition, "/"+key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contentTypeOf tells the content type of a file of a media from its extension
+func contentTypeOf(file string) (string, bool) {
+	for contentType, mediaType := range mediaTypes {
+		if mediaType.extension == path.Ext(file) {
+			return contentType, true
+		}
+	}
+
+	return "", false
+}
+
+// uploadKey is where the client puts the file of a direct upload, apart from the keys media are served from
+func uploadKey(id string) string {
+	return "uploads/" + id
+}
+
+// setRendition files the url of a rendition under its name
+func setRendition(urls *entity.MediaURLs, name, url string) {
+	switch name {
+	case media.RenditionOriginal:
+		urls.Original = url
+	case media.RenditionMedium:
+		urls.Medium = url
+	case media.RenditionThumbnail:
+		urls.Thumbnail = url
+	case media.RenditionOriginal + media.WebPSuffix:
+		urls.OriginalWebP = url
+	case media.RenditionMedium + media.WebPSuffix:
+		urls.MediumWebP = url
+	case media.RenditionThumbnail + media.WebPSuffix:
+		urls.ThumbnailWebP = url
+	}
+}
@@ -0,0 +1,279 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// limits are the upload sizes the media services of the tests allow
+var limits = map[string]int64{
+	entity.MediaKindImage: 1 << 20,
+	entity.MediaKindGIF:   1 << 20,
+	entity.MediaKindVideo: 1 << 10,
+}
+
+// pngFile encodes a small red picture as a png
+func pngFile(t *testing.T) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.Set(x, y, color.NRGBA{255, 0, 0, 255})
+		}
+	}
+
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, img))
+	return encoded.Bytes()
+}
+
+// gifFile encodes a one frame gif
+func gifFile(t *testing.T) []byte {
+	img := image.NewPaletted(image.Rect(0, 0, 16, 16), color.Palette{color.Black, color.White})
+
+	var encoded bytes.Buffer
+	require.NoError(t, gif.Encode(&encoded, img, nil))
+	return encoded.Bytes()
+}
+
+// mp4File is an ftyp box of an mp4 followed by zeros up to size bytes
+func mp4File(size int) []byte {
+	file := make([]byte, size)
+	copy(file, "\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00isommp42")
+	return file
+}
+
+func upload(kinds []string, name string, file []byte) entity.MediaUpload {
+	return entity.MediaUpload{
+		UserID:  "uploader",
+		Purpose: entity.MediaPurposeTweet,
+		Name:    name,
+		Size:    int64(len(file)),
+		Kinds:   kinds,
+		Body:    bytes.NewReader(file),
+	}
+}
+
+func TestUploadTellsTypeFromContent(t *testing.T) {
+	all := []string{entity.MediaKindImage, entity.MediaKindGIF, entity.MediaKindVideo}
+
+	for name, tc := range map[string]struct {
+		upload      entity.MediaUpload
+		kind        string
+		contentType string
+		extension   string
+	}{
+		"png named as a video": {upload: upload(all, "clip.mp4", pngFile(t)), kind: entity.MediaKindImage, contentType: "image/png", extension: ".png"},
+		"gif named as a jpeg":  {upload: upload(all, "photo.jpg", gifFile(t)), kind: entity.MediaKindGIF, contentType: "image/gif", extension: ".gif"},
+		"mp4 named as a png":   {upload: upload(all, "photo.png", mp4File(100)), kind: entity.MediaKindVideo, contentType: "video/mp4", extension: ".mp4"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			files, store := newMemoryMedia(), newMemoryStore()
+			service := usecase.NewMediaService(time.Second, files, store, limits, time.Minute, "https://app.example/media")
+
+			uploaded, err := service.Upload(context.Background(), tc.upload)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.kind, uploaded.Kind)
+			assert.Equal(t, tc.contentType, uploaded.ContentType)
+			assert.Equal(t, entity.MediaStatusReady, uploaded.Status)
+			assert.True(t, strings.HasPrefix(uploaded.Key, uploaded.ID+"/"))
+			assert.True(t, strings.HasSuffix(uploaded.Key, tc.extension))
+			assert.Equal(t, "https://app.example/media/"+uploaded.Key, uploaded.URLs.Original)
+			assert.Contains(t, store.keys(), uploaded.Key)
+
+			saved, ok := files.stored(uploaded.ID)
+			require.True(t, ok)
+			assert.Equal(t, "uploader", saved.UserID)
+		})
+	}
+}
+
+func TestUploadRefusesKindsNotAccepted(t *testing.T) {
+	for name, tc := range map[string]entity.MediaUpload{
+		"text":              upload([]string{entity.MediaKindImage, entity.MediaKindGIF, entity.MediaKindVideo}, "photo.png", []byte("just some text")),
+		"gif as an avatar":  upload([]string{entity.MediaKindImage}, "avatar.png", gifFile(t)),
+		"video as an image": upload([]string{entity.MediaKindImage}, "avatar.png", mp4File(100)),
+		"png as a video":    upload([]string{entity.MediaKindVideo}, "clip.mp4", pngFile(t)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			files, store := newMemoryMedia(), newMemoryStore()
+			service := usecase.NewMediaService(time.Second, files, store, limits, time.Minute, "https://app.example/media")
+
+			_, err := service.Upload(context.Background(), tc)
+			assert.ErrorIs(t, err, errorspkg.ErrorMediaType)
+			assert.Empty(t, store.keys())
+			assert.Zero(t, files.count())
+		})
+	}
+}
+
+func TestUploadChecksSizeLimitOfItsKind(t *testing.T) {
+	files, store := newMemoryMedia(), newMemoryStore()
+	service := usecase.NewMediaService(time.Second, files, store, limits, time.Minute, "https://app.example/media")
+	kinds := []string{entity.MediaKindImage, entity.MediaKindVideo}
+
+	_, err := service.Upload(context.Background(), upload(kinds, "clip.mp4", mp4File(int(limits[entity.MediaKindVideo])+1)))
+	assert.ErrorIs(t, err, errorspkg.ErrorMediaSize)
+	assert.Empty(t, store.keys())
+
+	// an image is held to the image limit, larger than the video one
+	photo := pngFile(t)
+	padded := upload(kinds, "photo.png", photo)
+	padded.Size = limits[entity.MediaKindVideo] + 1
+	padded.Body = bytes.NewReader(append(photo, make([]byte, padded.Size-int64(len(photo)))...))
+
+	_, err = service.Upload(context.Background(), padded)
+	require.NoError(t, err)
+
+	video, err := service.Upload(context.Background(), upload(kinds, "clip.mp4", mp4File(int(limits[entity.MediaKindVideo]))))
+	require.NoError(t, err)
+	assert.Equal(t, limits[entity.MediaKindVideo], video.Size)
+}
+
+// direct starts a direct upload of uploader announcing contentType and size
+func direct(t *testing.T, service usecase.Media, contentType string, size int) entity.DirectUploadResponse {
+	response, err := service.StartDirectUpload(context.Background(), entity.DirectUploadRequest{UserID: "uploader", ContentType: contentType, Size: int64(size)})
+	require.NoError(t, err)
+
+	return response
+}
+
+// put uploads file to the presigned URL of response like a client would
+func put(t *testing.T, store *memoryStore, response entity.DirectUploadResponse, file []byte) {
+	key := strings.TrimPrefix(response.UploadURL, "https://bucket.example/")
+	require.NoError(t, store.Put(context.Background(), key, response.Headers["Content-Type"], bytes.NewReader(file), int64(len(file))))
+}
+
+func TestCompleteDirectUploadMovesCheckedFile(t *testing.T) {
+	files, store := newMemoryMedia(), newMemoryStore()
+	service := usecase.NewMediaService(time.Second, files, store, limits, time.Minute, "https://app.example/media")
+
+	video := mp4File(100)
+	response := direct(t, service, "video/mp4", len(video))
+	put(t, store, response, video)
+
+	completed, err := service.CompleteDirectUpload(context.Background(), "uploader", response.MediaID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.MediaStatusReady, completed.Status)
+	assert.Equal(t, "https://app.example/media/"+response.MediaID+"/original.mp4", completed.URLs.Original)
+
+	// the object the presigned URL can still write to is gone
+	assert.Equal(t, []string{response.MediaID + "/original.mp4"}, store.keys())
+
+	saved, ok := files.stored(response.MediaID)
+	require.True(t, ok)
+	assert.Equal(t, entity.MediaStatusReady, saved.Status)
+}
+
+func TestCompleteDirectUploadDropsFileFailingChecks(t *testing.T) {
+	photo := pngFile(t)
+	broken := append(bytes.Clone(photo[:64]), make([]byte, 64)...)
+
+	for name, tc := range map[string]struct {
+		contentType string
+		size        int
+		file        []byte
+		err         error
+	}{
+		"larger than announced":  {contentType: "video/mp4", size: 100, file: mp4File(101), err: errorspkg.ErrorMediaMismatch},
+		"smaller than announced": {contentType: "video/mp4", size: 100, file: mp4File(99), err: errorspkg.ErrorMediaMismatch},
+		"not a media":            {contentType: "video/mp4", size: 14, file: []byte("just some text"), err: errorspkg.ErrorMediaType},
+		"other type":             {contentType: "image/png", size: len(gifFile(t)), file: gifFile(t), err: errorspkg.ErrorMediaMismatch},
+		"image not decoding":     {contentType: "image/png", size: len(broken), file: broken, err: errorspkg.ErrorMediaType},
+	} {
+		t.Run(name, func(t *testing.T) {
+			files, store := newMemoryMedia(), newMemoryStore()
+			service := usecase.NewMediaService(time.Second, files, store, limits, time.Minute, "https://app.example/media")
+
+			response := direct(t, service, tc.contentType, tc.size)
+			put(t, store, response, tc.file)
+
+			_, err := service.CompleteDirectUpload(context.Background(), "uploader", response.MediaID)
+			assert.ErrorIs(t, err, tc.err)
+
+			assert.Empty(t, store.keys())
+			_, ok := files.stored(response.MediaID)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestCompleteDirectUploadNotUploadedYet(t *testing.T) {
+	files, store := newMemoryMedia(), newMemoryStore()
+	service := usecase.NewMediaService(time.Second, files, store, limits, time.Minute, "https://app.example/media")
+
+	response := direct(t, service, "video/mp4", 100)
+
+	_, err := service.CompleteDirectUpload(context.Background(), "uploader", response.MediaID)
+	assert.ErrorIs(t, err, errorspkg.ErrorMediaMissing)
+
+	// the client can still upload and complete it
+	saved, ok := files.stored(response.MediaID)
+	require.True(t, ok)
+	assert.Equal(t, entity.MediaStatusPending, saved.Status)
+
+	put(t, store, response, mp4File(100))
+
+	_, err = service.CompleteDirectUpload(context.Background(), "someone else", response.MediaID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Len(t, store.keys(), 1)
+
+	_, err = service.CompleteDirectUpload(context.Background(), "uploader", response.MediaID)
+	require.NoError(t, err)
+}
+
+func TestStartDirectUploadChecksAnnouncedFile(t *testing.T) {
+	files, store := newMemoryMedia(), newMemoryStore()
+	service := usecase.NewMediaService(time.Second, files, store, limits, time.Minute, "https://app.example/media")
+
+	_, err := service.StartDirectUpload(context.Background(), entity.DirectUploadRequest{UserID: "uploader", ContentType: "text/plain", Size: 10})
+	assert.ErrorIs(t, err, errorspkg.ErrorMediaType)
+
+	_, err = service.StartDirectUpload(context.Background(), entity.DirectUploadRequest{UserID: "uploader", ContentType: "video/mp4", Size: limits[entity.MediaKindVideo] + 1})
+	assert.ErrorIs(t, err, errorspkg.ErrorMediaSize)
+
+	assert.Zero(t, files.count())
+}
+
+func TestSweepPendingUploadsDropsExpiredOnes(t *testing.T) {
+	files, store := newMemoryMedia(), newMemoryStore()
+	service := usecase.NewMediaService(time.Second, files, store, limits, time.Minute, "https://app.example/media")
+
+	expired, recent, completed := direct(t, service, "video/mp4", 100), direct(t, service, "video/mp4", 100), direct(t, service, "video/mp4", 100)
+	for _, response := range []entity.DirectUploadResponse{expired, recent, completed} {
+		put(t, store, response, mp4File(100))
+	}
+
+	_, err := service.CompleteDirectUpload(context.Background(), "uploader", completed.MediaID)
+	require.NoError(t, err)
+
+	// a recent upload still has its URL, or the time to complete an upload made with it
+	files.backdate(expired.MediaID, 3*time.Minute)
+	files.backdate(recent.MediaID, 90*time.Second)
+	files.backdate(completed.MediaID, time.Hour)
+
+	swept, err := service.SweepPendingUploads(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, swept)
+
+	_, ok := files.stored(expired.MediaID)
+	assert.False(t, ok)
+	assert.Equal(t, []string{completed.MediaID + "/original.mp4", "uploads/" + recent.MediaID}, store.keys())
+
+	_, err = service.CompleteDirectUpload(context.Background(), "uploader", recent.MediaID)
+	require.NoError(t, err)
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+)
+
+type messageService struct {
+	ctxTimeout time.Duration
+	repo       repo.MessageStorageI
+}
+
+func NewMessageService(timeout time.Duration, repository repo.MessageStorageI) Message {
+	return &messageService{
+		ctxTimeout: timeout,
+		repo:       repository,
+	}
+}
+
+func (m *messageService) StartConversation(ctx context.Context, request entity.StartConversationRequest) (entity.Conversation, bool, error) {
+	return m.repo.StartConversation(ctx, request)
+}
+
+func (m *messageService) GetConversation(ctx context.Context, id, userID string) (entity.Conversation, error) {
+	return m.repo.GetConversation(ctx, id, userID)
+}
+
+func (m *messageService) ListConversations(ctx context.Context, filter entity.ConversationFilter) (entity.ConversationsResponse, error) {
+	return m.repo.ListConversations(ctx, filter)
+}
+
+// SendMessage saves a message, its delivery to the other members goes through the outbox
+func (m *messageService) SendMessage(ctx context.Context, request entity.SendMessageRequest) (entity.Message, error) {
+	return m.repo.SendMessage(ctx, request)
+}
+
+func (m *messageService) ListMessages(ctx context.Context, filter entity.MessageFilter) (entity.MessagesResponse, error) {
+	return m.repo.ListMessages(ctx, filter)
+}
+
+func (m *messageService) MarkRead(ctx context.Context, conversationID, userID string) (entity.ReadReceipt, error) {
+	return m.repo.MarkRead(ctx, conversationID, userID)
+}
+
+func (m *messageService) Mute(ctx context.Context, request entity.MuteConversationRequest) error {
+	return m.repo.Mute(ctx, request)
+}
+
+func (m *messageService) CreateGroup(ctx context.Context, request entity.CreateGroupRequest) (entity.Conversation, error) {
+	return m.repo.CreateGroup(ctx, request)
+}
+
+func (m *messageService) UpdateGroup(ctx context.Context, request entity.UpdateGroupRequest) (entity.Conversation, error) {
+	return m.repo.UpdateGroup(ctx, request)
+}
+
+func (m *messageService) AddMembers(ctx context.Context, request entity.GroupMembersRequest) (entity.Conversation, error) {
+	return m.repo.AddMembers(ctx, request)
+}
+
+func (m *messageService) RemoveMember(ctx context.Context, request entity.GroupMemberRequest) error {
+	return m.repo.RemoveMember(ctx, request)
+}
+
+func (m *messageService) SetMemberRole(ctx context.Context, request entity.GroupMemberRequest) error {
+	return m.repo.SetMemberRole(ctx, request)
+}
+
+func (m *messageService) LeaveGroup(ctx context.Context, conversationID, userID string) error {
+	return m.repo.LeaveGroup(ctx, conversationID, userID)
+}
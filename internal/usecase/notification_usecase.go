@@ -0,0 +1,29 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+)
+
+type notificationService struct {
+	ctxTimeout time.Duration
+	repo       repo.NotificationStorageI
+}
+
+func NewNotificationService(timeout time.Duration, repository repo.NotificationStorageI) Notification {
+	return &notificationService{
+		ctxTimeout: timeout,
+		repo:       repository,
+	}
+}
+
+func (n *notificationService) List(ctx context.Context, filter entity.NotificationFilter) (entity.NotificationsResponse, error) {
+	return n.repo.List(ctx, filter)
+}
+
+func (n *notificationService) MarkRead(ctx context.Context, request entity.ReadNotificationsRequest) error {
+	return n.repo.MarkRead(ctx, request)
+}
@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+)
+
+type pollService struct {
+	ctxTimeout time.Duration
+	repo       repo.PollStorageI
+}
+
+func NewPollService(timeout time.Duration, repository repo.PollStorageI) Poll {
+	return &pollService{
+		ctxTimeout: timeout,
+		repo:       repository,
+	}
+}
+
+func (p *pollService) Vote(ctx context.Context, vote entity.PollVote) (entity.Poll, error) {
+	return p.repo.Vote(ctx, vote)
+}
@@ -20,6 +20,6 @@ func NewSearchService(timeout time.Duration, repository repo.SearchStorageI) Sea
 	}
 }
 
-func (s *searchService) Search(ctx context.Context, data string) (entity.SearchResponse, error) {
-	return s.repo.Search(ctx, data)
+func (s *searchService) Search(ctx context.Context, data, viewerID string) (entity.SearchResponse, error) {
+	return s.repo.Search(ctx, data, viewerID)
 }
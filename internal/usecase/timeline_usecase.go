@@ -0,0 +1,343 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	cache "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/redis"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// timelineSentinel has the lowest score and marks a timeline that holds all of its tweets
+	timelineSentinel = "-"
+	// timelineTieSlack extra members are read to skip tweets sharing the cursor score
+	timelineTieSlack = 8
+	// fanoutBatch is the number of timelines updated by one redis call
+	fanoutBatch = 500
+)
+
+// TimelineCache materializes home timelines in redis as sorted sets of tweet ids
+// scored by creation time. New tweets are pushed to the timelines of the author's
+// followers on write, tweets of authors with more followers than the fan-out
+// threshold are merged in from postgres on read instead.
+type TimelineCache struct {
+	kv        cache.KV
+	tweets    repo.TweetStorageI
+	follows   repo.FollowStorageI
+	threshold int
+	capacity  int
+	ttl       time.Duration
+}
+
+func NewTimelineCache(cfg *config.Config, kv cache.KV, tweets repo.TweetStorageI, follows repo.FollowStorageI) (*TimelineCache, error) {
+	ttl, err := time.ParseDuration(cfg.Timeline.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TimelineCache{
+		kv:        kv,
+		tweets:    tweets,
+		follows:   follows,
+		threshold: cfg.Timeline.FanoutThreshold,
+		capacity:  cfg.Timeline.Capacity,
+		ttl:       ttl,
+	}, nil
+}
+
+func timelineKey(userID string) string {
+	return "timeline:" + userID
+}
+
+func timelineHeavyKey(userID string) string {
+	return "timeline:" + userID + ":heavy"
+}
+
+func timelineScore(createdAt time.Time) float64 {
+	return float64(createdAt.UnixMicro())
+}
+
+// Push adds a new tweet to the materialized timelines of its author and followers
+func (tc *TimelineCache) Push(ctx context.Context, entry entity.TimelineEntry) error {
+	followers, err := tc.follows.FollowerIDs(ctx, entry.UserID, tc.threshold+1)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{timelineKey(entry.UserID)}
+
+	// followers of heavy authors read their tweets from postgres
+	if len(followers) <= tc.threshold {
+		for _, id := range followers {
+			keys = append(keys, timelineKey(id))
+		}
+	}
+
+	member := cache.ZMember{
+		Member: entry.ID,
+		Score:  timelineScore(entry.CreatedAt),
+	}
+
+	for start := 0; start < len(keys); start += fanoutBatch {
+		end := min(start+fanoutBatch, len(keys))
+		if err := tc.kv.ZAddCapped(ctx, keys[start:end], member, int64(tc.capacity)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Rebuild recomputes the materialized timeline of a user from postgres
+func (tc *TimelineCache) Rebuild(ctx context.Context, userID string) error {
+	followings, err := tc.follows.FollowingIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	heavy, err := tc.follows.HeavyFollowings(ctx, userID, tc.threshold)
+	if err != nil {
+		return err
+	}
+
+	isHeavy := make(map[string]bool, len(heavy))
+	for _, id := range heavy {
+		isHeavy[id] = true
+	}
+
+	authors := []string{userID}
+	for _, id := range followings {
+		if !isHeavy[id] {
+			authors = append(authors, id)
+		}
+	}
+
+	entries, err := tc.tweets.TimelineEntries(ctx, entity.TimelineEntriesFilter{
+		UserIDs: authors,
+		Limit:   tc.capacity,
+	})
+	if err != nil {
+		return err
+	}
+
+	members := make([]cache.ZMember, 0, len(entries)+1)
+	for _, entry := range entries {
+		members = append(members, cache.ZMember{
+			Member: entry.ID,
+			Score:  timelineScore(entry.CreatedAt),
+		})
+	}
+
+	if len(entries) < tc.capacity {
+		members = append(members, cache.ZMember{
+			Member: timelineSentinel,
+			Score:  0,
+		})
+	}
+
+	if err := tc.kv.ZReplace(ctx, timelineKey(userID), members, tc.ttl); err != nil {
+		return err
+	}
+
+	// the heavy list is written last, its presence means the timeline is built
+	heavyJSON, err := json.Marshal(heavy)
+	if err != nil {
+		return err
+	}
+
+	return tc.kv.Set(ctx, timelineHeavyKey(userID), string(heavyJSON), tc.ttl)
+}
+
+// Refresh rebuilds the timeline of a user after their follows changed. When that fails the
+// timeline is dropped instead, so the next read builds it again or falls back to postgres
+// rather than serving the tweets of users no longer followed
+func (tc *TimelineCache) Refresh(ctx context.Context, userID string) error {
+	err := tc.Rebuild(ctx, userID)
+	if err == nil {
+		return nil
+	}
+
+	// the heavy list goes first, without it the timeline is not read
+	for _, key := range []string{timelineHeavyKey(userID), timelineKey(userID)} {
+		if err := tc.kv.Del(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// Read returns a page of the home timeline, pages the cache cannot serve are read from postgres
+func (tc *TimelineCache) Read(ctx context.Context, filter entity.TimelineFilter) (entity.TimelineResponse, error) {
+	response, ok, err := tc.read(ctx, filter)
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	if err != nil || !ok {
+		return tc.tweets.HomeTimeline(ctx, filter)
+	}
+
+	return response, nil
+}
+
+func (tc *TimelineCache) read(ctx context.Context, filter entity.TimelineFilter) (entity.TimelineResponse, bool, error) {
+	heavy, err := tc.heavyFollowings(ctx, filter.UserID)
+	if errors.Is(err, redis.Nil) {
+		// the timeline expired while paging, serve the rest from postgres
+		if filter.Cursor != nil {
+			return entity.TimelineResponse{}, false, nil
+		}
+
+		if err := tc.Rebuild(ctx, filter.UserID); err != nil {
+			return entity.TimelineResponse{}, false, err
+		}
+
+		heavy, err = tc.heavyFollowings(ctx, filter.UserID)
+	}
+	if err != nil {
+		return entity.TimelineResponse{}, false, err
+	}
+
+	entries, complete, err := tc.cachedEntries(ctx, filter)
+	if err != nil {
+		return entity.TimelineResponse{}, false, err
+	}
+
+	// the page reaches past the tweets kept in redis
+	if len(entries) <= filter.Limit && !complete {
+		return entity.TimelineResponse{}, false, nil
+	}
+
+	if len(heavy) > 0 {
+		heavyEntries, err := tc.tweets.TimelineEntries(ctx, entity.TimelineEntriesFilter{
+			UserIDs: heavy,
+			Cursor:  filter.Cursor,
+			Limit:   filter.Limit + 1,
+		})
+		if err != nil {
+			return entity.TimelineResponse{}, false, err
+		}
+
+		entries = mergeTimelineEntries(entries, heavyEntries)
+	}
+
+	var response entity.TimelineResponse
+
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+		last := entries[len(entries)-1]
+		response.NextCursor = utils.EncodeCursor(entity.Cursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		})
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+
+	// deleted tweets are dropped here
+	response.Tweets, err = tc.tweets.GetTweetsByIDs(ctx, ids, filter.UserID)
+	if err != nil {
+		return entity.TimelineResponse{}, false, err
+	}
+
+	return response, true, nil
+}
+
+func (tc *TimelineCache) heavyFollowings(ctx context.Context, userID string) ([]string, error) {
+	value, err := tc.kv.Get(ctx, timelineHeavyKey(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	var heavy []string
+	if err := json.Unmarshal([]byte(value.(string)), &heavy); err != nil {
+		return nil, err
+	}
+
+	return heavy, nil
+}
+
+// cachedEntries reads up to limit+1 entries after the cursor, complete reports
+// that the timeline has no older tweets than the ones returned
+func (tc *TimelineCache) cachedEntries(ctx context.Context, filter entity.TimelineFilter) ([]entity.TimelineEntry, bool, error) {
+	max := "+inf"
+	if filter.Cursor != nil {
+		max = strconv.FormatFloat(timelineScore(filter.Cursor.CreatedAt), 'f', -1, 64)
+	}
+
+	members, err := tc.kv.ZRevRangeByScore(ctx, timelineKey(filter.UserID), max, int64(filter.Limit+1+timelineTieSlack))
+	if err != nil {
+		return nil, false, err
+	}
+
+	var (
+		entries  []entity.TimelineEntry
+		complete bool
+	)
+	for _, member := range members {
+		if member.Member == timelineSentinel {
+			complete = true
+			break
+		}
+
+		entry := entity.TimelineEntry{
+			ID:        member.Member,
+			CreatedAt: time.UnixMicro(int64(member.Score)).UTC(),
+		}
+
+		if filter.Cursor != nil && !newerEntry(entity.TimelineEntry{ID: filter.Cursor.ID, CreatedAt: filter.Cursor.CreatedAt}, entry) {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if len(entries) > filter.Limit+1 {
+		entries = entries[:filter.Limit+1]
+	}
+
+	return entries, complete, nil
+}
+
+// newerEntry reports whether a comes before b in a timeline
+func newerEntry(a, b entity.TimelineEntry) bool {
+	if a.CreatedAt.Equal(b.CreatedAt) {
+		return a.ID > b.ID
+	}
+	return a.CreatedAt.After(b.CreatedAt)
+}
+
+// mergeTimelineEntries merges two timelines, a tweet present in both is kept once
+func mergeTimelineEntries(a, b []entity.TimelineEntry) []entity.TimelineEntry {
+	merged := make([]entity.TimelineEntry, 0, len(a)+len(b))
+
+	for len(a) > 0 || len(b) > 0 {
+		var next entity.TimelineEntry
+		if len(b) == 0 || (len(a) > 0 && !newerEntry(b[0], a[0])) {
+			next, a = a[0], a[1:]
+		} else {
+			next, b = b[0], b[1:]
+		}
+
+		if len(merged) > 0 && merged[len(merged)-1].ID == next.ID {
+			continue
+		}
+
+		merged = append(merged, next)
+	}
+
+	return merged
+}
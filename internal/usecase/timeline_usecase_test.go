@@ -0,0 +1,241 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/config"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
+	"github.com/dostonshernazarov/mini-twitter/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timelineTweets serves the tweets of entries, the postgres fallback is only counted
+type timelineTweets struct {
+	repo.TweetStorageI
+	entries   []entity.TimelineEntry
+	fallbacks int
+}
+
+func (f *timelineTweets) TimelineEntries(ctx context.Context, filter entity.TimelineEntriesFilter) ([]entity.TimelineEntry, error) {
+	authors := make(map[string]bool)
+	for _, id := range filter.UserIDs {
+		authors[id] = true
+	}
+
+	var entries []entity.TimelineEntry
+	for _, entry := range f.entries {
+		if !authors[entry.UserID] {
+			continue
+		}
+		if filter.Cursor != nil && !entry.CreatedAt.Before(filter.Cursor.CreatedAt) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+func (f *timelineTweets) GetTweetsByIDs(ctx context.Context, ids []string, viewerID string) ([]entity.GetTweetResponse, error) {
+	tweets := make([]entity.GetTweetResponse, 0, len(ids))
+	for _, id := range ids {
+		tweets = append(tweets, entity.GetTweetResponse{ID: id})
+	}
+	return tweets, nil
+}
+
+func (f *timelineTweets) HomeTimeline(ctx context.Context, filter entity.TimelineFilter) (entity.TimelineResponse, error) {
+	f.fallbacks++
+	return entity.TimelineResponse{}, nil
+}
+
+// timelineFollows holds who follows whom
+type timelineFollows struct {
+	repo.FollowStorageI
+	followings map[string][]string
+}
+
+func (f *timelineFollows) FollowerIDs(ctx context.Context, id string, limit int) ([]string, error) {
+	var followers []string
+	for user, followings := range f.followings {
+		for _, following := range followings {
+			if following == id && len(followers) < limit {
+				followers = append(followers, user)
+			}
+		}
+	}
+	return followers, nil
+}
+
+func (f *timelineFollows) FollowingIDs(ctx context.Context, id string) ([]string, error) {
+	return f.followings[id], nil
+}
+
+func (f *timelineFollows) HeavyFollowings(ctx context.Context, id string, threshold int) ([]string, error) {
+	var heavy []string
+	for _, following := range f.followings[id] {
+		followers, _ := f.FollowerIDs(ctx, following, threshold+1)
+		if len(followers) > threshold {
+			heavy = append(heavy, following)
+		}
+	}
+	return heavy, nil
+}
+
+func timelinePage(t *testing.T, cache *usecase.TimelineCache, userID, cursor string, limit int) ([]string, string) {
+	decoded, err := utils.DecodeCursor(cursor)
+	require.NoError(t, err)
+
+	page, err := cache.Read(context.Background(), entity.TimelineFilter{UserID: userID, Cursor: decoded, Limit: limit})
+	require.NoError(t, err)
+
+	var ids []string
+	for _, tweet := range page.Tweets {
+		ids = append(ids, tweet.ID)
+	}
+	return ids, page.NextCursor
+}
+
+func TestTimelineFansOutLightAuthorsAndMergesHeavyOnes(t *testing.T) {
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+
+	tweets := &timelineTweets{entries: []entity.TimelineEntry{
+		{ID: "light-1", UserID: "light", CreatedAt: base},
+		{ID: "heavy-1", UserID: "heavy", CreatedAt: base.Add(time.Minute)},
+	}}
+	follows := &timelineFollows{followings: map[string][]string{
+		"viewer": {"light", "heavy"},
+		"other":  {"heavy"},
+	}}
+
+	var cfg config.Config
+	cfg.Timeline.FanoutThreshold = 1
+	cfg.Timeline.Capacity = 10
+	cfg.Timeline.TTL = "1h"
+
+	kv := newMemoryKV()
+	cache, err := usecase.NewTimelineCache(&cfg, kv, tweets, follows)
+	require.NoError(t, err)
+
+	ids, _ := timelinePage(t, cache, "viewer", "", 10)
+	assert.Equal(t, []string{"heavy-1", "light-1"}, ids)
+
+	for _, entry := range []entity.TimelineEntry{
+		{ID: "light-2", UserID: "light", CreatedAt: base.Add(2 * time.Minute)},
+		{ID: "heavy-2", UserID: "heavy", CreatedAt: base.Add(3 * time.Minute)},
+	} {
+		tweets.entries = append(tweets.entries, entry)
+		require.NoError(t, cache.Push(ctx, entry))
+	}
+
+	// the tweets of the author with more followers than the threshold are not pushed
+	assert.Equal(t, []string{"light-2", "light-1", "-"}, kv.zmembers("timeline:viewer"))
+
+	ids, cursor := timelinePage(t, cache, "viewer", "", 2)
+	assert.Equal(t, []string{"heavy-2", "light-2"}, ids)
+	require.NotEmpty(t, cursor)
+
+	ids, cursor = timelinePage(t, cache, "viewer", cursor, 2)
+	assert.Equal(t, []string{"heavy-1", "light-1"}, ids)
+	assert.Empty(t, cursor)
+
+	assert.Zero(t, tweets.fallbacks)
+}
+
+func TestTimelineFallsBackWhenPagingPastTheCache(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+
+	tweets := &timelineTweets{}
+	for i := 0; i < 5; i++ {
+		tweets.entries = append(tweets.entries, entity.TimelineEntry{
+			ID:        string(rune('a' + i)),
+			UserID:    "author",
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	follows := &timelineFollows{followings: map[string][]string{"viewer": {"author"}}}
+
+	var cfg config.Config
+	cfg.Timeline.FanoutThreshold = 10
+	cfg.Timeline.Capacity = 3
+	cfg.Timeline.TTL = "1h"
+
+	cache, err := usecase.NewTimelineCache(&cfg, newMemoryKV(), tweets, follows)
+	require.NoError(t, err)
+
+	ids, cursor := timelinePage(t, cache, "viewer", "", 2)
+	assert.Equal(t, []string{"e", "d"}, ids)
+	assert.Zero(t, tweets.fallbacks)
+
+	// only three tweets are kept, the second page is read from postgres
+	timelinePage(t, cache, "viewer", cursor, 2)
+	assert.Equal(t, 1, tweets.fallbacks)
+}
+
+// brokenFollows fails to list followings once broken, like postgres going away
+type brokenFollows struct {
+	*timelineFollows
+	broken bool
+}
+
+func (f *brokenFollows) FollowingIDs(ctx context.Context, id string) ([]string, error) {
+	if f.broken {
+		return nil, errors.New("connection refused")
+	}
+	return f.timelineFollows.FollowingIDs(ctx, id)
+}
+
+// followedRepo follows whoever is asked
+type followedRepo struct {
+	repo.FollowStorageI
+}
+
+func (followedRepo) Follow(ctx context.Context, follow entity.FollowAction) (entity.FollowResponse, error) {
+	return entity.FollowResponse{Status: true}, nil
+}
+
+func TestTimelineIsDroppedWhenRebuildFails(t *testing.T) {
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+
+	tweets := &timelineTweets{entries: []entity.TimelineEntry{
+		{ID: "a", UserID: "author", CreatedAt: base},
+	}}
+	follows := &brokenFollows{timelineFollows: &timelineFollows{followings: map[string][]string{"viewer": {"author"}}}}
+
+	var cfg config.Config
+	cfg.Timeline.FanoutThreshold = 10
+	cfg.Timeline.Capacity = 10
+	cfg.Timeline.TTL = "1h"
+
+	kv := newMemoryKV()
+	cache, err := usecase.NewTimelineCache(&cfg, kv, tweets, follows)
+	require.NoError(t, err)
+
+	ids, _ := timelinePage(t, cache, "viewer", "", 10)
+	assert.Equal(t, []string{"a"}, ids)
+
+	follows.broken = true
+	_, err = usecase.NewFollowService(time.Second, followedRepo{}, cache).Follow(ctx, entity.FollowAction{UserID: "viewer", FollowingID: "other"})
+	require.NoError(t, err)
+
+	// the timeline built before the follow is not served anymore
+	assert.Empty(t, kv.zmembers("timeline:viewer"))
+	_, err = kv.Get(ctx, "timeline:viewer:heavy")
+	assert.Error(t, err)
+
+	timelinePage(t, cache, "viewer", "", 10)
+	assert.Equal(t, 1, tweets.fallbacks)
+}
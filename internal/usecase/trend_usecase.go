@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	cache "github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/redis"
+)
+
+// trendsTTL is how long a computed window is kept in redis for inspection
+const trendsTTL = time.Minute
+
+// trendWindow is a sliding window made of the last count buckets of the given size
+type trendWindow struct {
+	bucket time.Duration
+	count  int
+}
+
+var trendWindows = map[string]trendWindow{
+	entity.TrendWindowHour: {bucket: time.Minute, count: 60},
+	entity.TrendWindowDay:  {bucket: time.Hour, count: 24},
+}
+
+// trendService counts hashtag uses in per minute and per hour redis sorted sets,
+// a window sums the buckets it covers
+type trendService struct {
+	ctxTimeout time.Duration
+	kv         cache.KV
+}
+
+func NewTrendService(timeout time.Duration, kv cache.KV) Trend {
+	return &trendService{
+		ctxTimeout: timeout,
+		kv:         kv,
+	}
+}
+
+func trendBucketKey(bucket time.Duration, start time.Time) string {
+	return "trends:" + strconv.FormatInt(int64(bucket.Seconds()), 10) + ":" + strconv.FormatInt(start.Unix(), 10)
+}
+
+// Record counts one use of every tag at the given time in each window
+func (t *trendService) Record(ctx context.Context, tags []string, at time.Time) error {
+	for _, window := range trendWindows {
+		start := at.Truncate(window.bucket)
+		expiration := window.bucket * time.Duration(window.count+1)
+
+		if err := t.kv.ZIncr(ctx, trendBucketKey(window.bucket, start), tags, expiration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *trendService) Trends(ctx context.Context, window string, limit int) (entity.TrendsResponse, error) {
+	w, ok := trendWindows[window]
+	if !ok {
+		return entity.TrendsResponse{}, errorspkg.ErrorInvalidWindow
+	}
+
+	last := time.Now().Truncate(w.bucket)
+
+	keys := make([]string, 0, w.count)
+	for i := 0; i < w.count; i++ {
+		keys = append(keys, trendBucketKey(w.bucket, last.Add(-time.Duration(i)*w.bucket)))
+	}
+
+	members, err := t.kv.ZUnionRevRange(ctx, "trends:"+window, keys, int64(limit), trendsTTL)
+	if err != nil {
+		return entity.TrendsResponse{}, err
+	}
+
+	response := entity.TrendsResponse{
+		Window: window,
+		Trends: make([]entity.Trend, 0, len(members)),
+	}
+	for _, member := range members {
+		response.Trends = append(response.Trends, entity.Trend{
+			Tag:   member.Member,
+			Count: int64(member.Score),
+		})
+	}
+
+	return response, nil
+}
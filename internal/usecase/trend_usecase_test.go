@@ -0,0 +1,36 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrendsSumTheBucketsOfTheirWindow(t *testing.T) {
+	ctx := context.Background()
+	trends := usecase.NewTrendService(time.Second, newMemoryKV())
+	now := time.Now()
+
+	require.NoError(t, trends.Record(ctx, []string{"go", "redis"}, now))
+	require.NoError(t, trends.Record(ctx, []string{"go"}, now.Add(-10*time.Minute)))
+	// two hours ago is out of the hour window but still in the day window
+	require.NoError(t, trends.Record(ctx, []string{"redis", "postgres"}, now.Add(-2*time.Hour)))
+	require.NoError(t, trends.Record(ctx, []string{"redis"}, now.Add(-3*time.Hour)))
+
+	hour, err := trends.Trends(ctx, entity.TrendWindowHour, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Trend{{Tag: "go", Count: 2}, {Tag: "redis", Count: 1}}, hour.Trends)
+
+	day, err := trends.Trends(ctx, entity.TrendWindowDay, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Trend{{Tag: "redis", Count: 3}, {Tag: "go", Count: 2}}, day.Trends)
+
+	_, err = trends.Trends(ctx, "7d", 10)
+	assert.ErrorIs(t, err, errorspkg.ErrorInvalidWindow)
+}
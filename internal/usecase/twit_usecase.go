@@ -2,29 +2,121 @@ package usecase
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
 	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/utils"
 )
 
 type tweetService struct {
 	ctxTimeout time.Duration
 	repo       repo.TweetStorageI
+	timeline   *TimelineCache
+	trends     Trend
+	editWindow time.Duration
+	maxEdits   int
 }
 
-func NewTweetService(timeout time.Duration, repository repo.TweetStorageI) Twit {
+func NewTweetService(timeout time.Duration, repository repo.TweetStorageI, timeline *TimelineCache, trends Trend, editWindow time.Duration, maxEdits int) Twit {
 	return &tweetService{
 		ctxTimeout: timeout,
 		repo:       repository,
+		timeline:   timeline,
+		trends:     trends,
+		editWindow: editWindow,
+		maxEdits:   maxEdits,
+	}
+}
+
+// prepareTweet derives the kind, hashtags and mentions of a tweet about to be saved
+func prepareTweet(tweet *entity.CreateTweetRequest) {
+	switch {
+	case tweet.ParentTweetID != nil:
+		tweet.Kind = entity.TweetKindReply
+	case tweet.OriginalTweetID != nil:
+		tweet.Kind = entity.TweetKindQuote
+	default:
+		tweet.Kind = entity.TweetKindTweet
+	}
+
+	if tweet.Content != nil {
+		tweet.Hashtags = utils.ExtractHashtags(*tweet.Content)
+		tweet.Mentions = utils.ExtractMentions(*tweet.Content)
 	}
 }
 
 func (t *tweetService) CreateTweet(ctx context.Context, tweet entity.CreateTweetRequest) (entity.CreateTweetResponse, error) {
-	return t.repo.CreateTweet(ctx, tweet)
+	prepareTweet(&tweet)
+
+	response, err := t.repo.CreateTweet(ctx, tweet)
+	if err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	pushToTimelines(ctx, t.timeline, response)
+
+	// trends are best effort, a lost count does not fail the tweet
+	if err := t.trends.Record(ctx, tweet.Hashtags, response.CreatedAt); err != nil {
+		log.Println(err.Error())
+	}
+
+	return response, nil
+}
+
+func (t *tweetService) Retweet(ctx context.Context, retweet entity.RetweetAction) (entity.CreateTweetResponse, error) {
+	response, err := t.repo.Retweet(ctx, retweet)
+	if err != nil {
+		return entity.CreateTweetResponse{}, err
+	}
+
+	pushToTimelines(ctx, t.timeline, response)
+
+	return response, nil
+}
+
+func (t *tweetService) UndoRetweet(ctx context.Context, retweet entity.RetweetAction) error {
+	return t.repo.UndoRetweet(ctx, retweet)
+}
+
+// pushToTimelines fans a saved tweet out, a failure only delays it until the next rebuild
+func pushToTimelines(ctx context.Context, timeline *TimelineCache, tweet entity.CreateTweetResponse) {
+	err := timeline.Push(ctx, entity.TimelineEntry{
+		ID:        tweet.ID,
+		UserID:    tweet.UserID,
+		CreatedAt: tweet.CreatedAt,
+	})
+	if err != nil {
+		log.Println(err.Error())
+	}
 }
 
+// UpdateTweet edits a tweet of tweet.UserID within the edit window after posting and up to
+// maxEdits times, the content it replaces stays in the history of the tweet
 func (t *tweetService) UpdateTweet(ctx context.Context, tweet entity.UpdateTweetRequest) (entity.UpdateTweetResponse, error) {
+	current, err := t.repo.GetTweet(ctx, tweet.ID, tweet.UserID)
+	if err != nil {
+		return entity.UpdateTweetResponse{}, err
+	}
+
+	if current.UserID != tweet.UserID {
+		return entity.UpdateTweetResponse{}, errorspkg.ErrorNoPermission
+	}
+
+	if time.Since(current.CreatedAt) > t.editWindow {
+		return entity.UpdateTweetResponse{}, errorspkg.ErrorEditClosed
+	}
+
+	if current.EditCount >= t.maxEdits {
+		return entity.UpdateTweetResponse{}, errorspkg.ErrorEditLimit
+	}
+
+	tweet.EditCount = current.EditCount
+	tweet.Hashtags = utils.ExtractHashtags(tweet.Content)
+	tweet.Mentions = utils.ExtractMentions(tweet.Content)
+
 	return t.repo.UpdateTweet(ctx, tweet)
 }
 
@@ -32,14 +124,34 @@ func (t *tweetService) DeleteTweet(ctx context.Context, id string) error {
 	return t.repo.DeleteTweet(ctx, id)
 }
 
-func (t *tweetService) GetTweet(ctx context.Context, id string) (entity.GetTweetResponse, error) {
-	return t.repo.GetTweet(ctx, id)
+func (t *tweetService) GetTweet(ctx context.Context, id, viewerID string) (entity.GetTweetResponse, error) {
+	return t.repo.GetTweet(ctx, id, viewerID)
+}
+
+func (t *tweetService) TweetHistory(ctx context.Context, id, viewerID string) (entity.TweetHistoryResponse, error) {
+	return t.repo.TweetHistory(ctx, id, viewerID)
+}
+
+func (t *tweetService) ListTweets(ctx context.Context, filter entity.Filter, viewerID string) (entity.ListTweetsResponse, error) {
+	return t.repo.ListTweets(ctx, filter, viewerID)
+}
+
+func (t *tweetService) UserTweets(ctx context.Context, usrID, viewerID string) (entity.ListTweetsResponse, error) {
+	return t.repo.UserTweets(ctx, usrID, viewerID)
+}
+
+func (t *tweetService) HomeTimeline(ctx context.Context, filter entity.TimelineFilter) (entity.TimelineResponse, error) {
+	return t.timeline.Read(ctx, filter)
+}
+
+func (t *tweetService) Thread(ctx context.Context, filter entity.ThreadFilter) (entity.ThreadResponse, error) {
+	return t.repo.Thread(ctx, filter)
 }
 
-func (t *tweetService) ListTweets(ctx context.Context, filter entity.Filter) (entity.ListTweetsResponse, error) {
-	return t.repo.ListTweets(ctx, filter)
+func (t *tweetService) HashtagTweets(ctx context.Context, filter entity.HashtagFilter) (entity.TimelineResponse, error) {
+	return t.repo.HashtagTweets(ctx, filter)
 }
 
-func (t *tweetService) UserTweets(ctx context.Context, usrID string) (entity.ListTweetsResponse, error) {
-	return t.repo.UserTweets(ctx, usrID)
+func (t *tweetService) MentionTweets(ctx context.Context, filter entity.TimelineFilter) (entity.TimelineResponse, error) {
+	return t.repo.MentionTweets(ctx, filter)
 }
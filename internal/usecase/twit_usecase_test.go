@@ -0,0 +1,104 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	errorspkg "github.com/dostonshernazarov/mini-twitter/internal/errors"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// editedTweets serves current and keeps the edits that reach the repository, an edit of a
+// stale edit count gives a conflict like the postgres repository does
+type editedTweets struct {
+	repo.TweetStorageI
+	current entity.GetTweetResponse
+	edits   []entity.UpdateTweetRequest
+}
+
+func (f *editedTweets) GetTweet(ctx context.Context, id, viewerID string) (entity.GetTweetResponse, error) {
+	return f.current, nil
+}
+
+func (f *editedTweets) UpdateTweet(ctx context.Context, tweet entity.UpdateTweetRequest) (entity.UpdateTweetResponse, error) {
+	if tweet.EditCount != f.current.EditCount {
+		return entity.UpdateTweetResponse{}, errorspkg.ErrorConflict
+	}
+
+	f.edits = append(f.edits, tweet)
+	f.current.EditCount++
+	return entity.UpdateTweetResponse{ID: tweet.ID, Content: &tweet.Content, EditCount: f.current.EditCount}, nil
+}
+
+func newEditedTweets(userID string, postedAgo time.Duration, edits int) *editedTweets {
+	return &editedTweets{current: entity.GetTweetResponse{
+		ID:        "tweet",
+		UserID:    userID,
+		EditCount: edits,
+		CreatedAt: time.Now().Add(-postedAgo),
+	}}
+}
+
+func TestUpdateTweetWithinWindowAndLimit(t *testing.T) {
+	tweets := newEditedTweets("author", time.Minute, 1)
+	service := usecase.NewTweetService(time.Second, tweets, nil, nil, time.Hour, 2)
+
+	updated, err := service.UpdateTweet(context.Background(), entity.UpdateTweetRequest{ID: "tweet", UserID: "author", Content: "now #golang with @gopher"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.EditCount)
+
+	require.Len(t, tweets.edits, 1)
+	assert.Equal(t, 1, tweets.edits[0].EditCount)
+	assert.Equal(t, []string{"golang"}, tweets.edits[0].Hashtags)
+	assert.Equal(t, []string{"gopher"}, tweets.edits[0].Mentions)
+
+	// the second edit used up maxEdits
+	_, err = service.UpdateTweet(context.Background(), entity.UpdateTweetRequest{ID: "tweet", UserID: "author", Content: "again"})
+	assert.ErrorIs(t, err, errorspkg.ErrorEditLimit)
+	assert.Len(t, tweets.edits, 1)
+}
+
+func TestUpdateTweetRefusedEdits(t *testing.T) {
+	for name, tc := range map[string]struct {
+		tweets *editedTweets
+		userID string
+		err    error
+	}{
+		"not the author":        {tweets: newEditedTweets("author", time.Minute, 0), userID: "other", err: errorspkg.ErrorNoPermission},
+		"after the edit window": {tweets: newEditedTweets("author", 2*time.Hour, 0), userID: "author", err: errorspkg.ErrorEditClosed},
+		"at the edit limit":     {tweets: newEditedTweets("author", time.Minute, 5), userID: "author", err: errorspkg.ErrorEditLimit},
+	} {
+		t.Run(name, func(t *testing.T) {
+			service := usecase.NewTweetService(time.Second, tc.tweets, nil, nil, time.Hour, 5)
+
+			_, err := service.UpdateTweet(context.Background(), entity.UpdateTweetRequest{ID: "tweet", UserID: tc.userID, Content: "edited"})
+			assert.ErrorIs(t, err, tc.err)
+			assert.Empty(t, tc.tweets.edits)
+		})
+	}
+}
+
+// racedTweets is edited by someone else between reading the tweet and saving the edit
+type racedTweets struct {
+	*editedTweets
+}
+
+func (f racedTweets) GetTweet(ctx context.Context, id, viewerID string) (entity.GetTweetResponse, error) {
+	current, err := f.editedTweets.GetTweet(ctx, id, viewerID)
+	f.current.EditCount++
+	return current, err
+}
+
+func TestUpdateTweetConflictsWithConcurrentEdit(t *testing.T) {
+	tweets := newEditedTweets("author", time.Minute, 0)
+	service := usecase.NewTweetService(time.Second, racedTweets{tweets}, nil, nil, time.Hour, 5)
+
+	_, err := service.UpdateTweet(context.Background(), entity.UpdateTweetRequest{ID: "tweet", UserID: "author", Content: "edited"})
+	assert.ErrorIs(t, err, errorspkg.ErrorConflict)
+	assert.Empty(t, tweets.edits)
+}
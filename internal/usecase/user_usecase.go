@@ -47,8 +47,8 @@ func (u *userService) UpdatePasswd(ctx context.Context, id string, passwd string
 	return u.repo.UpdatePasswd(ctx, id, passwd)
 }
 
-func (u *userService) UploadImage(ctx context.Context, id string, url string) error {
-	return u.repo.UploadImage(ctx, id, url)
+func (u *userService) UploadImage(ctx context.Context, id string, picture entity.MediaURLs) error {
+	return u.repo.UploadImage(ctx, id, picture)
 }
 
 func (u *userService) Delete(ctx context.Context, id string) error {
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/usecase"
+)
+
+// MediaSweeper drops direct uploads that were started and never completed, with the objects
+// clients put in the bucket for them. Sweepers on several instances may drop the same upload
+type MediaSweeper struct {
+	media     usecase.Media
+	interval  time.Duration
+	batchSize int
+}
+
+func NewMediaSweeper(media usecase.Media, interval time.Duration, batchSize int) *MediaSweeper {
+	return &MediaSweeper{
+		media:     media,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run sweeps batches until ctx is done, a full batch is followed by the next one
+// right away and stale uploads are looked for every interval otherwise
+func (s *MediaSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		swept, err := s.media.SweepPendingUploads(ctx, s.batchSize)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("media sweeper failed: %v", err)
+		}
+
+		if err == nil && swept == s.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/eventbus"
+)
+
+// outboxPruneInterval is the pause between two deletions of old outbox rows
+const outboxPruneInterval = time.Hour
+
+// OutboxRelay moves committed outbox events to the event bus, keyed by their aggregate. Events are published
+// at least once, consumers drop duplicates by the event id
+type OutboxRelay struct {
+	repo      repo.OutboxStorageI
+	bus       eventbus.Bus
+	interval  time.Duration
+	batchSize int
+	retention time.Duration
+}
+
+func NewOutboxRelay(repository repo.OutboxStorageI, bus eventbus.Bus, interval time.Duration, batchSize int, retention time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		repo:      repository,
+		bus:       bus,
+		interval:  interval,
+		batchSize: batchSize,
+		retention: retention,
+	}
+}
+
+// Run relays batches until ctx is done, a full batch is followed by the next one
+// right away and the outbox is polled every interval once drained. Published events
+// are deleted once they are older than the retention
+func (o *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		if time.Since(pruned) >= outboxPruneInterval {
+			if _, err := o.repo.Prune(ctx, time.Now().Add(-o.retention)); err != nil && ctx.Err() == nil {
+				log.Printf("outbox prune failed: %v", err)
+			}
+			pruned = time.Now()
+		}
+
+		claimed, err := o.Relay(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("outbox relay failed: %v", err)
+		}
+
+		if err == nil && claimed == o.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay publishes one claimed batch and returns its size. The batch is claimed in a
+// transaction of its own, so no transaction stays open while the bus is slow. An aggregate
+// whose event failed is held back until that event went out, so later events never overtake it
+func (o *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	messages, err := o.repo.Claim(ctx, o.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		published []int64
+		blocked   = make(map[string]bool)
+	)
+	for _, message := range messages {
+		if blocked[message.AggregateID] {
+			continue
+		}
+
+		if err := o.bus.Publish(ctx, message.AggregateID, message.Event); err != nil {
+			blocked[message.AggregateID] = true
+
+			if err := o.repo.MarkFailed(ctx, message.Seq, err.Error()); err != nil {
+				return len(messages), err
+			}
+			continue
+		}
+
+		published = append(published, message.Seq)
+	}
+
+	return len(messages), o.repo.MarkPublished(ctx, published)
+}
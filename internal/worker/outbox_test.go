@@ -0,0 +1,89 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/entity"
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/pkg/eventbus"
+	"github.com/dostonshernazarov/mini-twitter/internal/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOutbox struct {
+	repo.OutboxStorageI
+	claimed   []entity.OutboxMessage
+	published []int64
+	failed    []int64
+}
+
+func (f *fakeOutbox) Claim(ctx context.Context, limit int) ([]entity.OutboxMessage, error) {
+	return f.claimed, nil
+}
+
+func (f *fakeOutbox) MarkPublished(ctx context.Context, seqs []int64) error {
+	f.published = append(f.published, seqs...)
+	return nil
+}
+
+func (f *fakeOutbox) MarkFailed(ctx context.Context, seq int64, reason string) error {
+	f.failed = append(f.failed, seq)
+	return nil
+}
+
+// fakeBus records the published events and fails the ones listed in fail
+type fakeBus struct {
+	eventbus.Bus
+	fail      map[string]bool
+	published []string
+}
+
+func (f *fakeBus) Publish(ctx context.Context, key string, event entity.Event) error {
+	if f.fail[event.ID] {
+		return errors.New("broker unavailable")
+	}
+
+	f.published = append(f.published, event.ID)
+	return nil
+}
+
+func message(seq int64, aggregateID, eventID string) entity.OutboxMessage {
+	return entity.OutboxMessage{Seq: seq, AggregateID: aggregateID, Event: entity.Event{ID: eventID}}
+}
+
+func TestOutboxRelayPublishesClaimedEventsInOrder(t *testing.T) {
+	outbox := &fakeOutbox{claimed: []entity.OutboxMessage{
+		message(1, "a", "e1"),
+		message(2, "b", "e2"),
+		message(3, "a", "e3"),
+	}}
+	bus := &fakeBus{}
+
+	claimed, err := worker.NewOutboxRelay(outbox, bus, time.Second, 10, time.Hour).Relay(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, claimed)
+	assert.Equal(t, []string{"e1", "e2", "e3"}, bus.published)
+	assert.Equal(t, []int64{1, 2, 3}, outbox.published)
+	assert.Empty(t, outbox.failed)
+}
+
+func TestOutboxRelayHoldsBackAggregateOfFailedEvent(t *testing.T) {
+	outbox := &fakeOutbox{claimed: []entity.OutboxMessage{
+		message(1, "a", "e1"),
+		message(2, "b", "e2"),
+		message(3, "a", "e3"),
+	}}
+	bus := &fakeBus{fail: map[string]bool{"e1": true}}
+
+	_, err := worker.NewOutboxRelay(outbox, bus, time.Second, 10, time.Hour).Relay(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"e2"}, bus.published)
+	assert.Equal(t, []int64{2}, outbox.published)
+	assert.Equal(t, []int64{1}, outbox.failed)
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+)
+
+// PollCloser finalizes polls whose time ran out and notifies their authors, closers on
+// several instances share the work without closing a poll twice
+type PollCloser struct {
+	repo      repo.PollStorageI
+	interval  time.Duration
+	batchSize int
+}
+
+func NewPollCloser(repository repo.PollStorageI, interval time.Duration, batchSize int) *PollCloser {
+	return &PollCloser{
+		repo:      repository,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run closes batches until ctx is done, a full batch is followed by the next one
+// right away and expired polls are looked for every interval otherwise
+func (p *PollCloser) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		closed, err := p.repo.ClosePolls(ctx, p.batchSize)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("poll closer failed: %v", err)
+		}
+
+		if err == nil && closed == p.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package worker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/infrastructure/repository/postgres/repo"
+	"github.com/dostonshernazarov/mini-twitter/internal/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePolls hands out the closed counts of batches in turn and stops the closer with the last one
+type fakePolls struct {
+	repo.PollStorageI
+	batches []int
+	calls   int
+	stop    context.CancelFunc
+}
+
+func (f *fakePolls) ClosePolls(ctx context.Context, limit int) (int, error) {
+	f.calls++
+	if f.calls >= len(f.batches) {
+		f.stop()
+	}
+	if f.calls > len(f.batches) {
+		return 0, nil
+	}
+
+	return f.batches[f.calls-1], nil
+}
+
+func TestPollCloserDrainsFullBatchesThenWaits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	polls := &fakePolls{batches: []int{10, 10, 3}, stop: cancel}
+
+	// the interval outlasts the test, a closer waiting after a full batch would hang it
+	worker.NewPollCloser(polls, time.Hour, 10).Run(ctx)
+
+	// the partial batch is not followed by another look
+	assert.Equal(t, 3, polls.calls)
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dostonshernazarov/mini-twitter/internal/usecase"
+)
+
+// TweetScheduler publishes scheduled tweets once their time came, schedulers on several
+// instances share the work and every tweet goes out exactly once
+type TweetScheduler struct {
+	drafts    usecase.Draft
+	interval  time.Duration
+	batchSize int
+}
+
+func NewTweetScheduler(drafts usecase.Draft, interval time.Duration, batchSize int) *TweetScheduler {
+	return &TweetScheduler{
+		drafts:    drafts,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run publishes batches until ctx is done, a full batch is followed by the next one
+// right away and due tweets are looked for every interval otherwise
+func (s *TweetScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := s.drafts.PublishDue(ctx, s.batchSize)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("tweet scheduler failed: %v", err)
+		}
+
+		if err == nil && published == s.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_follows_user_id;
+
+DROP INDEX IF EXISTS idx_tweets_user_id_created_at;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_tweets_user_id_created_at ON tweets (user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_follows_user_id ON follows (user_id);
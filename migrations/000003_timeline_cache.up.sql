@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_follows_following_id ON follows (following_id);
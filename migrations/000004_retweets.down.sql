@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_tweets_original_tweet_id;
+
+DROP INDEX IF EXISTS idx_tweets_user_id_retweet;
+
+ALTER TABLE tweets DROP COLUMN IF EXISTS quote_count;
+ALTER TABLE tweets DROP COLUMN IF EXISTS retweet_count;
+ALTER TABLE tweets DROP COLUMN IF EXISTS original_tweet_id;
+ALTER TABLE tweets DROP COLUMN IF EXISTS kind;
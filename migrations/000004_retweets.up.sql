@@ -0,0 +1,9 @@
+ALTER TABLE tweets ADD COLUMN IF NOT EXISTS kind VARCHAR(10) NOT NULL DEFAULT 'tweet';
+ALTER TABLE tweets ADD COLUMN IF NOT EXISTS original_tweet_id UUID REFERENCES tweets(id);
+ALTER TABLE tweets ADD COLUMN IF NOT EXISTS retweet_count INT NOT NULL DEFAULT 0;
+ALTER TABLE tweets ADD COLUMN IF NOT EXISTS quote_count INT NOT NULL DEFAULT 0;
+
+UPDATE tweets SET kind = 'reply' WHERE parent_tweet_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tweets_user_id_retweet ON tweets (user_id, original_tweet_id) WHERE kind = 'retweet' AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_tweets_original_tweet_id ON tweets (original_tweet_id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_tweets_parent_tweet_id;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_tweets_parent_tweet_id ON tweets (parent_tweet_id, created_at, id) WHERE deleted_at IS NULL;
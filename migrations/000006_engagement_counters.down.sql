@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_likes_user_id_tweet_id;
+
+ALTER TABLE tweets DROP COLUMN IF EXISTS reply_count;
+ALTER TABLE tweets DROP COLUMN IF EXISTS like_count;
@@ -0,0 +1,8 @@
+ALTER TABLE tweets ADD COLUMN IF NOT EXISTS like_count INT NOT NULL DEFAULT 0;
+ALTER TABLE tweets ADD COLUMN IF NOT EXISTS reply_count INT NOT NULL DEFAULT 0;
+
+DELETE FROM likes AS l USING likes AS d WHERE l.user_id = d.user_id AND l.tweet_id = d.tweet_id AND l.ctid > d.ctid;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_likes_user_id_tweet_id ON likes (user_id, tweet_id);
+
+UPDATE tweets AS t SET like_count = (SELECT COUNT(*) FROM likes WHERE tweet_id = t.id);
+UPDATE tweets AS t SET reply_count = (SELECT COUNT(*) FROM tweets AS r WHERE r.parent_tweet_id = t.id AND r.deleted_at IS NULL);
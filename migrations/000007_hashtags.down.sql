@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tweet_hashtags;
+DROP TABLE IF EXISTS hashtags;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS hashtags (
+    id UUID PRIMARY KEY,
+    tag VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS tweet_hashtags (
+    tweet_id UUID NOT NULL,
+    hashtag_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tweet_id, hashtag_id),
+    FOREIGN KEY (tweet_id) REFERENCES tweets(id),
+    FOREIGN KEY (hashtag_id) REFERENCES hashtags(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tweet_hashtags_hashtag_id ON tweet_hashtags (hashtag_id);
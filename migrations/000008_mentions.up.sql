@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS mentions (
+    tweet_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tweet_id, user_id),
+    FOREIGN KEY (tweet_id) REFERENCES tweets(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_follows_user_id_following_id;
+
+DROP TABLE IF EXISTS outbox;
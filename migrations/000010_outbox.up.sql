@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    seq BIGSERIAL PRIMARY KEY,
+    id UUID NOT NULL UNIQUE,
+    aggregate_type VARCHAR(20) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    event JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (aggregate_id, seq) WHERE published_at IS NULL;
+
+DELETE FROM follows AS f USING follows AS d WHERE f.user_id = d.user_id AND f.following_id = d.following_id AND f.ctid > d.ctid;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_follows_user_id_following_id ON follows (user_id, following_id);
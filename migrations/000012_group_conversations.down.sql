@@ -0,0 +1,7 @@
+ALTER TABLE conversation_members DROP COLUMN IF EXISTS muted_until;
+ALTER TABLE conversation_members DROP COLUMN IF EXISTS muted;
+ALTER TABLE conversation_members DROP COLUMN IF EXISTS role;
+
+ALTER TABLE conversations DROP COLUMN IF EXISTS avatar_url;
+ALTER TABLE conversations DROP COLUMN IF EXISTS title;
+ALTER TABLE conversations DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,7 @@
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS kind VARCHAR(10) NOT NULL DEFAULT 'direct';
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS title VARCHAR(100);
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS avatar_url TEXT;
+
+ALTER TABLE conversation_members ADD COLUMN IF NOT EXISTS role VARCHAR(10) NOT NULL DEFAULT 'member';
+ALTER TABLE conversation_members ADD COLUMN IF NOT EXISTS muted BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE conversation_members ADD COLUMN IF NOT EXISTS muted_until TIMESTAMP;
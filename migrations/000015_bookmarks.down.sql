@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS bookmarks;
+
+DROP TABLE IF EXISTS bookmark_folders;
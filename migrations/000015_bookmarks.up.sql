@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS bookmark_folders (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS bookmarks (
+    user_id UUID NOT NULL,
+    tweet_id UUID NOT NULL,
+    folder_id UUID,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, tweet_id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (tweet_id) REFERENCES tweets(id),
+    FOREIGN KEY (folder_id) REFERENCES bookmark_folders(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user_id_created_at ON bookmarks (user_id, created_at DESC, tweet_id DESC);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_folder_id_created_at ON bookmarks (folder_id, created_at DESC, tweet_id DESC);
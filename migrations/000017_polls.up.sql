@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS polls (
+    tweet_id UUID PRIMARY KEY,
+    ends_at TIMESTAMP NOT NULL,
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (tweet_id) REFERENCES tweets(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_polls_open_ends_at ON polls (ends_at) WHERE closed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    id UUID PRIMARY KEY,
+    tweet_id UUID NOT NULL,
+    position SMALLINT NOT NULL,
+    label VARCHAR(25) NOT NULL,
+    vote_count INT NOT NULL DEFAULT 0,
+    UNIQUE (tweet_id, position),
+    FOREIGN KEY (tweet_id) REFERENCES polls(tweet_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+    tweet_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    option_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tweet_id, user_id),
+    FOREIGN KEY (tweet_id) REFERENCES polls(tweet_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE
+);
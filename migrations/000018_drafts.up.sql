@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS drafts (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    parent_tweet_id UUID,
+    quote_tweet_id UUID,
+    content VARCHAR(280),
+    files TEXT[] NOT NULL DEFAULT '{}',
+    publish_at TIMESTAMP,
+    last_error VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_drafts_user_id_created_at ON drafts (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_drafts_publish_at ON drafts (publish_at) WHERE publish_at IS NOT NULL;
@@ -0,0 +1,10 @@
+ALTER TABLE tweets ADD COLUMN IF NOT EXISTS edit_count INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS tweet_revisions (
+    tweet_id UUID NOT NULL,
+    revision INT NOT NULL,
+    content VARCHAR(280),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (tweet_id, revision),
+    FOREIGN KEY (tweet_id) REFERENCES tweets(id)
+);
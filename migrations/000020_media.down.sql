@@ -0,0 +1,4 @@
+ALTER TABLE drafts DROP COLUMN IF EXISTS media_ids;
+ALTER TABLE drafts ADD COLUMN IF NOT EXISTS files TEXT[] NOT NULL DEFAULT '{}';
+
+DROP TABLE IF EXISTS media;
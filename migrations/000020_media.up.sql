@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS media (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    kind VARCHAR(10) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key TEXT NOT NULL,
+    url TEXT NOT NULL,
+    tweet_id UUID,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (tweet_id) REFERENCES tweets(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_user_id_created_at ON media (user_id, created_at DESC);
+
+-- drafts point at uploaded media instead of file urls, the files of saved drafts are carried
+-- over to media by 000025 before the column goes
+ALTER TABLE drafts ADD COLUMN IF NOT EXISTS media_ids UUID[] NOT NULL DEFAULT '{}';
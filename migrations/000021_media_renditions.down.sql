@@ -0,0 +1,5 @@
+ALTER TABLE users ALTER COLUMN profile_picture TYPE TEXT USING profile_picture->>'original';
+
+ALTER TABLE files DROP COLUMN IF EXISTS position;
+
+ALTER TABLE media DROP COLUMN IF EXISTS urls;
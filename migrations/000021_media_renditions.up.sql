@@ -0,0 +1,10 @@
+ALTER TABLE media ADD COLUMN IF NOT EXISTS urls JSONB;
+UPDATE media SET urls = jsonb_build_object('original', url) WHERE urls IS NULL;
+ALTER TABLE media ALTER COLUMN urls SET NOT NULL;
+
+-- the files of a tweet keep the order they were attached in
+ALTER TABLE files ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+
+-- profile pictures hold the urls of all their renditions
+ALTER TABLE users ALTER COLUMN profile_picture TYPE JSONB
+    USING CASE WHEN profile_picture IS NULL OR profile_picture = '' THEN NULL ELSE jsonb_build_object('original', profile_picture) END;
@@ -0,0 +1,2 @@
+DELETE FROM media WHERE status = 'pending';
+ALTER TABLE media DROP COLUMN IF EXISTS status;
@@ -0,0 +1,2 @@
+-- media uploaded straight to the bucket are pending until their upload is checked
+ALTER TABLE media ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'ready';
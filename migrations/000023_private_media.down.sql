@@ -0,0 +1,3 @@
+-- media URLs stay on /media, the bucket they were rewritten from is not known here
+ALTER TABLE media DROP COLUMN IF EXISTS conversation_id;
+ALTER TABLE media DROP COLUMN IF EXISTS purpose;
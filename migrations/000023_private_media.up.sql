@@ -0,0 +1,34 @@
+-- besides its uploader, a media can be seen by everyone when it is a profile picture, by the
+-- members of its conversation when it is a group avatar and by the viewers of its tweet otherwise
+ALTER TABLE media ADD COLUMN IF NOT EXISTS purpose VARCHAR(15) NOT NULL DEFAULT 'tweet';
+ALTER TABLE media ADD COLUMN IF NOT EXISTS conversation_id UUID REFERENCES conversations(id);
+
+UPDATE media AS m SET purpose = 'profile'
+FROM users AS u
+WHERE u.profile_picture->>'original' = m.url;
+
+UPDATE media AS m SET purpose = 'conversation', conversation_id = c.id
+FROM conversations AS c
+WHERE c.avatar_url = m.urls->>'medium';
+
+-- objects are private now, the app serves media under /media/<media id>/<file>. Files uploaded
+-- before media were recorded keep their bucket URLs and have to stay public to be seen
+UPDATE media SET
+    url = regexp_replace(url, '^.*/([0-9a-f-]{36}/[^/]+)$', '/media/\1'),
+    urls = COALESCE((
+        SELECT jsonb_object_agg(key, regexp_replace(value, '^.*/([0-9a-f-]{36}/[^/]+)$', '/media/\1'))
+        FROM jsonb_each_text(urls)
+    ), '{}');
+
+UPDATE files AS f SET file_url = m.url
+FROM media AS m
+WHERE m.id = f.id;
+
+UPDATE users SET profile_picture = (
+    SELECT jsonb_object_agg(key, regexp_replace(value, '^.*/([0-9a-f-]{36}/[^/]+)$', '/media/\1'))
+    FROM jsonb_each_text(profile_picture)
+)
+WHERE profile_picture->>'original' ~ '/[0-9a-f-]{36}/[^/]+$';
+
+UPDATE conversations SET avatar_url = regexp_replace(avatar_url, '^.*/([0-9a-f-]{36}/[^/]+)$', '/media/\1')
+WHERE avatar_url ~ '/[0-9a-f-]{36}/[^/]+$';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_outbox_published_at;
+DROP TABLE IF EXISTS processed_events;
@@ -0,0 +1,10 @@
+-- the idempotency keys of the events a consumer group handled, a redelivered event is dropped
+CREATE TABLE IF NOT EXISTS processed_events (
+    consumer_group VARCHAR(255) NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    processed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (consumer_group, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_processed_events_processed_at ON processed_events (processed_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_published_at ON outbox (published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,9 @@
+ALTER TABLE drafts ADD COLUMN IF NOT EXISTS files TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE drafts AS d SET files = ARRAY(
+    SELECT m.url
+    FROM unnest(d.media_ids) WITH ORDINALITY AS i(id, position)
+    JOIN media AS m ON m.id = i.id
+    ORDER BY i.position
+)
+WHERE d.media_ids <> '{}';
@@ -0,0 +1,62 @@
+-- the files of drafts saved before media were recorded become media of the draft's author, in
+-- the same order, so publishing the draft still attaches them. Their size was never recorded
+-- and the kind is told by the extension. Databases that ran 000020 when it still dropped the
+-- files column have nothing left to carry over
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'drafts' AND column_name = 'files'
+    ) THEN
+        RETURN;
+    END IF;
+
+    WITH legacy AS (
+        SELECT
+            d.id AS draft_id,
+            d.user_id,
+            f.file,
+            f.position,
+            lower(substring(f.file FROM '\.([^./]+)$')) AS extension,
+            gen_random_uuid() AS media_id
+        FROM
+            drafts AS d,
+            unnest(d.files) WITH ORDINALITY AS f(file, position)
+    ), saved AS (
+        INSERT INTO media (id, user_id, kind, content_type, size, status, purpose, storage_key, url, urls)
+        SELECT
+            media_id,
+            user_id,
+            CASE
+                WHEN extension = 'gif' THEN 'gif'
+                WHEN extension IN ('mp4', 'mov', 'webm') THEN 'video'
+                ELSE 'image'
+            END,
+            CASE extension
+                WHEN 'gif' THEN 'image/gif'
+                WHEN 'mp4' THEN 'video/mp4'
+                WHEN 'mov' THEN 'video/quicktime'
+                WHEN 'webm' THEN 'video/webm'
+                WHEN 'png' THEN 'image/png'
+                WHEN 'jpg' THEN 'image/jpeg'
+                WHEN 'jpeg' THEN 'image/jpeg'
+                WHEN 'webp' THEN 'image/webp'
+                ELSE 'application/octet-stream'
+            END,
+            0,
+            'ready',
+            'tweet',
+            file,
+            file,
+            jsonb_build_object('original', file)
+        FROM
+            legacy
+    )
+    UPDATE drafts AS d SET media_ids = d.media_ids || ARRAY(
+        SELECT media_id FROM legacy WHERE legacy.draft_id = d.id ORDER BY position
+    )
+    WHERE d.files <> '{}';
+
+    ALTER TABLE drafts DROP COLUMN files;
+END
+$$;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_media_pending_created_at;
@@ -0,0 +1,2 @@
+-- direct uploads never completed are swept once their upload URL expired
+CREATE INDEX IF NOT EXISTS idx_media_pending_created_at ON media (created_at) WHERE status = 'pending';
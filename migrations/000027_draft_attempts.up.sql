@@ -0,0 +1 @@
+ALTER TABLE drafts ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+DELETE FROM notifications AS n
+USING notifications AS kept
+WHERE n.user_id = kept.user_id
+    AND n.actor_id = kept.actor_id
+    AND n.type = kept.type
+    AND n.tweet_id IS NOT DISTINCT FROM kept.tweet_id
+    AND (n.created_at, n.id) > (kept.created_at, kept.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_unique ON notifications (user_id, actor_id, type, tweet_id) NULLS NOT DISTINCT;